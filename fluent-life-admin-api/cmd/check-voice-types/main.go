@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"fluent-life-admin-api/internal/config"
+	"fluent-life-admin-api/internal/voicetype"
+)
+
+// 检查AI角色和用户设置中引用了不存在或已停用音色的记录，有无效引用时以状态码 1 退出。
+// 修复方式：在后台创建或启用对应音色，或通过 POST /api/v1/admin/voice-types/:id/reassign 替换引用。
+//
+//	go run cmd/check-voice-types/main.go
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := config.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+
+	dangling, err := voicetype.Check(db)
+	if err != nil {
+		log.Fatalf("Check failed: %v", err)
+	}
+	if len(dangling) == 0 {
+		fmt.Println("✅ 没有无效的音色引用")
+		return
+	}
+
+	status := map[string]string{voicetype.StatusMissing: "不存在", voicetype.StatusDisabled: "已停用"}
+	fmt.Printf("发现 %d 个无效音色被引用：\n", len(dangling))
+	for _, d := range dangling {
+		fmt.Printf("  %s（%s）：%d 个用户设置", d.VoiceType, status[d.Status], d.UserSettings)
+		if len(d.Roles) > 0 {
+			fmt.Printf("，AI角色 %s", strings.Join(d.Roles, ", "))
+		}
+		fmt.Println()
+	}
+	os.Exit(1)
+}
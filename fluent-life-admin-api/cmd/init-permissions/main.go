@@ -6,6 +6,7 @@ import (
 
 	"fluent-life-admin-api/internal/config"
 	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/permission"
 
 	"gorm.io/gorm"
 )
@@ -29,6 +30,10 @@ func main() {
 	fmt.Println("初始化菜单数据...")
 	initMenus(db)
 
+	// 初始化角色菜单
+	fmt.Println("初始化角色菜单...")
+	initRoleMenus(db)
+
 	fmt.Println("✅ 权限数据初始化完成！")
 }
 
@@ -55,6 +60,27 @@ func initRoles(db *gorm.DB) {
 				"comment:write": true,
 				"content:read": true,
 				"content:write": true,
+				"room:read":      true,
+				"room:write":     true,
+				"training:read":  true,
+				"training:write": true,
+				"feedback:read":  true,
+				"feedback:write": true,
+				"ai:read":        true,
+				"ai:write":       true,
+				"video:read":     true,
+				"video:write":    true,
+				"log:read":       true,
+				"sensitive:read":  true,
+				"sensitive:write": true,
+				"report:read":     true,
+				"report:write":    true,
+				"analytics:read":  true,
+				"job:manage":      true,
+				"setting:read":    true,
+				"setting:write":   true,
+				"flag:read":       true,
+				"flag:write":      true,
 			},
 		},
 		{
@@ -70,6 +96,14 @@ func initRoles(db *gorm.DB) {
 				"training:write": true,
 				"content:read":   true,
 				"content:write":  true,
+				"room:read":      true,
+				"room:write":     true,
+				"video:read":     true,
+				"video:write":    true,
+				"sensitive:read":  true,
+				"sensitive:write": true,
+				"report:read":     true,
+				"report:write":    true,
 			},
 		},
 		{
@@ -82,7 +116,14 @@ func initRoles(db *gorm.DB) {
 		},
 	}
 
+	registry := permission.NewRegistry(permission.Catalog)
 	for _, role := range roles {
+		for code := range role.Permissions {
+			if !registry.Known(code) {
+				log.Printf("角色 %s 的权限代码不在权限目录中: %s", role.Code, code)
+			}
+		}
+
 		var existingRole models.Role
 		if err := db.Where("code = ?", role.Code).First(&existingRole).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
@@ -95,7 +136,26 @@ func initRoles(db *gorm.DB) {
 				log.Printf("查询角色失败 %s: %v", role.Code, err)
 			}
 		} else {
-			fmt.Printf("  - 角色已存在: %s (%s)\n", existingRole.Name, existingRole.Code)
+			// 角色已存在时补齐新增的权限项，不覆盖管理员手动调整过的权限
+			added := 0
+			if existingRole.Permissions == nil {
+				existingRole.Permissions = models.JSONB{}
+			}
+			for perm := range role.Permissions {
+				if _, ok := existingRole.Permissions[perm]; !ok {
+					existingRole.Permissions[perm] = true
+					added++
+				}
+			}
+			if added > 0 {
+				if err := db.Model(&existingRole).Update("permissions", existingRole.Permissions).Error; err != nil {
+					log.Printf("更新角色权限失败 %s: %v", role.Code, err)
+					continue
+				}
+				fmt.Printf("  ✓ 角色已存在，补充 %d 项权限: %s (%s)\n", added, existingRole.Name, existingRole.Code)
+			} else {
+				fmt.Printf("  - 角色已存在: %s (%s)\n", existingRole.Name, existingRole.Code)
+			}
 		}
 	}
 }
@@ -219,6 +279,52 @@ func initMenus(db *gorm.DB) {
 	}
 }
 
+// initRoleMenus 为尚未分配菜单的内置角色分配默认菜单（按路径匹配，上级分组由菜单树自动补齐）。
+// 超级管理员始终可见全部菜单，无需分配；已分配过菜单的角色保持不变
+func initRoleMenus(db *gorm.DB) {
+	roleMenus := map[string][]string{
+		"admin": {
+			"/", "/users", "/videos",
+			"/posts", "/comments", "/post-likes", "/follows-collections",
+			"/training", "/rooms", "/exposure-modules",
+			"/tongue-twisters", "/daily-expressions", "/speech-techniques", "/legal-documents",
+			"/app-settings", "/help-categories", "/help-articles",
+			"/ai-roles", "/voice-types",
+			"/operation-logs",
+		},
+		"content_admin": {
+			"/", "/videos",
+			"/posts", "/comments", "/post-likes", "/follows-collections",
+			"/training", "/rooms", "/exposure-modules",
+			"/tongue-twisters", "/daily-expressions", "/speech-techniques", "/legal-documents",
+			"/help-categories", "/help-articles",
+		},
+	}
+
+	for code, paths := range roleMenus {
+		var role models.Role
+		if err := db.Where("code = ?", code).First(&role).Error; err != nil {
+			log.Printf("查询角色失败 %s: %v", code, err)
+			continue
+		}
+		if count := db.Model(&role).Association("Menus").Count(); count > 0 {
+			fmt.Printf("  - 角色已分配 %d 个菜单: %s\n", count, code)
+			continue
+		}
+
+		var menus []models.Menu
+		if err := db.Where("path IN ?", paths).Find(&menus).Error; err != nil {
+			log.Printf("查询菜单失败 %s: %v", code, err)
+			continue
+		}
+		if err := db.Model(&role).Association("Menus").Replace(menus); err != nil {
+			log.Printf("分配角色菜单失败 %s: %v", code, err)
+			continue
+		}
+		fmt.Printf("  ✓ 角色分配菜单 %d 个: %s\n", len(menus), code)
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
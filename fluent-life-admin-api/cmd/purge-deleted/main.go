@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"fluent-life-admin-api/internal/config"
+	"fluent-life-admin-api/internal/recyclebin"
+)
+
+// 彻底删除回收站中超过保留期的记录（用户、帖子、评论、房间、练习内容），
+// 适合通过 cron 每天执行一次。
+//
+//	go run cmd/purge-deleted/main.go [-retention-days 30] [-dry-run]
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	retentionDays := flag.Int("retention-days", cfg.RecycleBinRetentionDays, "回收站保留天数，默认取配置 RECYCLE_BIN_RETENTION_DAYS")
+	dryRun := flag.Bool("dry-run", false, "只统计将被删除的数量，不实际删除")
+	flag.Parse()
+
+	if *retentionDays < 1 {
+		log.Fatalf("retention-days must be at least 1, got %d", *retentionDays)
+	}
+
+	db, err := config.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+
+	before := time.Now().AddDate(0, 0, -*retentionDays)
+	fmt.Printf("清除 %s 之前删除的记录（保留 %d 天）...\n", before.Format("2006-01-02 15:04:05"), *retentionDays)
+
+	result, err := recyclebin.PurgeExpired(db, before, *dryRun)
+	for _, res := range recyclebin.Resources {
+		if n, ok := result[res.Name]; ok {
+			fmt.Printf("  %s: %d\n", res.Label, n)
+		} else {
+			fmt.Printf("  %s: 失败\n", res.Label)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Purge failed: %v", err)
+	}
+
+	if *dryRun {
+		fmt.Println("dry-run 模式，未删除任何数据")
+		return
+	}
+	fmt.Println("✅ 回收站清理完成！")
+}
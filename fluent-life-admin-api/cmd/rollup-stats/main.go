@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"fluent-life-admin-api/internal/analytics"
+	"fluent-life-admin-api/internal/config"
+	"fluent-life-admin-api/internal/models"
+)
+
+// 重新计算每日统计汇总表 daily_stats，用于首次上线时回填历史数据，
+// 或在回收站删除、恢复旧数据后修正对应日期的统计。
+//
+//	go run cmd/rollup-stats/main.go [-from 2025-01-01] [-to 2025-12-31]
+func main() {
+	from := flag.String("from", "", "开始日期 YYYY-MM-DD（北京时间），默认最早一条用户或训练记录的日期")
+	to := flag.String("to", "", "结束日期 YYYY-MM-DD（含），默认今天")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := config.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.DailyStat{}); err != nil {
+		log.Fatalf("Failed to migrate daily_stats: %v", err)
+	}
+
+	now := time.Now()
+	start, end := now, now
+	if *from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", *from, analytics.Location); err != nil {
+			log.Fatalf("Invalid -from %q: %v", *from, err)
+		}
+	} else if start, err = analytics.FirstDay(db, now); err != nil {
+		log.Fatalf("Failed to find first day: %v", err)
+	}
+	if *to != "" {
+		if end, err = time.ParseInLocation("2006-01-02", *to, analytics.Location); err != nil {
+			log.Fatalf("Invalid -to %q: %v", *to, err)
+		}
+	}
+
+	if end.Before(analytics.StartOf(start, analytics.Day)) {
+		log.Fatalf("-to %s is before -from %s", end.Format("2006-01-02"), start.Format("2006-01-02"))
+	}
+
+	fmt.Printf("重新计算 %s ~ %s 的每日统计...\n", start.In(analytics.Location).Format("2006-01-02"), end.In(analytics.Location).Format("2006-01-02"))
+
+	// 按月分批，避免单条语句扫描过多数据
+	for batchStart := start; !batchStart.After(end); {
+		batchEnd := analytics.StartOf(batchStart, analytics.Month).AddDate(0, 1, -1)
+		if batchEnd.After(end) {
+			batchEnd = end
+		}
+		if err := analytics.Rollup(db, batchStart, batchEnd); err != nil {
+			log.Fatalf("Rollup failed: %v", err)
+		}
+		fmt.Printf("  %s ~ %s\n", batchStart.In(analytics.Location).Format("2006-01-02"), batchEnd.In(analytics.Location).Format("2006-01-02"))
+		batchStart = batchEnd.AddDate(0, 0, 1)
+	}
+
+	fmt.Println("✅ 每日统计计算完成！")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fluent-life-admin-api/internal/middleware"
+	"fluent-life-admin-api/internal/models"
+)
+
+// auditResources 声明后台路由对应的实体，审计中间件据此加载变更前后的快照。
+// 未列出的路由仍会记录操作日志，只是没有快照。
+var auditResources = []middleware.AuditResource{
+	{Prefix: "/api/v1/admin/users", Resource: "User", Model: &models.User{}, Param: "id"},
+	{Prefix: "/api/v1/admin/posts", Resource: "Post", Model: &models.Post{}, Param: "id"},
+	{Prefix: "/api/v1/admin/rooms", Resource: "Room", Model: &models.PracticeRoom{}, Param: "id"},
+	{Prefix: "/api/v1/admin/training/records", Resource: "TrainingRecord", Model: &models.TrainingRecord{}, Param: "id"},
+	{Prefix: "/api/v1/admin/comments", Resource: "Comment", Model: &models.Comment{}, Param: "id"},
+	{Prefix: "/api/v1/admin/follows", Resource: "Follow"},
+	{Prefix: "/api/v1/admin/post-collections", Resource: "PostCollection"},
+	{Prefix: "/api/v1/admin/post-likes", Resource: "PostLike"},
+	{Prefix: "/api/v1/admin/tongue-twisters", Resource: "TongueTwister", Model: &models.TongueTwister{}, Param: "id"},
+	{Prefix: "/api/v1/admin/daily-expressions", Resource: "DailyExpression", Model: &models.DailyExpression{}, Param: "id"},
+	{Prefix: "/api/v1/admin/speech-techniques", Resource: "SpeechTechnique", Model: &models.SpeechTechnique{}, Param: "id"},
+	{Prefix: "/api/v1/admin/achievements", Resource: "Achievement", Model: &models.Achievement{}, Param: "id"},
+	{Prefix: "/api/v1/admin/meditation-progress", Resource: "MeditationProgress", Model: &models.MeditationProgress{}, Param: "id"},
+	{Prefix: "/api/v1/admin/ai-conversations", Resource: "AIConversation"},
+	{Prefix: "/api/v1/admin/verification-codes", Resource: "VerificationCode"},
+	{Prefix: "/api/v1/admin/user-settings", Resource: "UserSettings", Model: &models.UserSettings{}, Param: "user_id", Column: "user_id"},
+	{Prefix: "/api/v1/admin/feedback", Resource: "Feedback", Model: &models.Feedback{}, Param: "id"},
+	{Prefix: "/api/v1/admin/legal-documents", Resource: "LegalDocument", Model: &models.LegalDocument{}, Param: "id"},
+	{Prefix: "/api/v1/admin/help-categories", Resource: "HelpCategory", Model: &models.HelpCategory{}, Param: "id"},
+	{Prefix: "/api/v1/admin/help-articles", Resource: "HelpArticle", Model: &models.HelpArticle{}, Param: "id"},
+	{Prefix: "/api/v1/admin/app-settings", Resource: "AppSetting", Model: &models.AppSetting{}, Param: "key", Column: "key"},
+	{Prefix: "/api/v1/admin/feature-flags", Resource: "FeatureFlag", Model: &models.FeatureFlag{}, Param: "id"},
+	// AI 角色保存在 ai_roles 表中，app_settings 的 ai_simulation_roles 只是由其同步生成的只读快照
+	{Prefix: "/api/v1/admin/ai-roles", Resource: "AIRole", Model: &models.AIRole{}, Param: "id"},
+	{Prefix: "/api/v1/admin/voice-types", Resource: "VoiceType", Model: &models.VoiceType{}, Param: "id"},
+	{Prefix: "/api/v1/admin/exposure/modules", Resource: "ExposureModule", Model: &models.ExposureModule{}, Param: "id"},
+	{Prefix: "/api/v1/admin/exposure/modules/:id/steps", Resource: "ExposureStep", Model: &models.ExposureStep{}},
+	{Prefix: "/api/v1/admin/exposure/steps", Resource: "ExposureStep", Model: &models.ExposureStep{}, Param: "step_id"},
+	{Prefix: "/api/v1/admin/videos", Resource: "Video"},
+	{Prefix: "/api/v1/admin/roles", Resource: "Role", Model: &models.Role{}, Param: "id"},
+	{Prefix: "/api/v1/admin/menus", Resource: "Menu", Model: &models.Menu{}, Param: "id"},
+	{Prefix: "/api/v1/admin/sanctions", Resource: "UserSanction", Model: &models.UserSanction{}, Param: "id"},
+	{Prefix: "/api/v1/admin/sensitive-word-categories", Resource: "SensitiveWordCategory", Model: &models.SensitiveWordCategory{}, Param: "id"},
+	{Prefix: "/api/v1/admin/sensitive-words", Resource: "SensitiveWord", Model: &models.SensitiveWord{}, Param: "id"},
+	{Prefix: "/api/v1/admin/jobs", Resource: "Job", Model: &models.Job{}, Param: "id"},
+}
@@ -2,11 +2,22 @@ package main
 
 import (
 	"log"
+	"os"
+	"time"
 
+	"fluent-life-admin-api/internal/airole"
+	"fluent-life-admin-api/internal/analytics"
 	"fluent-life-admin-api/internal/config"
 	"fluent-life-admin-api/internal/handlers"
+	"fluent-life-admin-api/internal/jobs"
 	"fluent-life-admin-api/internal/middleware"
 	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/permission"
+	"fluent-life-admin-api/internal/recyclebin"
+	"fluent-life-admin-api/internal/sanction"
+	"fluent-life-admin-api/internal/sensitive"
+	"fluent-life-admin-api/internal/settings"
+	"fluent-life-admin-api/pkg/auth"
 	"fluent-life-admin-api/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -30,12 +41,20 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// AI角色从 ai_simulation_roles 设置迁移到 ai_roles 表，只在表为空时执行
+	if n, err := airole.ImportFromSetting(db); err != nil {
+		log.Printf("迁移AI角色失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已从 ai_simulation_roles 设置迁移 %d 个AI角色", n)
+	}
+
 	// Check and create default admin user if not exists
+	// 回收站中的 admin 仍占用用户名，不能重新创建，需由其他管理员在回收站中恢复
 	var adminUser models.User
-	if err := db.Where("username = ?", "admin").First(&adminUser).Error; err != nil {
+	if err := db.Unscoped().Where("username = ?", "admin").First(&adminUser).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// Create default admin user
-			hashedPassword, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(auth.DefaultAdminPassword), bcrypt.DefaultCost)
 			if err != nil {
 				log.Fatalf("Failed to hash password: %v", err)
 			}
@@ -44,6 +63,8 @@ func main() {
 				Username:     "admin",
 				PasswordHash: string(hashedPassword),
 				Role:         "super_admin", // Assign super_admin role
+				// 默认密码众所周知，首次登录后必须修改
+				MustChangePassword: true,
 			}
 			if err := db.Create(&adminUser).Error; err != nil {
 				log.Fatalf("Failed to create default admin user: %v", err)
@@ -52,6 +73,31 @@ func main() {
 		} else {
 			log.Fatalf("Failed to query admin user: %v", err)
 		}
+	} else if adminUser.DeletedAt.Valid {
+		log.Println("Default admin user is in the recycle bin; restore it from the recycle bin to use it again")
+	} else if !adminUser.MustChangePassword &&
+		bcrypt.CompareHashAndPassword([]byte(adminUser.PasswordHash), []byte(auth.DefaultAdminPassword)) == nil {
+		// 已存在的管理员仍在使用默认密码时，强制其修改
+		if err := db.Model(&adminUser).Update("must_change_password", true).Error; err != nil {
+			log.Fatalf("Failed to flag default admin password: %v", err)
+		}
+		log.Println("Admin user still uses the default password; a password change is required on next login")
+	}
+
+	if err := sensitive.EnsureDefaultCategories(db); err != nil {
+		log.Printf("Failed to create default sensitive word categories: %v", err)
+	}
+	sensitiveFilter := sensitive.NewFilter(db)
+	if cfg.SensitiveScanIntervalSeconds > 0 {
+		sensitive.StartScanner(db, sensitiveFilter, time.Duration(cfg.SensitiveScanIntervalSeconds)*time.Second)
+	}
+
+	if cfg.SanctionSweepIntervalSeconds > 0 {
+		sanction.StartSweeper(db, time.Duration(cfg.SanctionSweepIntervalSeconds)*time.Second)
+	}
+
+	if cfg.StatsRollupIntervalSeconds > 0 {
+		analytics.StartAggregator(db, time.Duration(cfg.StatsRollupIntervalSeconds)*time.Second)
 	}
 
 	if cfg.Environment == "production" {
@@ -65,204 +111,371 @@ func main() {
 		response.Success(c, gin.H{"status": "ok"}, "服务运行正常")
 	})
 
-	adminHandler := handlers.NewAdminHandler(db)
+	settingStore := settings.NewStore(db, time.Duration(cfg.SettingsCacheSeconds)*time.Second)
+	adminHandler := handlers.NewAdminHandler(db, settingStore)
 	exposureModuleHandler := handlers.NewAdminExposureModuleHandler(db)
 	adminVideoHandler := handlers.NewAdminVideoHandler(db)
-	adminPermissionHandler := handlers.NewAdminPermissionHandler(db)
+	permissions := permission.NewRegistry(permission.Catalog)
+	adminPermissionHandler := handlers.NewAdminPermissionHandler(db, permissions)
+	recycleBinHandler := handlers.NewAdminRecycleBinHandler(db)
+	moderationHandler := handlers.NewAdminModerationHandler(db)
+	sensitiveWordHandler := handlers.NewAdminSensitiveWordHandler(db, sensitiveFilter)
+	analyticsHandler := handlers.NewAdminAnalyticsHandler(db)
+	helpHandler := handlers.NewAdminHelpHandler(db)
+	appSettingHandler := handlers.NewAdminAppSettingHandler(db, settingStore)
+	featureFlagHandler := handlers.NewAdminFeatureFlagHandler(db)
+
+	// 后台任务：JobWorkers 为 0 时仍可提交任务，由其他进程的工作协程执行
+	if err := os.MkdirAll(cfg.JobFilesDir, 0o755); err != nil {
+		log.Fatalf("Failed to create job files directory: %v", err)
+	}
+	jobQueue := jobs.NewQueue(db)
+	adminHandler.RegisterJobs(jobQueue, cfg.JobFilesDir)
+	jobHandler := handlers.NewAdminJobHandler(db, jobQueue, cfg.JobFilesDir)
+	if cfg.JobWorkers > 0 {
+		jobQueue.Start(cfg.JobWorkers, time.Duration(cfg.JobPollIntervalSeconds)*time.Second,
+			time.Duration(cfg.JobRetentionDays)*24*time.Hour)
+	}
+
+	// perm 校验路由所需的权限代码，权限来自当前用户角色的 models.Role.Permissions
+	perm := func(code string) gin.HandlerFunc {
+		return middleware.RequirePermission(db, code)
+	}
 
 	api := r.Group("/api/v1")
 	{
 		// 管理员登录
 		api.POST("/admin/login", adminHandler.Login)
+		// 两步验证登录
+		api.POST("/admin/login/2fa", adminHandler.LoginTwoFactor)
+		// 刷新令牌
+		api.POST("/admin/refresh", adminHandler.RefreshToken)
 
 		// 测试根路由
 		api.GET("/test-root", adminHandler.TestRoute)
 
+		// 帮助中心（客户端使用，无需登录）
+		api.GET("/help/categories", helpHandler.GetPublicHelpCenter)
+		api.GET("/help/articles/:id", helpHandler.GetPublicHelpArticle)
+		api.POST("/help/articles/:id/vote", helpHandler.VoteHelpArticle)
+		// 功能开关求值，供主站后端按用户ID调用，需要服务凭证
+		api.GET("/feature-flags", middleware.ServiceAuthMiddleware(cfg.ServiceToken), featureFlagHandler.GetUserFeatureFlags)
+
 		// 需要认证的管理接口（简化版，实际应该使用JWT中间件）
 		admin := api.Group("/admin")
 		admin.Use(middleware.UserAuthMiddleware(db))
 		admin.Use(middleware.AdminAuthMiddleware())
+		admin.Use(middleware.Audit(db, auditResources))
+		admin.Use(middleware.PasswordChangeGuard("/api/v1/admin/change-password", "/api/v1/admin/logout"))
+		// routes 注册需要权限的路由，并登记到权限目录；admin 上直接注册的路由所有管理员都可访问
+		routes := permission.NewRouter(admin, permissions, perm)
 		{
+			// 退出登录
+			admin.POST("/logout", adminHandler.Logout)
+			// 修改当前账号密码
+			admin.POST("/change-password", adminHandler.ChangePassword)
+
+			// 当前账号的两步验证
+			admin.GET("/2fa", adminHandler.GetTwoFactorStatus)
+			admin.POST("/2fa/setup", adminHandler.SetupTwoFactor)
+			admin.POST("/2fa/enable", adminHandler.EnableTwoFactor)
+			admin.POST("/2fa/disable", adminHandler.DisableTwoFactor)
+			admin.POST("/2fa/recovery-codes", adminHandler.RegenerateRecoveryCodes)
+
 			// 用户管理
-			admin.GET("/users", adminHandler.GetUsers)
-			admin.GET("/users/:id", adminHandler.GetUser)
-			admin.POST("/users", adminHandler.CreateUser)
-			admin.PUT("/users/:id", adminHandler.UpdateUser)
-			admin.DELETE("/users/:id", adminHandler.DeleteUser)
+			routes.GET("/users", "user:read", adminHandler.GetUsers)
+			routes.GET("/users/export", "user:export", adminHandler.ExportUsers)
+			routes.GET("/users/:id", "user:read", adminHandler.GetUser)
+			routes.POST("/users", "user:write", adminHandler.CreateUser)
+			routes.PUT("/users/:id", "user:write", adminHandler.UpdateUser)
+			routes.DELETE("/users/:id", "user:write", adminHandler.DeleteUser)
+			routes.POST("/users/:id/2fa/reset", "user:2fa-reset", adminHandler.ResetUserTwoFactor)
+			routes.GET("/users/:id/profile", "user:read", analyticsHandler.GetUserProfile)
+			routes.GET("/users/:id/sanctions", "user:read", adminHandler.GetUserSanctions)
+			routes.POST("/users/:id/sanctions", "user:write", adminHandler.CreateUserSanction)
+			routes.GET("/sanctions", "user:read", adminHandler.GetSanctions)
+			routes.POST("/sanctions/:id/revoke", "user:write", adminHandler.RevokeSanction)
+			routes.POST("/users/:id/export", "user:export", adminHandler.ExportUserData)
+			routes.POST("/users/:id/erase", "user:erase", adminHandler.EraseUser)
+			routes.GET("/data-erasure-records", "user:erase", adminHandler.GetDataErasureRecords)
+			routes.GET("/users/:id/permissions", "permission:read", adminPermissionHandler.GetUserPermissions)
+			routes.PUT("/users/:id/roles", "permission:write", adminPermissionHandler.UpdateUserRoles)
 
 			// 帖子管理
-			admin.GET("/posts", adminHandler.GetPosts)
-			admin.GET("/posts/:id", adminHandler.GetPost)
-			admin.POST("/posts", adminHandler.CreatePost)
-			admin.PUT("/posts/:id", adminHandler.UpdatePost)
-			admin.POST("/posts/delete-batch", adminHandler.DeletePost)
+			routes.GET("/posts", "post:read", adminHandler.GetPosts)
+			routes.GET("/posts/export", "post:read", adminHandler.ExportPosts)
+			routes.GET("/posts/:id", "post:read", adminHandler.GetPost)
+			routes.POST("/posts", "post:write", adminHandler.CreatePost)
+			routes.PUT("/posts/:id", "post:write", adminHandler.UpdatePost)
+			routes.POST("/posts/delete-batch", "post:write", adminHandler.DeletePost)
 
 			// 房间管理
-			admin.GET("/rooms", adminHandler.GetRooms)
-			admin.GET("/rooms/:id", adminHandler.GetRoom)
-			admin.POST("/rooms", adminHandler.CreateRoom)
-			admin.PUT("/rooms/:id", adminHandler.UpdateRoom)
-			admin.DELETE("/rooms/:id", adminHandler.DeleteRoom)
-			admin.POST("/rooms/delete-batch", adminHandler.DeleteRoom)
-			admin.PATCH("/rooms/:id/toggle", adminHandler.ToggleRoom)
+			routes.GET("/rooms", "room:read", adminHandler.GetRooms)
+			routes.GET("/rooms/:id", "room:read", adminHandler.GetRoom)
+			routes.POST("/rooms", "room:write", adminHandler.CreateRoom)
+			routes.PUT("/rooms/:id", "room:write", adminHandler.UpdateRoom)
+			routes.DELETE("/rooms/:id", "room:write", adminHandler.DeleteRoom)
+			routes.POST("/rooms/delete-batch", "room:write", adminHandler.DeleteRoom)
+			routes.PATCH("/rooms/:id/toggle", "room:write", adminHandler.ToggleRoom)
 
 			// 训练统计
-			admin.GET("/training/stats", adminHandler.GetTrainingStats)
-			admin.GET("/training/detailed-stats", adminHandler.GetDetailedStats)
-			admin.GET("/training/records", adminHandler.GetTrainingRecords)
-			admin.GET("/training/records/:id", adminHandler.GetTrainingRecord)
-			admin.PUT("/training/records/:id", adminHandler.UpdateTrainingRecord)
-			admin.POST("/training/records/delete-batch", adminHandler.DeleteTrainingRecord)
+			routes.GET("/training/stats", "training:read", adminHandler.GetTrainingStats)
+			routes.GET("/training/detailed-stats", "training:read", adminHandler.GetDetailedStats)
+
+			// 数据分析
+			routes.GET("/analytics/timeseries", "analytics:read", analyticsHandler.GetTimeSeries)
+			routes.GET("/analytics/cohorts", "analytics:read", analyticsHandler.GetCohorts)
+			routes.GET("/training/records", "training:read", adminHandler.GetTrainingRecords)
+			routes.GET("/training/records/export", "training:read", adminHandler.ExportTrainingRecords)
+			routes.GET("/training/records/:id", "training:read", adminHandler.GetTrainingRecord)
+			routes.PUT("/training/records/:id", "training:write", adminHandler.UpdateTrainingRecord)
+			routes.POST("/training/records/delete-batch", "training:write", adminHandler.DeleteTrainingRecord)
 
 			// 随机匹配记录
-			admin.GET("/random-match", adminHandler.GetRandomMatchRecords)
+			routes.GET("/random-match", "training:read", adminHandler.GetRandomMatchRecords)
+			routes.GET("/random-match/export", "training:read", adminHandler.ExportRandomMatchRecords)
 
 			// 操作日志管理
-			admin.GET("/operation-logs", adminHandler.GetOperationLogs)
-			admin.GET("/operation-logs/:id", adminHandler.GetOperationLog)
+			routes.GET("/operation-logs", "log:read", adminHandler.GetOperationLogs)
+			routes.GET("/operation-logs/export", "log:read", adminHandler.ExportOperationLogs)
+			routes.GET("/operation-logs/:id", "log:read", adminHandler.GetOperationLog)
+
+			// 后台任务：提交时按任务类型校验权限，其他管理员的任务需要 job:manage
+			admin.GET("/jobs/types", jobHandler.GetJobTypes)
+			admin.GET("/jobs", jobHandler.GetJobs)
+			admin.POST("/jobs", jobHandler.SubmitJob)
+			admin.GET("/jobs/:id", jobHandler.GetJob)
+			admin.POST("/jobs/:id/cancel", jobHandler.CancelJob)
+			admin.GET("/jobs/:id/file", jobHandler.DownloadJobFile)
 
 			// 评论管理
-			admin.GET("/comments", adminHandler.GetComments)
-			admin.GET("/comments/:id", adminHandler.GetComment)
-			admin.PUT("/comments/:id", adminHandler.UpdateComment)
-			admin.POST("/comments/delete-batch", adminHandler.DeleteComment)
+			routes.GET("/comments", "comment:read", adminHandler.GetComments)
+			routes.GET("/comments/export", "comment:read", adminHandler.ExportComments)
+			routes.GET("/comments/:id", "comment:read", adminHandler.GetComment)
+			routes.PUT("/comments/:id", "comment:write", adminHandler.UpdateComment)
+			routes.POST("/comments/delete-batch", "comment:write", adminHandler.DeleteComment)
 
 			// 关注/收藏管理
-			admin.GET("/follows", adminHandler.GetFollows)
-			admin.POST("/follows/delete-batch", adminHandler.DeleteFollow)
-			admin.GET("/post-collections", adminHandler.GetPostCollections)
-			admin.POST("/post-collections/delete-batch", adminHandler.DeletePostCollection)
+			routes.GET("/follows", "user:read", adminHandler.GetFollows)
+			routes.POST("/follows/delete-batch", "user:write", adminHandler.DeleteFollow)
+			routes.GET("/post-collections", "post:read", adminHandler.GetPostCollections)
+			routes.POST("/post-collections/delete-batch", "post:write", adminHandler.DeletePostCollection)
 
 			// 点赞管理
-			admin.GET("/post-likes", adminHandler.GetPostLikes)
-			admin.POST("/post-likes/delete-batch", adminHandler.DeletePostLike)
+			routes.GET("/post-likes", "post:read", adminHandler.GetPostLikes)
+			routes.POST("/post-likes/delete-batch", "post:write", adminHandler.DeletePostLike)
 
 			// 绕口令管理
-			admin.GET("/tongue-twisters", adminHandler.GetTongueTwisters)
-			admin.GET("/tongue-twisters/:id", adminHandler.GetTongueTwister)
-			admin.POST("/tongue-twisters", adminHandler.CreateTongueTwister)
-			admin.POST("/tongue-twisters/batch-create", adminHandler.BatchCreateTongueTwisters)
-			admin.PUT("/tongue-twisters/:id", adminHandler.UpdateTongueTwister)
-			admin.POST("/tongue-twisters/delete-batch", adminHandler.DeleteTongueTwister)
-			admin.DELETE("/tongue-twisters/all", adminHandler.DeleteAllTongueTwisters)
-			admin.POST("/tongue-twisters/clean", adminHandler.CleanTongueTwisters)
+			routes.GET("/tongue-twisters", "content:read", adminHandler.GetTongueTwisters)
+			routes.GET("/tongue-twisters/:id", "content:read", adminHandler.GetTongueTwister)
+			routes.POST("/tongue-twisters", "content:write", adminHandler.CreateTongueTwister)
+			routes.POST("/tongue-twisters/batch-create", "content:write", adminHandler.BatchCreateTongueTwisters)
+			routes.PUT("/tongue-twisters/:id", "content:write", adminHandler.UpdateTongueTwister)
+			routes.POST("/tongue-twisters/delete-batch", "content:write", adminHandler.DeleteTongueTwister)
+			routes.DELETE("/tongue-twisters/all", "content:write", adminHandler.DeleteAllTongueTwisters)
+			routes.POST("/tongue-twisters/clean", "content:write", adminHandler.CleanTongueTwisters)
 
 			// 每日朗诵文案管理
-			admin.GET("/daily-expressions", adminHandler.GetDailyExpressions)
-			admin.GET("/daily-expressions/:id", adminHandler.GetDailyExpression)
-			admin.POST("/daily-expressions", adminHandler.CreateDailyExpression)
-			admin.POST("/daily-expressions/batch-create", adminHandler.BatchCreateDailyExpressions)
-			admin.PUT("/daily-expressions/:id", adminHandler.UpdateDailyExpression)
-			admin.POST("/daily-expressions/delete-batch", adminHandler.DeleteDailyExpression)
+			routes.GET("/daily-expressions", "content:read", adminHandler.GetDailyExpressions)
+			routes.GET("/daily-expressions/:id", "content:read", adminHandler.GetDailyExpression)
+			routes.POST("/daily-expressions", "content:write", adminHandler.CreateDailyExpression)
+			routes.POST("/daily-expressions/batch-create", "content:write", adminHandler.BatchCreateDailyExpressions)
+			routes.PUT("/daily-expressions/:id", "content:write", adminHandler.UpdateDailyExpression)
+			routes.POST("/daily-expressions/delete-batch", "content:write", adminHandler.DeleteDailyExpression)
 
 			// 语音技巧训练管理
-			admin.GET("/speech-techniques", adminHandler.GetSpeechTechniques)
-			admin.GET("/speech-techniques/:id", adminHandler.GetSpeechTechnique)
-			admin.POST("/speech-techniques", adminHandler.CreateSpeechTechnique)
-			admin.POST("/speech-techniques/batch-create", adminHandler.BatchCreateSpeechTechniques)
-			admin.PUT("/speech-techniques/:id", adminHandler.UpdateSpeechTechnique)
-			admin.POST("/speech-techniques/delete-batch", adminHandler.DeleteSpeechTechnique)
+			routes.GET("/speech-techniques", "content:read", adminHandler.GetSpeechTechniques)
+			routes.GET("/speech-techniques/:id", "content:read", adminHandler.GetSpeechTechnique)
+			routes.POST("/speech-techniques", "content:write", adminHandler.CreateSpeechTechnique)
+			routes.POST("/speech-techniques/batch-create", "content:write", adminHandler.BatchCreateSpeechTechniques)
+			routes.PUT("/speech-techniques/:id", "content:write", adminHandler.UpdateSpeechTechnique)
+			routes.POST("/speech-techniques/delete-batch", "content:write", adminHandler.DeleteSpeechTechnique)
 
 			// 成就管理
-			admin.POST("/achievements", adminHandler.CreateAchievement)
-			admin.GET("/achievements", adminHandler.GetAchievements)
-			admin.GET("/achievements/:id", adminHandler.GetAchievement)
-			admin.DELETE("/achievements/:id", adminHandler.DeleteAchievement)
+			routes.POST("/achievements", "training:write", adminHandler.CreateAchievement)
+			routes.GET("/achievements", "training:read", adminHandler.GetAchievements)
+			routes.GET("/achievements/:id", "training:read", adminHandler.GetAchievement)
+			routes.DELETE("/achievements/:id", "training:write", adminHandler.DeleteAchievement)
 
 			// 冥想进度管理
-			admin.POST("/meditation-progress", adminHandler.CreateMeditationProgress)
-			admin.GET("/meditation-progress", adminHandler.GetMeditationProgresses)
-			admin.GET("/meditation-progress/:id", adminHandler.GetMeditationProgress)
-			admin.PUT("/meditation-progress/:id", adminHandler.UpdateMeditationProgress)
-			admin.DELETE("/meditation-progress/:id", adminHandler.DeleteMeditationProgress)
+			routes.POST("/meditation-progress", "training:write", adminHandler.CreateMeditationProgress)
+			routes.GET("/meditation-progress", "training:read", adminHandler.GetMeditationProgresses)
+			routes.GET("/meditation-progress/:id", "training:read", adminHandler.GetMeditationProgress)
+			routes.PUT("/meditation-progress/:id", "training:write", adminHandler.UpdateMeditationProgress)
+			routes.DELETE("/meditation-progress/:id", "training:write", adminHandler.DeleteMeditationProgress)
 
 			// AI对话管理
-			admin.GET("/ai-conversations", adminHandler.GetAIConversations)
-			admin.GET("/ai-conversations/:id", adminHandler.GetAIConversation)
-			admin.POST("/ai-conversations/delete-batch", adminHandler.DeleteAIConversation)
+			routes.GET("/ai-conversations", "ai:read", adminHandler.GetAIConversations)
+			routes.GET("/ai-conversations/:id", "ai:read", adminHandler.GetAIConversation)
+			routes.POST("/ai-conversations/delete-batch", "ai:write", adminHandler.DeleteAIConversation)
 
 			// 验证码管理
-			admin.GET("/verification-codes", adminHandler.GetVerificationCodes)
-			admin.GET("/verification-codes/:id", adminHandler.GetVerificationCode)
-			admin.POST("/verification-codes/delete-batch", adminHandler.DeleteVerificationCode)
+			routes.GET("/verification-codes", "user:read", adminHandler.GetVerificationCodes)
+			routes.GET("/verification-codes/:id", "user:read", adminHandler.GetVerificationCode)
+			routes.POST("/verification-codes/delete-batch", "user:write", adminHandler.DeleteVerificationCode)
 
 			// 测试路由
 			admin.GET("/test", adminHandler.TestRoute)
 
 			// 用户设置管理
-			admin.GET("/user-settings/:user_id", adminHandler.GetUserSettings)
-			admin.PUT("/user-settings/:user_id", adminHandler.UpdateUserSettings)
-			admin.GET("/user-settings", adminHandler.GetAllUserSettings)
-			admin.POST("/user-settings/:user_id/reset", adminHandler.ResetUserSettings)
+			routes.GET("/user-settings/:user_id", "user:read", adminHandler.GetUserSettings)
+			routes.PUT("/user-settings/:user_id", "user:write", adminHandler.UpdateUserSettings)
+			routes.GET("/user-settings", "user:read", adminHandler.GetAllUserSettings)
+			routes.POST("/user-settings/:user_id/reset", "user:write", adminHandler.ResetUserSettings)
 
 			// 用户反馈管理
-			admin.GET("/feedback", adminHandler.GetFeedbackList)
-			admin.GET("/feedback/:id", adminHandler.GetFeedback)
-			admin.PUT("/feedback/:id/status", adminHandler.UpdateFeedbackStatus)
-			admin.DELETE("/feedback/:id", adminHandler.DeleteFeedback)
-			admin.GET("/feedback-stats", adminHandler.GetFeedbackStats)
+			routes.GET("/feedback", "feedback:read", adminHandler.GetFeedbackList)
+			routes.GET("/feedback/export", "feedback:read", adminHandler.ExportFeedback)
+			routes.GET("/feedback/:id", "feedback:read", adminHandler.GetFeedback)
+			routes.PUT("/feedback/:id/status", "feedback:write", adminHandler.UpdateFeedbackStatus)
+			routes.DELETE("/feedback/:id", "feedback:write", adminHandler.DeleteFeedback)
+			routes.GET("/feedback-stats", "feedback:read", adminHandler.GetFeedbackStats)
+
+			// 举报管理
+			routes.GET("/reports", "report:read", adminHandler.GetReports)
+			routes.GET("/report-cases", "report:read", adminHandler.GetReportCases)
+			routes.GET("/report-cases/:target_type/:target_id", "report:read", adminHandler.GetReportCase)
+			routes.POST("/report-cases/:target_type/:target_id/resolve", "report:write", adminHandler.ResolveReportCase)
+			routes.GET("/report-stats", "report:read", adminHandler.GetReportStats)
 
 			// 法律文档管理
-			admin.GET("/legal-documents", adminHandler.GetLegalDocuments)
-			admin.GET("/legal-documents/:id", adminHandler.GetLegalDocument)
-			admin.POST("/legal-documents", adminHandler.CreateLegalDocument)
-			admin.PUT("/legal-documents/:id", adminHandler.UpdateLegalDocument)
-			admin.DELETE("/legal-documents/:id", adminHandler.DeleteLegalDocument)
+			routes.GET("/legal-documents", "content:read", adminHandler.GetLegalDocuments)
+			routes.GET("/legal-documents/:id", "content:read", adminHandler.GetLegalDocument)
+			routes.POST("/legal-documents", "content:write", adminHandler.CreateLegalDocument)
+			routes.PUT("/legal-documents/:id", "content:write", adminHandler.UpdateLegalDocument)
+			routes.DELETE("/legal-documents/:id", "content:write", adminHandler.DeleteLegalDocument)
+
+			// 帮助中心
+			routes.GET("/help-categories", "content:read", helpHandler.GetHelpCategories)
+			routes.POST("/help-categories", "content:write", helpHandler.CreateHelpCategory)
+			routes.PUT("/help-categories/order", "content:write", helpHandler.ReorderHelpCategories)
+			routes.PUT("/help-categories/:id", "content:write", helpHandler.UpdateHelpCategory)
+			routes.DELETE("/help-categories/:id", "content:write", helpHandler.DeleteHelpCategory)
+			routes.PUT("/help-categories/:id/articles/order", "content:write", helpHandler.ReorderHelpArticles)
+			routes.GET("/help-articles", "content:read", helpHandler.GetHelpArticles)
+			routes.POST("/help-articles", "content:write", helpHandler.CreateHelpArticle)
+			routes.PUT("/help-articles/:id", "content:write", helpHandler.UpdateHelpArticle)
+			routes.DELETE("/help-articles/:id", "content:write", helpHandler.DeleteHelpArticle)
+
+			// 应用设置：按 key 读写，修改、恢复默认和回滚都记录版本
+			routes.GET("/app-settings", "setting:read", appSettingHandler.GetAppSettings)
+			routes.GET("/app-settings/:key", "setting:read", appSettingHandler.GetAppSetting)
+			routes.PUT("/app-settings/:key", "setting:write", appSettingHandler.UpdateAppSetting)
+			routes.DELETE("/app-settings/:key", "setting:write", appSettingHandler.ResetAppSetting)
+			routes.GET("/app-settings/:key/history", "setting:read", appSettingHandler.GetAppSettingHistory)
+			routes.POST("/app-settings/:key/rollback", "setting:write", appSettingHandler.RollbackAppSetting)
+
+			// 功能开关
+			routes.GET("/feature-flags", "flag:read", featureFlagHandler.GetFeatureFlags)
+			routes.GET("/feature-flags/evaluate", "flag:read", featureFlagHandler.EvaluateFeatureFlags)
+			routes.GET("/feature-flags/:id", "flag:read", featureFlagHandler.GetFeatureFlag)
+			routes.POST("/feature-flags", "flag:write", featureFlagHandler.CreateFeatureFlag)
+			routes.PUT("/feature-flags/:id", "flag:write", featureFlagHandler.UpdateFeatureFlag)
+			routes.DELETE("/feature-flags/:id", "flag:write", featureFlagHandler.DeleteFeatureFlag)
 
 			// AI角色管理
-			admin.GET("/ai-roles", adminHandler.GetAIRoles)
-			admin.POST("/ai-roles", adminHandler.CreateAIRole)
-			admin.PUT("/ai-roles/:id", adminHandler.UpdateAIRole)
-			admin.DELETE("/ai-roles/:id", adminHandler.DeleteAIRole)
-			admin.POST("/ai-roles/init-from-config", adminHandler.InitAIRolesFromConfig)
+			routes.GET("/ai-roles", "ai:read", adminHandler.GetAIRoles)
+			routes.GET("/ai-roles/categories", "ai:read", adminHandler.GetAIRoleCategories)
+			routes.GET("/ai-roles/:id", "ai:read", adminHandler.GetAIRole)
+			routes.POST("/ai-roles", "ai:write", adminHandler.CreateAIRole)
+			routes.PUT("/ai-roles/order", "ai:write", adminHandler.ReorderAIRoles)
+			routes.PUT("/ai-roles/:id", "ai:write", adminHandler.UpdateAIRole)
+			routes.DELETE("/ai-roles/:id", "ai:write", adminHandler.DeleteAIRole)
+			routes.POST("/ai-roles/init-from-config", "ai:write", adminHandler.InitAIRolesFromConfig)
+			// AI角色提示词版本
+			routes.GET("/ai-roles/:id/prompts", "ai:read", adminHandler.GetAIRolePrompts)
+			routes.GET("/ai-roles/:id/prompts/diff", "ai:read", adminHandler.DiffAIRolePrompts)
+			routes.POST("/ai-roles/:id/prompts", "ai:write", adminHandler.CreateAIRolePrompt)
+			routes.POST("/ai-roles/:id/prompts/:version/publish", "ai:write", adminHandler.PublishAIRolePrompt)
+			routes.POST("/ai-roles/:id/prompts/:version/restore", "ai:write", adminHandler.RestoreAIRolePrompt)
 
 			// 音色管理（在AI管理下）
-			admin.GET("/voice-types", adminHandler.GetVoiceTypes)
-			admin.GET("/voice-types/enabled", adminHandler.GetEnabledVoiceTypes)
-			admin.GET("/voice-types/:id", adminHandler.GetVoiceType)
-			admin.POST("/voice-types", adminHandler.CreateVoiceType)
-			admin.PUT("/voice-types/:id", adminHandler.UpdateVoiceType)
-			admin.DELETE("/voice-types/:id", adminHandler.DeleteVoiceType)
+			routes.GET("/voice-types", "ai:read", adminHandler.GetVoiceTypes)
+			routes.GET("/voice-types/enabled", "ai:read", adminHandler.GetEnabledVoiceTypes)
+			routes.GET("/voice-types/:id", "ai:read", adminHandler.GetVoiceType)
+			routes.POST("/voice-types", "ai:write", adminHandler.CreateVoiceType)
+			routes.PUT("/voice-types/:id", "ai:write", adminHandler.UpdateVoiceType)
+			routes.DELETE("/voice-types/:id", "ai:write", adminHandler.DeleteVoiceType)
+			routes.GET("/voice-types/:id/usage", "ai:read", adminHandler.GetVoiceTypeUsage)
+			routes.POST("/voice-types/:id/reassign", "ai:write", adminHandler.ReassignVoiceType)
 
 			// 脱敏练习管理
-			exposureManagement := admin.Group("/exposure")
+			exposureManagement := routes.Group("/exposure")
 			{
 				// 场景管理
-				exposureManagement.GET("/modules", exposureModuleHandler.GetModules)
-				exposureManagement.POST("/modules", exposureModuleHandler.CreateModule)
+				exposureManagement.GET("/modules", "content:read", exposureModuleHandler.GetModules)
+				exposureManagement.POST("/modules", "content:write", exposureModuleHandler.CreateModule)
 				// 批量更新顺序必须在 /modules/:id 之前，否则会匹配到 :id
-				exposureManagement.PUT("/modules/order", exposureModuleHandler.BatchUpdateModulesOrder)
-				exposureManagement.GET("/modules/:id", exposureModuleHandler.GetModule)
-				exposureManagement.PUT("/modules/:id", exposureModuleHandler.UpdateModule)
-				exposureManagement.DELETE("/modules/:id", exposureModuleHandler.DeleteModule)
+				exposureManagement.PUT("/modules/order", "content:write", exposureModuleHandler.BatchUpdateModulesOrder)
+				exposureManagement.GET("/modules/:id", "content:read", exposureModuleHandler.GetModule)
+				exposureManagement.PUT("/modules/:id", "content:write", exposureModuleHandler.UpdateModule)
+				exposureManagement.DELETE("/modules/:id", "content:write", exposureModuleHandler.DeleteModule)
 
 				// 步骤管理
-				exposureManagement.GET("/modules/:id/steps", exposureModuleHandler.GetModuleSteps)
-				exposureManagement.POST("/modules/:id/steps", exposureModuleHandler.CreateStep)
-				exposureManagement.PUT("/modules/:id/steps/order", exposureModuleHandler.BatchUpdateStepsOrder)
-				exposureManagement.PUT("/steps/:step_id", exposureModuleHandler.UpdateStep)
-				exposureManagement.DELETE("/steps/:step_id", exposureModuleHandler.DeleteStep)
+				exposureManagement.GET("/modules/:id/steps", "content:read", exposureModuleHandler.GetModuleSteps)
+				exposureManagement.POST("/modules/:id/steps", "content:write", exposureModuleHandler.CreateStep)
+				exposureManagement.PUT("/modules/:id/steps/order", "content:write", exposureModuleHandler.BatchUpdateStepsOrder)
+				exposureManagement.PUT("/steps/:step_id", "content:write", exposureModuleHandler.UpdateStep)
+				exposureManagement.DELETE("/steps/:step_id", "content:write", exposureModuleHandler.DeleteStep)
 			}
 
 			// 视频管理
-			admin.GET("/videos", adminVideoHandler.GetVideoList)
-			admin.GET("/videos/:id", adminVideoHandler.GetVideoDetail)
-			admin.DELETE("/videos/:id", adminVideoHandler.DeleteVideo)
-			admin.POST("/videos/batch-delete", adminVideoHandler.BatchDeleteVideos)
+			routes.GET("/videos", "video:read", adminVideoHandler.GetVideoList)
+			routes.GET("/videos/:id", "video:read", adminVideoHandler.GetVideoDetail)
+			routes.DELETE("/videos/:id", "video:write", adminVideoHandler.DeleteVideo)
+			routes.POST("/videos/batch-delete", "video:write", adminVideoHandler.BatchDeleteVideos)
+
+			// 当前管理员的侧边栏菜单，按角色分配过滤，无需额外权限
+			admin.GET("/my-menus", adminPermissionHandler.GetMyMenus)
 
 			// 权限管理 - 角色管理
-			admin.GET("/roles", adminPermissionHandler.GetRoles)
-			admin.GET("/roles/:id", adminPermissionHandler.GetRole)
-			admin.POST("/roles", adminPermissionHandler.CreateRole)
-			admin.PUT("/roles/:id", adminPermissionHandler.UpdateRole)
-			admin.DELETE("/roles/:id", adminPermissionHandler.DeleteRole)
+			routes.GET("/roles", "permission:read", adminPermissionHandler.GetRoles)
+			routes.GET("/roles/:id", "permission:read", adminPermissionHandler.GetRole)
+			routes.POST("/roles", "permission:write", adminPermissionHandler.CreateRole)
+			routes.PUT("/roles/:id", "permission:write", adminPermissionHandler.UpdateRole)
+			routes.DELETE("/roles/:id", "permission:write", adminPermissionHandler.DeleteRole)
+			routes.GET("/roles/:id/menus", "permission:read", adminPermissionHandler.GetRoleMenus)
+			routes.PUT("/roles/:id/menus", "permission:write", adminPermissionHandler.UpdateRoleMenus)
+
+			// 权限管理 - 权限目录
+			routes.GET("/permissions", "permission:read", adminPermissionHandler.GetPermissions)
+			routes.GET("/permissions/role-routes", "permission:read", adminPermissionHandler.GetRoleRoutes)
 
 			// 权限管理 - 菜单管理
-			admin.GET("/menus", adminPermissionHandler.GetMenus)
-			admin.GET("/menus/:id", adminPermissionHandler.GetMenu)
-			admin.POST("/menus", adminPermissionHandler.CreateMenu)
-			admin.PUT("/menus/:id", adminPermissionHandler.UpdateMenu)
-			admin.DELETE("/menus/:id", adminPermissionHandler.DeleteMenu)
+			routes.GET("/menus", "permission:read", adminPermissionHandler.GetMenus)
+			routes.GET("/menus/tree", "permission:read", adminPermissionHandler.GetMenuTree)
+			routes.GET("/menus/:id", "permission:read", adminPermissionHandler.GetMenu)
+			routes.POST("/menus", "permission:write", adminPermissionHandler.CreateMenu)
+			routes.PUT("/menus/:id", "permission:write", adminPermissionHandler.UpdateMenu)
+			routes.DELETE("/menus/:id", "permission:write", adminPermissionHandler.DeleteMenu)
+
+			// 内容审核（按内容类型分别授权）
+			admin.GET("/moderation/reasons", moderationHandler.GetReasons)
+			for _, target := range handlers.ModerationTargets {
+				routes.GET("/moderation/"+target.Name, target.Permission+":read", moderationHandler.Queue(target))
+				routes.POST("/moderation/"+target.Name+"/moderate", target.Permission+":write", moderationHandler.Moderate(target))
+			}
+
+			// 敏感词词库
+			routes.GET("/sensitive-word-categories", "sensitive:read", sensitiveWordHandler.GetCategories)
+			routes.POST("/sensitive-word-categories", "sensitive:write", sensitiveWordHandler.CreateCategory)
+			routes.PUT("/sensitive-word-categories/:id", "sensitive:write", sensitiveWordHandler.UpdateCategory)
+			routes.DELETE("/sensitive-word-categories/:id", "sensitive:write", sensitiveWordHandler.DeleteCategory)
+			routes.GET("/sensitive-words", "sensitive:read", sensitiveWordHandler.GetWords)
+			routes.POST("/sensitive-words", "sensitive:write", sensitiveWordHandler.CreateWords)
+			routes.PUT("/sensitive-words/:id", "sensitive:write", sensitiveWordHandler.UpdateWord)
+			routes.POST("/sensitive-words/delete-batch", "sensitive:write", sensitiveWordHandler.DeleteWords)
+			routes.POST("/sensitive-words/test", "sensitive:read", sensitiveWordHandler.TestText)
+			routes.POST("/sensitive-words/rescan", "sensitive:write", sensitiveWordHandler.Rescan)
+			routes.GET("/sensitive-hits", "sensitive:read", sensitiveWordHandler.GetHits)
+
+			// 回收站（按资源分别授权）
+
+			for _, res := range recyclebin.Resources {
+				routes.GET("/recycle-bin/"+res.Name, res.Permission+":read", recycleBinHandler.List(res))
+				routes.POST("/recycle-bin/"+res.Name+"/restore", res.Permission+":write", recycleBinHandler.Restore(res))
+				routes.POST("/recycle-bin/"+res.Name+"/purge", res.Permission+":write", recycleBinHandler.Purge(res))
+			}
 		}
 	}
 
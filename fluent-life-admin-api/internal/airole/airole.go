@@ -0,0 +1,300 @@
+// Package airole 管理AI实战模拟角色及其提示词版本。
+//
+// 角色保存在 ai_roles 表中，提示词的每次修改都在 ai_role_prompt_versions 中新增一个版本，
+// 角色的 system_prompt 始终是已发布版本的内容。修改提示词前先锁定角色记录，
+// 版本号按角色递增，多个管理员同时编辑不会互相覆盖。
+//
+// ai_simulation_roles 设置保留为角色的发布快照，供仍读取该设置的服务使用，
+// 每次修改角色后由 SyncSetting 重新生成，不应再直接修改。
+package airole
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/settings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxRoles 角色数量上限，与 ai_simulation_roles 设置的校验规则一致
+const MaxRoles = 100
+
+// FallbackVoiceType 没有启用的音色时使用的音色
+const FallbackVoiceType = "zh_female_wanqudashu_moon_bigtts"
+
+// ErrVersionNotFound 提示词版本不存在
+var ErrVersionNotFound = errors.New("prompt version not found")
+
+// SettingRole ai_simulation_roles 设置中的角色格式
+type SettingRole struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Category     string `json:"category,omitempty"`
+	Avatar       string `json:"avatar,omitempty"`
+	OpeningLine  string `json:"opening_line,omitempty"`
+	SystemPrompt string `json:"system_prompt"`
+	VoiceType    string `json:"voice_type"`
+	Enabled      bool   `json:"enabled"`
+}
+
+// DefaultRoles 预置角色，由 Seed 写入
+var DefaultRoles = []SettingRole{
+	{
+		ID:           "interviewer",
+		Name:         "面试官",
+		Description:  "专业的面试官，帮助提升面试技巧",
+		Category:     "职场",
+		OpeningLine:  "你好，欢迎参加今天的面试。先请你简单做个自我介绍吧。",
+		SystemPrompt: "你现在是一名面试官，请根据用户的问题进行提问和追问，并对用户的回答进行评价和指导。你的目标是模拟一场真实的面试，帮助用户提升面试技巧。",
+		VoiceType:    FallbackVoiceType,
+		Enabled:      true,
+	},
+	{
+		ID:           "language_tutor",
+		Name:         "语言导师",
+		Description:  "专业的语言导师，帮助练习口语和纠正语法",
+		Category:     "语言学习",
+		OpeningLine:  "你好！今天想练习哪方面的表达？我们可以从日常对话开始。",
+		SystemPrompt: "你现在是一名语言导师，请帮助用户练习口语，纠正语法错误，并提供词汇和表达建议。你的目标是帮助用户提高语言流利度和准确性。",
+		VoiceType:    FallbackVoiceType,
+		Enabled:      true,
+	},
+	{
+		ID:           "presentation_coach",
+		Name:         "演讲教练",
+		Description:  "专业的演讲教练，帮助准备演讲和提升表达能力",
+		Category:     "职场",
+		OpeningLine:  "你好，这次要准备什么主题的演讲？可以先把开头讲给我听听。",
+		SystemPrompt: "你现在是一名演讲教练，请帮助用户准备演讲，提供演讲稿修改建议，并指导用户如何更好地表达。你的目标是帮助用户提升演讲能力和自信心。",
+		VoiceType:    FallbackVoiceType,
+		Enabled:      true,
+	},
+}
+
+// Lock 在事务中锁定角色记录
+func Lock(tx *gorm.DB, id string) (*models.AIRole, error) {
+	var role models.AIRole
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Create 创建角色，role.SystemPrompt 作为第 1 版并发布
+func Create(tx *gorm.DB, role *models.AIRole, comment string, actor settings.Actor) error {
+	prompt := role.SystemPrompt
+	role.PublishedVersion, role.LatestVersion = 0, 0
+	if err := tx.Create(role).Error; err != nil {
+		return err
+	}
+	_, err := AddVersion(tx, role, prompt, comment, actor, nil, true)
+	return err
+}
+
+// AddVersion 为已锁定的角色新增提示词版本，publish 为 true 时同时发布
+func AddVersion(tx *gorm.DB, role *models.AIRole, prompt, comment string, actor settings.Actor, restoredFrom *int, publish bool) (*models.AIRolePromptVersion, error) {
+	v := &models.AIRolePromptVersion{
+		RoleID:        role.ID,
+		Version:       role.LatestVersion + 1,
+		SystemPrompt:  prompt,
+		Comment:       comment,
+		RestoredFrom:  restoredFrom,
+		CreatedBy:     actor.ID,
+		CreatedByName: actor.Name,
+	}
+	if err := tx.Create(v).Error; err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"latest_version": v.Version}
+	if publish {
+		updates["published_version"], updates["system_prompt"] = v.Version, prompt
+	}
+	if err := tx.Model(role).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	role.LatestVersion = v.Version
+	if publish {
+		role.PublishedVersion, role.SystemPrompt = v.Version, prompt
+		v.Published = true
+	}
+	return v, nil
+}
+
+// FindVersion 查找角色的提示词版本
+func FindVersion(db *gorm.DB, roleID string, version int) (*models.AIRolePromptVersion, error) {
+	var v models.AIRolePromptVersion
+	if err := db.Where("role_id = ? AND version = ?", roleID, version).First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVersionNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Publish 发布已锁定角色的某个版本
+func Publish(tx *gorm.DB, role *models.AIRole, version int) (*models.AIRolePromptVersion, error) {
+	v, err := FindVersion(tx, role.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(role).Updates(map[string]interface{}{
+		"published_version": v.Version,
+		"system_prompt":     v.SystemPrompt,
+	}).Error; err != nil {
+		return nil, err
+	}
+	role.PublishedVersion, role.SystemPrompt = v.Version, v.SystemPrompt
+	v.Published = true
+	return v, nil
+}
+
+// Delete 删除角色及其全部提示词版本
+func Delete(tx *gorm.DB, id string) error {
+	if err := tx.Where("role_id = ?", id).Delete(&models.AIRolePromptVersion{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id = ?", id).Delete(&models.AIRole{}).Error
+}
+
+// DefaultVoiceType 第一个启用的音色，没有时使用 FallbackVoiceType
+func DefaultVoiceType(db *gorm.DB) string {
+	var voiceType models.VoiceType
+	if err := db.Where("enabled = ?", true).Order("created_at ASC").First(&voiceType).Error; err == nil {
+		return voiceType.Type
+	}
+	return FallbackVoiceType
+}
+
+// Seed 写入 DefaultRoles 中尚不存在的角色，已存在的（包括被修改过的）保持不变，可重复执行。
+// 返回新建的角色ID
+func Seed(db *gorm.DB, actor settings.Actor) ([]string, error) {
+	created := make([]string, 0)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var maxOrder int
+		if err := tx.Model(&models.AIRole{}).Select(`COALESCE(MAX("order"), 0)`).Scan(&maxOrder).Error; err != nil {
+			return err
+		}
+		for _, def := range DefaultRoles {
+			role := fromSettingRole(def)
+			var voice models.VoiceType
+			if tx.Where("type = ? AND enabled = ?", role.VoiceType, true).Limit(1).Find(&voice).RowsAffected == 0 {
+				role.VoiceType = DefaultVoiceType(tx)
+			}
+			role.Order = maxOrder + len(created) + 1
+
+			// 并发执行时以主键冲突判断是否已存在
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(role)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			if _, err := AddVersion(tx, role, def.SystemPrompt, "预置角色", actor, nil, true); err != nil {
+				return err
+			}
+			created = append(created, role.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ImportFromSetting 把 ai_simulation_roles 设置中的角色迁移到 ai_roles 表，只在表为空时执行一次。
+// 返回迁移的角色数
+func ImportFromSetting(db *gorm.DB) (int, error) {
+	var imported int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 多个进程同时启动时只有一个执行迁移
+		if err := tx.Exec("LOCK TABLE ai_roles IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.AIRole{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		var setting models.AppSetting
+		if err := tx.Where("key = ?", settings.AISimulationRolesKey).Limit(1).Find(&setting).Error; err != nil {
+			return err
+		}
+		if setting.Value == "" {
+			return nil
+		}
+		var roles []SettingRole
+		if err := json.Unmarshal([]byte(setting.Value), &roles); err != nil {
+			return fmt.Errorf("解析 %s 失败: %w", settings.AISimulationRolesKey, err)
+		}
+
+		seen := make(map[string]bool, len(roles))
+		for _, r := range roles {
+			if r.ID == "" || seen[r.ID] {
+				continue
+			}
+			seen[r.ID] = true
+			role := fromSettingRole(r)
+			role.Order = imported + 1
+			if err := Create(tx, role, "从 ai_simulation_roles 设置迁移", settings.Actor{}); err != nil {
+				return err
+			}
+			imported++
+		}
+		return nil
+	})
+	return imported, err
+}
+
+// SyncSetting 按 ai_roles 表重新生成 ai_simulation_roles 设置
+func SyncSetting(db *gorm.DB, store *settings.Store, actor settings.Actor) error {
+	var roles []models.AIRole
+	if err := db.Order(`"order" ASC, created_at ASC`).Find(&roles).Error; err != nil {
+		return err
+	}
+	snapshot := make([]SettingRole, len(roles))
+	for i, r := range roles {
+		snapshot[i] = SettingRole{
+			ID:           r.ID,
+			Name:         r.Name,
+			Description:  r.Description,
+			Category:     r.Category,
+			Avatar:       r.Avatar,
+			OpeningLine:  r.OpeningLine,
+			SystemPrompt: r.SystemPrompt,
+			VoiceType:    r.VoiceType,
+			Enabled:      r.Enabled,
+		}
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = store.Set(actor, settings.AISimulationRolesKey, data, nil, "由 ai_roles 表同步")
+	return err
+}
+
+func fromSettingRole(r SettingRole) *models.AIRole {
+	return &models.AIRole{
+		ID:           r.ID,
+		Name:         r.Name,
+		Description:  r.Description,
+		Category:     r.Category,
+		Avatar:       r.Avatar,
+		OpeningLine:  r.OpeningLine,
+		SystemPrompt: r.SystemPrompt,
+		VoiceType:    r.VoiceType,
+		Enabled:      r.Enabled,
+	}
+}
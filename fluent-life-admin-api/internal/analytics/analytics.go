@@ -0,0 +1,112 @@
+// Package analytics 按自然日/周/月统计用户、训练和社区数据。
+//
+// 所有分桶都以北京时间（Asia/Shanghai）计算：Go 侧用 Location 解析日期范围，
+// SQL 侧用 date_trunc(unit, col AT TIME ZONE 'Asia/Shanghai') 分桶，两边的桶边界一致。
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeZone 统计使用的时区名称，用于 SQL
+const TimeZone = "Asia/Shanghai"
+
+// Location 统计使用的时区。中国自 1991 年起不再实行夏令时，固定 UTC+8 与 Asia/Shanghai 一致，
+// 且不依赖运行环境的时区数据库
+var Location = time.FixedZone(TimeZone, 8*60*60)
+
+// 分桶粒度
+const (
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+)
+
+// maxBuckets 单次查询最多返回的桶数
+const maxBuckets = 400
+
+// dateLayout 日期参数和桶标签的格式
+const dateLayout = "2006-01-02"
+
+// Range 统计区间 [Start, End)，Start 和 End 都是北京时间的零点
+type Range struct {
+	Interval string
+	Start    time.Time
+	End      time.Time
+}
+
+// ParseRange 解析 start/end 日期（YYYY-MM-DD，均包含在内）和分桶粒度；
+// 为空时默认最近 30 天、按天统计。start 会对齐到所在周（周一）或月的第一天
+func ParseRange(start, end, interval string, now time.Time) (Range, error) {
+	if interval == "" {
+		interval = Day
+	}
+	if interval != Day && interval != Week && interval != Month {
+		return Range{}, errors.New("interval 只能是 day、week 或 month")
+	}
+
+	today := truncate(now.In(Location), Day)
+	r := Range{Interval: interval, End: today.AddDate(0, 0, 1), Start: today.AddDate(0, 0, -29)}
+	if end != "" {
+		t, err := time.ParseInLocation(dateLayout, end, Location)
+		if err != nil {
+			return Range{}, fmt.Errorf("无效的结束日期: %s", end)
+		}
+		r.End = t.AddDate(0, 0, 1)
+	}
+	if start != "" {
+		t, err := time.ParseInLocation(dateLayout, start, Location)
+		if err != nil {
+			return Range{}, fmt.Errorf("无效的开始日期: %s", start)
+		}
+		r.Start = t
+	}
+	r.Start = truncate(r.Start, interval)
+	if !r.Start.Before(r.End) {
+		return Range{}, errors.New("开始日期不能晚于结束日期")
+	}
+	if len(r.Buckets()) > maxBuckets {
+		return Range{}, fmt.Errorf("时间范围过大，最多 %d 个统计周期", maxBuckets)
+	}
+	return r, nil
+}
+
+// Buckets 区间内每个桶的标签（桶起始日期），包括没有数据的桶
+func (r Range) Buckets() []string {
+	var labels []string
+	for t := r.Start; t.Before(r.End); t = next(t, r.Interval) {
+		labels = append(labels, t.Format(dateLayout))
+	}
+	return labels
+}
+
+// BucketExpr 把 timestamptz 列转换为桶标签的 SQL 表达式。interval 必须来自 ParseRange
+func BucketExpr(interval, column string) string {
+	return fmt.Sprintf("to_char(date_trunc('%s', %s AT TIME ZONE '%s'), 'YYYY-MM-DD')", interval, column, TimeZone)
+}
+
+// truncate 对齐到所在天、周（周一）或月的零点
+func truncate(t time.Time, interval string) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, Location)
+	switch interval {
+	case Week:
+		// date_trunc('week') 以周一为一周的开始
+		offset := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -offset)
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, Location)
+	}
+	return t
+}
+
+func next(t time.Time, interval string) time.Time {
+	switch interval {
+	case Week:
+		return t.AddDate(0, 0, 7)
+	case Month:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
@@ -0,0 +1,144 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultRetentionDays 默认统计的留存天数
+var DefaultRetentionDays = []int{1, 7, 30}
+
+// CohortFilter 同期群的筛选条件，空值表示不筛选
+type CohortFilter struct {
+	Gender            string `json:"gender,omitempty"`
+	DifficultyLevel   string `json:"difficulty_level,omitempty"`
+	FirstTrainingType string `json:"first_training_type,omitempty"`
+}
+
+// Cohort 一周内注册的用户及其留存，切片与 CohortMatrix.Days 一一对应
+type Cohort struct {
+	Cohort string `json:"cohort"` // 注册周的周一
+	Users  int64  `json:"users"`
+	// Eligible 第 N 天已经完整过去的用户数，Retained 其中第 N 天有训练的用户数
+	Eligible []int64 `json:"eligible"`
+	Retained []int64 `json:"retained"`
+	// Rates 留存率（百分比），没有可统计的用户时为 null
+	Rates []*float64 `json:"rates"`
+}
+
+// CohortMatrix 按注册周分组的留存矩阵
+type CohortMatrix struct {
+	TimeZone string       `json:"timezone"`
+	Start    string       `json:"start"`
+	End      string       `json:"end"`
+	Days     []int        `json:"days"`
+	Filter   CohortFilter `json:"filter"`
+	Cohorts  []Cohort     `json:"cohorts"`
+}
+
+// Cohorts 统计 r 内注册的普通用户按注册周分组的第 N 天留存。
+// 第 N 天留存指注册后第 N 个自然日（北京时间，注册当天为第 0 天）有训练记录；
+// 第 N 天尚未结束的用户不计入该天的分母
+func Cohorts(db *gorm.DB, r Range, days []int, filter CohortFilter, now time.Time) (*CohortMatrix, error) {
+	args := map[string]interface{}{
+		"start": r.Start,
+		"end":   r.End,
+		"today": now.In(Location).Format(dateLayout),
+	}
+
+	conditions := []string{"u.deleted_at IS NULL", "u.role = 'user'", "u.created_at >= @start", "u.created_at < @end"}
+	if filter.Gender != "" {
+		conditions = append(conditions, "u.gender = @gender")
+		args["gender"] = filter.Gender
+	}
+	if filter.DifficultyLevel != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM user_settings s WHERE s.user_id = u.id AND s.difficulty_level = @difficulty_level)")
+		args["difficulty_level"] = filter.DifficultyLevel
+	}
+	if filter.FirstTrainingType != "" {
+		conditions = append(conditions, "(SELECT t.type FROM training_records t WHERE t.user_id = u.id ORDER BY t.timestamp ASC LIMIT 1) = @first_training_type")
+		args["first_training_type"] = filter.FirstTrainingType
+	}
+
+	dayList := make([]string, len(days))
+	columns := []string{"to_char(c.week, 'YYYY-MM-DD') AS cohort", "COUNT(*) AS users"}
+	for i, d := range days {
+		dayList[i] = fmt.Sprint(d)
+		columns = append(columns,
+			fmt.Sprintf("COUNT(*) FILTER (WHERE c.signup_day + %d < CAST(@today AS date)) AS eligible_%d", d, i),
+			fmt.Sprintf("COUNT(*) FILTER (WHERE c.signup_day + %d < CAST(@today AS date) AND EXISTS (SELECT 1 FROM activity a WHERE a.user_id = c.id AND a.day = %d)) AS retained_%d", d, d, i))
+	}
+
+	query := fmt.Sprintf(`WITH cohort AS (
+			SELECT u.id,
+				date_trunc('week', u.created_at AT TIME ZONE '%[1]s')::date AS week,
+				(u.created_at AT TIME ZONE '%[1]s')::date AS signup_day
+			FROM users u WHERE %[2]s
+		), activity AS (
+			SELECT DISTINCT t.user_id, (t.timestamp AT TIME ZONE '%[1]s')::date - c.signup_day AS day
+			FROM training_records t JOIN cohort c ON c.id = t.user_id
+			WHERE (t.timestamp AT TIME ZONE '%[1]s')::date - c.signup_day IN (%[3]s)
+		)
+		SELECT %[4]s FROM cohort c GROUP BY c.week`,
+		TimeZone, strings.Join(conditions, " AND "), strings.Join(dayList, ", "), strings.Join(columns, ", "))
+
+	var rows []map[string]interface{}
+	if err := db.Raw(query, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byWeek := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		if week, ok := row["cohort"].(string); ok {
+			byWeek[week] = row
+		}
+	}
+
+	// 没有注册用户的周也列出，便于前端按周连续渲染热力图
+	matrix := &CohortMatrix{
+		TimeZone: TimeZone,
+		Start:    r.Start.Format(dateLayout),
+		End:      r.End.AddDate(0, 0, -1).Format(dateLayout),
+		Days:     days,
+		Filter:   filter,
+		Cohorts:  []Cohort{},
+	}
+	for _, week := range r.Buckets() {
+		row := byWeek[week]
+		cohort := Cohort{
+			Cohort:   week,
+			Users:    toInt64(row["users"]),
+			Eligible: make([]int64, len(days)),
+			Retained: make([]int64, len(days)),
+			Rates:    make([]*float64, len(days)),
+		}
+		for i := range days {
+			cohort.Eligible[i] = toInt64(row[fmt.Sprintf("eligible_%d", i)])
+			cohort.Retained[i] = toInt64(row[fmt.Sprintf("retained_%d", i)])
+			if cohort.Eligible[i] > 0 {
+				rate := math.Round(float64(cohort.Retained[i])*1000/float64(cohort.Eligible[i])) / 10
+				cohort.Rates[i] = &rate
+			}
+		}
+		matrix.Cohorts = append(matrix.Cohorts, cohort)
+	}
+	return matrix, nil
+}
+
+// toInt64 map 查询结果中的计数列
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int32:
+		return int64(n)
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
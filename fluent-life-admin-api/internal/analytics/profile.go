@@ -0,0 +1,305 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// goalWindowDays 每日目标达成率的统计天数（含今天）
+const goalWindowDays = 30
+
+// defaultDailyGoalMinutes 用户没有设置记录时的每日目标，与 UserSettings 的默认值一致
+const defaultDailyGoalMinutes = 15
+
+// Profile 单个用户的学习进度汇总
+type Profile struct {
+	User           ProfileUser             `json:"user"`
+	Training       map[string]TrainingStat `json:"training"` // 按训练类型
+	TotalMinutes   float64                 `json:"total_minutes"`
+	TotalSessions  int64                   `json:"total_sessions"`
+	FirstTrainedAt *time.Time              `json:"first_trained_at"`
+	LastTrainedAt  *time.Time              `json:"last_trained_at"`
+	Streak         Streak                  `json:"streak"`
+	DailyGoal      DailyGoal               `json:"daily_goal"`
+	Meditation     []MeditationStage       `json:"meditation"`
+	Achievements   []ProfileAchievement    `json:"achievements"`
+	Exposure       ExposureProgress        `json:"exposure"`
+	Community      Community               `json:"community"`
+	LastActiveAt   *time.Time              `json:"last_active_at"`
+}
+
+// ProfileUser 用户基本信息
+type ProfileUser struct {
+	ID          uuid.UUID  `json:"id"`
+	Username    string     `json:"username"`
+	Status      int        `json:"status"`
+	Role        string     `json:"role"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// TrainingStat 一种训练类型的累计数据
+type TrainingStat struct {
+	Sessions int64   `json:"sessions"`
+	Minutes  float64 `json:"minutes"`
+}
+
+// Streak 连续训练天数（北京时间自然日）。今天还没训练时，截至昨天的连续天数仍算作当前连续
+type Streak struct {
+	Current     int    `json:"current"`
+	Longest     int    `json:"longest"`
+	TrainedDays int    `json:"trained_days"`
+	LastDay     string `json:"last_day,omitempty"`
+}
+
+// DailyGoal 最近 WindowDays 天（含今天）每日训练分钟数与目标的对比
+type DailyGoal struct {
+	GoalMinutes  int     `json:"goal_minutes"`
+	WindowDays   int     `json:"window_days"`
+	DaysTrained  int     `json:"days_trained"`
+	DaysMet      int     `json:"days_met"`
+	Rate         float64 `json:"rate"` // 达标天数占统计天数的百分比
+	TodayMinutes float64 `json:"today_minutes"`
+	// Days 每天的训练分钟数，从最早一天到今天
+	Days []DailyMinutes `json:"days"`
+}
+
+// DailyMinutes 一天的训练分钟数
+type DailyMinutes struct {
+	Day     string  `json:"day"`
+	Minutes float64 `json:"minutes"`
+	Met     bool    `json:"met"`
+}
+
+// MeditationStage 冥想阶段进度
+type MeditationStage struct {
+	Stage         int       `json:"stage"`
+	CompletedDays int       `json:"completed_days"`
+	Unlocked      bool      `json:"unlocked"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ProfileAchievement 已解锁的成就
+type ProfileAchievement struct {
+	Type       string    `json:"type"`
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
+
+// ExposureProgress 脱敏练习场景的练习情况。场景完成以训练记录 data.module_id 为准
+type ExposureProgress struct {
+	ModulesPracticed int              `json:"modules_practiced"`
+	ModulesTotal     int64            `json:"modules_total"` // 当前启用的场景数
+	Modules          []ExposureModule `json:"modules"`
+}
+
+// ExposureModule 用户练习过的一个场景
+type ExposureModule struct {
+	ModuleID      string    `json:"module_id"`
+	Title         string    `json:"title"`
+	Sessions      int64     `json:"sessions"`
+	Minutes       float64   `json:"minutes"`
+	LastPracticed time.Time `json:"last_practiced"`
+}
+
+// Community 社区和 AI 对话活动
+type Community struct {
+	Posts            int64      `json:"posts"`
+	Comments         int64      `json:"comments"`
+	LikesReceived    int64      `json:"likes_received"`
+	LikesGiven       int64      `json:"likes_given"`
+	Followers        int64      `json:"followers"`
+	Following        int64      `json:"following"`
+	AIMessages       int64      `json:"ai_messages"`
+	LastPostAt       *time.Time `json:"last_post_at"`
+	LastCommentAt    *time.Time `json:"last_comment_at"`
+	LastAIActivityAt *time.Time `json:"last_ai_activity_at"`
+}
+
+// ErrUserNotFound 用户不存在
+var ErrUserNotFound = errors.New("user not found")
+
+// UserProfile 汇总用户的学习进度，每个部分一条查询
+func UserProfile(db *gorm.DB, userID uuid.UUID, now time.Time) (*Profile, error) {
+	var user models.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	p := &Profile{
+		User: ProfileUser{
+			ID: user.ID, Username: user.Username, Status: user.Status, Role: user.Role,
+			CreatedAt: user.CreatedAt, LastLoginAt: user.LastLoginAt,
+		},
+		Training:     map[string]TrainingStat{},
+		Meditation:   []MeditationStage{},
+		Achievements: []ProfileAchievement{},
+		Exposure:     ExposureProgress{Modules: []ExposureModule{}},
+	}
+
+	var byType []struct {
+		Type     string
+		Sessions int64
+		Seconds  float64
+		First    time.Time
+		Last     time.Time
+	}
+	if err := db.Model(&models.TrainingRecord{}).
+		Select("type, COUNT(*) AS sessions, COALESCE(SUM(duration), 0) AS seconds, MIN(timestamp) AS first, MAX(timestamp) AS last").
+		Where("user_id = ?", userID).Group("type").Scan(&byType).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range byType {
+		p.Training[row.Type] = TrainingStat{Sessions: row.Sessions, Minutes: roundMinutes(row.Seconds)}
+		p.TotalSessions += row.Sessions
+		p.TotalMinutes += row.Seconds
+		first, last := row.First, row.Last
+		if p.FirstTrainedAt == nil || first.Before(*p.FirstTrainedAt) {
+			p.FirstTrainedAt = &first
+		}
+		if p.LastTrainedAt == nil || last.After(*p.LastTrainedAt) {
+			p.LastTrainedAt = &last
+		}
+	}
+	p.TotalMinutes = roundMinutes(p.TotalMinutes)
+
+	// 每天的训练时长同时用于连续天数和每日目标
+	var daily []struct {
+		Day     string
+		Seconds float64
+	}
+	if err := db.Model(&models.TrainingRecord{}).
+		Select(fmt.Sprintf("%s AS day, COALESCE(SUM(duration), 0) AS seconds", BucketExpr(Day, "timestamp"))).
+		Where("user_id = ?", userID).Group("1").Order("1").Scan(&daily).Error; err != nil {
+		return nil, err
+	}
+	days := make([]string, len(daily))
+	minutesByDay := make(map[string]float64, len(daily))
+	for i, row := range daily {
+		days[i] = row.Day
+		minutesByDay[row.Day] = roundMinutes(row.Seconds)
+	}
+	p.Streak = streaks(days, now)
+
+	goal := defaultDailyGoalMinutes
+	var settings models.UserSettings
+	if err := db.Where("user_id = ?", userID).Order("updated_at DESC").First(&settings).Error; err == nil && settings.DailyGoalMinutes > 0 {
+		goal = settings.DailyGoalMinutes
+	}
+	p.DailyGoal = dailyGoal(minutesByDay, goal, now)
+
+	if err := db.Model(&models.MeditationProgress{}).Select("stage, completed_days, unlocked, updated_at").
+		Where("user_id = ?", userID).Order("stage ASC").Scan(&p.Meditation).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Achievement{}).Select("achievement_type AS type, unlocked_at").
+		Where("user_id = ?", userID).Order("unlocked_at ASC").Scan(&p.Achievements).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Table("training_records t").
+		Select(`t.data->>'module_id' AS module_id, COALESCE(MAX(m.title), '') AS title, COUNT(*) AS sessions,
+			COALESCE(SUM(t.duration), 0) / 60.0 AS minutes, MAX(t.timestamp) AS last_practiced`).
+		Joins("LEFT JOIN exposure_modules m ON m.id = t.data->>'module_id'").
+		Where("t.user_id = ? AND t.type = ? AND COALESCE(t.data->>'module_id', '') <> ''", userID, "exposure").
+		Group("t.data->>'module_id'").Order("last_practiced DESC").
+		Scan(&p.Exposure.Modules).Error; err != nil {
+		return nil, err
+	}
+	for i := range p.Exposure.Modules {
+		p.Exposure.Modules[i].Minutes = math.Round(p.Exposure.Modules[i].Minutes*10) / 10
+	}
+	p.Exposure.ModulesPracticed = len(p.Exposure.Modules)
+	db.Model(&models.ExposureModule{}).Where("is_active = ?", true).Count(&p.Exposure.ModulesTotal)
+
+	if err := db.Raw(`SELECT
+			(SELECT COUNT(*) FROM posts WHERE user_id = @id AND deleted_at IS NULL) AS posts,
+			(SELECT COUNT(*) FROM comments WHERE user_id = @id AND deleted_at IS NULL) AS comments,
+			(SELECT COALESCE(SUM(likes_count), 0) FROM posts WHERE user_id = @id AND deleted_at IS NULL) AS likes_received,
+			(SELECT COUNT(*) FROM post_likes WHERE user_id = @id) AS likes_given,
+			(SELECT COUNT(*) FROM follows WHERE followee_id = @id) AS followers,
+			(SELECT COUNT(*) FROM follows WHERE follower_id = @id) AS following,
+			(SELECT COUNT(*) FROM ai_conversations, jsonb_array_elements(COALESCE(messages, '[]'::jsonb)) m
+				WHERE user_id = @id AND m->>'role' = 'user') AS ai_messages,
+			(SELECT MAX(created_at) FROM posts WHERE user_id = @id AND deleted_at IS NULL) AS last_post_at,
+			(SELECT MAX(created_at) FROM comments WHERE user_id = @id AND deleted_at IS NULL) AS last_comment_at,
+			(SELECT MAX(updated_at) FROM ai_conversations WHERE user_id = @id) AS last_ai_activity_at`,
+		map[string]interface{}{"id": userID}).Scan(&p.Community).Error; err != nil {
+		return nil, err
+	}
+
+	for _, t := range []*time.Time{p.LastTrainedAt, user.LastLoginAt, p.Community.LastPostAt, p.Community.LastCommentAt, p.Community.LastAIActivityAt} {
+		if t != nil && (p.LastActiveAt == nil || t.After(*p.LastActiveAt)) {
+			p.LastActiveAt = t
+		}
+	}
+	return p, nil
+}
+
+// streaks 根据升序排列的训练日期（YYYY-MM-DD）计算连续天数
+func streaks(days []string, now time.Time) Streak {
+	s := Streak{TrainedDays: len(days)}
+	if len(days) == 0 {
+		return s
+	}
+	s.LastDay = days[len(days)-1]
+
+	run := 0
+	var prev time.Time
+	for _, d := range days {
+		t, err := time.ParseInLocation(dateLayout, d, Location)
+		if err != nil {
+			continue
+		}
+		if run > 0 && t.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+		prev = t
+	}
+
+	// 最后一段连续以今天或昨天结束时才算当前连续
+	today := truncate(now.In(Location), Day)
+	if prev.Equal(today) || prev.Equal(today.AddDate(0, 0, -1)) {
+		s.Current = run
+	}
+	return s
+}
+
+// dailyGoal 统计最近 goalWindowDays 天的目标达成情况
+func dailyGoal(minutesByDay map[string]float64, goal int, now time.Time) DailyGoal {
+	g := DailyGoal{GoalMinutes: goal, WindowDays: goalWindowDays, Days: make([]DailyMinutes, 0, goalWindowDays)}
+	today := truncate(now.In(Location), Day)
+	for i := goalWindowDays - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format(dateLayout)
+		minutes := minutesByDay[day]
+		met := minutes >= float64(goal)
+		if minutes > 0 {
+			g.DaysTrained++
+		}
+		if met {
+			g.DaysMet++
+		}
+		g.Days = append(g.Days, DailyMinutes{Day: day, Minutes: minutes, Met: met})
+	}
+	g.TodayMinutes = minutesByDay[today.Format(dateLayout)]
+	g.Rate = math.Round(float64(g.DaysMet)*1000/float64(goalWindowDays)) / 10
+	return g
+}
+
+// roundMinutes 秒转换为分钟，保留一位小数
+func roundMinutes(seconds float64) float64 {
+	return math.Round(seconds/6) / 10
+}
@@ -0,0 +1,203 @@
+package analytics
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// rollupSource daily_stats 中来自同一张表的列
+type rollupSource struct {
+	table string
+	// softDelete 表有 deleted_at 列，已删除的记录不计入
+	softDelete bool
+	// columns daily_stats 列名及其聚合表达式，依次对应
+	columns [][2]string
+}
+
+var rollupSources = []rollupSource{
+	{table: "users", softDelete: true, columns: [][2]string{{"new_users", "COUNT(*)"}}},
+	{table: "training_records", columns: [][2]string{
+		{"training_records", "COUNT(*)"},
+		{"meditation_count", "COUNT(*) FILTER (WHERE type = 'meditation')"},
+		{"airflow_count", "COUNT(*) FILTER (WHERE type = 'airflow')"},
+		{"exposure_count", "COUNT(*) FILTER (WHERE type = 'exposure')"},
+		{"practice_count", "COUNT(*) FILTER (WHERE type = 'practice')"},
+		{"training_seconds", "COALESCE(SUM(duration), 0)"},
+	}},
+	{table: "posts", softDelete: true, columns: [][2]string{{"posts", "COUNT(*)"}}},
+	{table: "comments", softDelete: true, columns: [][2]string{{"comments", "COUNT(*)"}}},
+	{table: "post_likes", columns: [][2]string{{"likes", "COUNT(*)"}}},
+	{table: "post_collections", columns: [][2]string{{"collections", "COUNT(*)"}}},
+	{table: "follows", columns: [][2]string{{"follows", "COUNT(*)"}}},
+	{table: "practice_rooms", softDelete: true, columns: [][2]string{{"rooms", "COUNT(*)"}}},
+	{table: "ai_conversations", columns: [][2]string{{"ai_conversations", "COUNT(*)"}}},
+}
+
+// rollupSQL 一条语句计算 @from~@to 每天的数据并写入 daily_stats，没有数据的日期写入 0
+var rollupSQL = buildRollupSQL()
+
+func buildRollupSQL() string {
+	var columns, values, joins, updates []string
+	for i, src := range rollupSources {
+		alias := fmt.Sprintf("s%d", i)
+		aggregates := make([]string, 0, len(src.columns))
+		for _, col := range src.columns {
+			aggregates = append(aggregates, fmt.Sprintf("%s AS %s", col[1], col[0]))
+			columns = append(columns, col[0])
+			values = append(values, fmt.Sprintf("COALESCE(%s.%s, 0)", alias, col[0]))
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", col[0], col[0]))
+		}
+		where := "created_at >= @start AND created_at < @end"
+		if src.softDelete {
+			where += " AND deleted_at IS NULL"
+		}
+		joins = append(joins, fmt.Sprintf(
+			"LEFT JOIN (SELECT CAST(created_at AT TIME ZONE '%s' AS date) AS day, %s FROM %s WHERE %s GROUP BY 1) %s ON %s.day = d.day",
+			TimeZone, strings.Join(aggregates, ", "), src.table, where, alias, alias))
+	}
+
+	return fmt.Sprintf(`INSERT INTO daily_stats (day, %s, computed_at)
+SELECT d.day, %s, NOW()
+FROM (SELECT CAST(g AS date) AS day FROM generate_series(CAST(@from AS date), CAST(@to AS date), interval '1 day') g) d
+%s
+ON CONFLICT (day) DO UPDATE SET %s, computed_at = EXCLUDED.computed_at`,
+		strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(joins, "\n"), strings.Join(updates, ", "))
+}
+
+// Rollup 重新计算 from~to（北京时间自然日，含两端）的每日数据
+func Rollup(db *gorm.DB, from, to time.Time) error {
+	from, to = truncate(from.In(Location), Day), truncate(to.In(Location), Day)
+	if to.Before(from) {
+		return fmt.Errorf("rollup end %s is before start %s", to.Format(dateLayout), from.Format(dateLayout))
+	}
+	return db.Exec(rollupSQL, map[string]interface{}{
+		"from":  from.Format(dateLayout),
+		"to":    to.Format(dateLayout),
+		"start": from,
+		"end":   to.AddDate(0, 0, 1),
+	}).Error
+}
+
+// FirstDay 最早一条用户或训练记录所在的日期，没有数据时返回 now 所在的日期
+func FirstDay(db *gorm.DB, now time.Time) (time.Time, error) {
+	var first *time.Time
+	if err := db.Raw(`SELECT LEAST((SELECT MIN(created_at) FROM users), (SELECT MIN(created_at) FROM training_records))`).
+		Scan(&first).Error; err != nil {
+		return time.Time{}, err
+	}
+	if first == nil {
+		return truncate(now.In(Location), Day), nil
+	}
+	return truncate(first.In(Location), Day), nil
+}
+
+// CatchUp 补算从最近一次汇总的日期到今天的数据；汇总表为空时从最早的数据开始
+func CatchUp(db *gorm.DB, now time.Time) error {
+	var last *time.Time
+	if err := db.Model(&models.DailyStat{}).Select("MAX(day)").Scan(&last).Error; err != nil {
+		return err
+	}
+	var from time.Time
+	if last == nil {
+		var err error
+		if from, err = FirstDay(db, now); err != nil {
+			return err
+		}
+	} else {
+		// date 列读出的是 UTC 零点，按日期重新解释为北京时间
+		from = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, Location)
+	}
+	return Rollup(db, from, now)
+}
+
+// SumDailyStats 汇总 since 所在日期（含）以来的每日数据，since 为零值时汇总全部日期
+func SumDailyStats(db *gorm.DB, since time.Time) (models.DailyStat, error) {
+	selects := []string{"COALESCE(MAX(computed_at), NOW()) AS computed_at"}
+	for _, src := range rollupSources {
+		for _, col := range src.columns {
+			selects = append(selects, fmt.Sprintf("COALESCE(SUM(%s), 0) AS %s", col[0], col[0]))
+		}
+	}
+	query := db.Model(&models.DailyStat{}).Select(strings.Join(selects, ", "))
+	if !since.IsZero() {
+		query = query.Where("day >= CAST(? AS date)", truncate(since.In(Location), Day).Format(dateLayout))
+	}
+	var sum models.DailyStat
+	err := query.Scan(&sum).Error
+	return sum, err
+}
+
+// Earliest model 中 ids 对应记录（包括已软删除的）最早的创建时间，没有记录时返回零值。
+// 彻底删除前调用，删除后记录已不存在
+func Earliest(db *gorm.DB, model interface{}, ids []string) (time.Time, error) {
+	var earliest *time.Time
+	if len(ids) == 0 {
+		return time.Time{}, nil
+	}
+	if err := db.Unscoped().Model(model).Where("id IN ?", ids).Select("MIN(created_at)").Scan(&earliest).Error; err != nil {
+		return time.Time{}, err
+	}
+	if earliest == nil {
+		return time.Time{}, nil
+	}
+	return *earliest, nil
+}
+
+// RollupSince 重新计算 since 所在日期到今天的数据，since 为零值时不做任何事。
+// 删除、恢复或彻底删除较早的记录后调用，使累计数据与源表一致；随父记录级联处理的子记录
+// （用户的内容、帖子下的评论和点赞）都晚于父记录创建，因此从父记录的创建日期一直算到今天
+func RollupSince(db *gorm.DB, since time.Time) error {
+	if since.IsZero() {
+		return nil
+	}
+	return Rollup(db, since, time.Now())
+}
+
+// Reroll 执行 change（删除、恢复或彻底删除 model 中 ids 对应的记录），然后重新计算受影响日期的数据。
+// 在事务中调用时汇总随事务一起提交
+func Reroll(db *gorm.DB, model interface{}, ids []string, change func() error) error {
+	since, err := Earliest(db, model, ids)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	return RollupSince(db, since)
+}
+
+// StartAggregator 启动时补算缺失的日期，之后定期重新计算本月、本周和昨天以来的数据，
+// 保证仪表盘上今天、本周、本月的新增数据包含期间的删除和恢复；
+// 昨天一并重算，保证跨零点前后写入的记录都能计入
+func StartAggregator(db *gorm.DB, interval time.Duration) {
+	go func() {
+		if err := CatchUp(db, time.Now()); err != nil {
+			log.Printf("每日统计补算失败: %v", err)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now()
+			from := now.AddDate(0, 0, -1)
+			for _, interval := range []string{Week, Month} {
+				if start := StartOf(now, interval); start.Before(from) {
+					from = start
+				}
+			}
+			if err := Rollup(db, from, now); err != nil {
+				log.Printf("每日统计汇总失败: %v", err)
+			}
+		}
+	}()
+}
+
+// StartOf 北京时间 t 所在天、周（周一开始）或月的零点
+func StartOf(t time.Time, interval string) time.Time {
+	return truncate(t.In(Location), interval)
+}
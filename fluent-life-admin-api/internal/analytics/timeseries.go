@@ -0,0 +1,151 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TimeSeries 按桶统计的指标，每个切片与 Buckets 一一对应
+type TimeSeries struct {
+	Interval string   `json:"interval"`
+	TimeZone string   `json:"timezone"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"` // 包含在内的最后一天
+	Buckets  []string `json:"buckets"`
+
+	NewUsers    []int64 `json:"new_users"`
+	ActiveUsers []int64 `json:"active_users"` // 有训练、发帖、评论或登录的去重用户数
+	Posts       []int64 `json:"posts"`
+	Comments    []int64 `json:"comments"`
+	// AIConversations 新开始的 AI 对话（每个用户一个对话），AIMessages 用户发送的 AI 对话消息
+	AIConversations  []int64 `json:"ai_conversations"`
+	AIMessages       []int64 `json:"ai_messages"`
+	TrainingSessions []int64 `json:"training_sessions"`
+	// TrainingMinutes 按训练类型的训练分钟数，total 为合计
+	TrainingMinutes map[string][]float64 `json:"training_minutes"`
+}
+
+// bucketValue 分组查询的一行
+type bucketValue struct {
+	Bucket string
+	Value  float64
+}
+
+// activitySQL 用户活跃事件（训练、发帖、评论、登录）的 user_id 和发生时间。
+// 已删除的帖子和评论同样计入，它们在发生时是真实的活跃；users.last_login_at 只保留最近一次登录，
+// 因此较早区间内的登录活跃只能通过其他行为体现
+const activitySQL = `
+	SELECT user_id, timestamp AS at FROM training_records WHERE timestamp >= @start AND timestamp < @end
+	UNION ALL SELECT user_id, created_at FROM posts WHERE created_at >= @start AND created_at < @end
+	UNION ALL SELECT user_id, created_at FROM comments WHERE created_at >= @start AND created_at < @end
+	UNION ALL SELECT id, last_login_at FROM users WHERE last_login_at >= @start AND last_login_at < @end`
+
+// ActiveUsers 区间 [start, end) 内的活跃用户数
+func ActiveUsers(db *gorm.DB, start, end time.Time) (int64, error) {
+	var count int64
+	err := db.Raw("SELECT COUNT(DISTINCT user_id) FROM ("+activitySQL+") a",
+		map[string]interface{}{"start": start, "end": end}).Scan(&count).Error
+	return count, err
+}
+
+// Compute 在一次请求内用少量分组查询计算全部指标
+func Compute(db *gorm.DB, r Range) (*TimeSeries, error) {
+	buckets := r.Buckets()
+	index := make(map[string]int, len(buckets))
+	for i, b := range buckets {
+		index[b] = i
+	}
+	args := map[string]interface{}{"start": r.Start, "end": r.End}
+
+	ts := &TimeSeries{
+		Interval:        r.Interval,
+		TimeZone:        TimeZone,
+		Start:           r.Start.Format(dateLayout),
+		End:             r.End.AddDate(0, 0, -1).Format(dateLayout),
+		Buckets:         buckets,
+		TrainingMinutes: map[string][]float64{"total": make([]float64, len(buckets))},
+	}
+
+	// counts 把分组结果填入与 buckets 对齐的切片
+	counts := func(query string) ([]int64, error) {
+		var rows []bucketValue
+		if err := db.Raw(query, args).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		values := make([]int64, len(buckets))
+		for _, row := range rows {
+			if i, ok := index[row.Bucket]; ok {
+				values[i] = int64(row.Value)
+			}
+		}
+		return values, nil
+	}
+	countCreated := func(table, column string) ([]int64, error) {
+		return counts(fmt.Sprintf("SELECT %s AS bucket, COUNT(*) AS value FROM %s WHERE %s >= @start AND %s < @end GROUP BY 1",
+			BucketExpr(r.Interval, column), table, column, column))
+	}
+
+	var err error
+	if ts.NewUsers, err = countCreated("users", "created_at"); err != nil {
+		return nil, err
+	}
+	if ts.Posts, err = countCreated("posts", "created_at"); err != nil {
+		return nil, err
+	}
+	if ts.Comments, err = countCreated("comments", "created_at"); err != nil {
+		return nil, err
+	}
+	if ts.AIConversations, err = countCreated("ai_conversations", "created_at"); err != nil {
+		return nil, err
+	}
+	if ts.ActiveUsers, err = counts(fmt.Sprintf("SELECT %s AS bucket, COUNT(DISTINCT user_id) AS value FROM (%s) a GROUP BY 1",
+		BucketExpr(r.Interval, "at"), activitySQL)); err != nil {
+		return nil, err
+	}
+
+	// 消息时间保存在 JSON 中；CASE 保证格式不正确的时间不会参与类型转换。
+	// 有新消息的对话 updated_at 一定不早于消息时间，先按 updated_at 缩小扫描范围
+	if ts.AIMessages, err = counts(fmt.Sprintf(`SELECT %s AS bucket, COUNT(*) AS value FROM (
+			SELECT CASE WHEN m->>'timestamp' ~ '^\d{4}-\d{2}-\d{2}T' THEN (m->>'timestamp')::timestamptz END AS at
+			FROM ai_conversations, jsonb_array_elements(COALESCE(messages, '[]'::jsonb)) m
+			WHERE updated_at >= @start AND m->>'role' = 'user'
+		) msg WHERE at >= @start AND at < @end GROUP BY 1`, BucketExpr(r.Interval, "at"))); err != nil {
+		return nil, err
+	}
+
+	var training []struct {
+		Bucket   string
+		Type     string
+		Sessions int64
+		Seconds  float64
+	}
+	if err := db.Raw(fmt.Sprintf(`SELECT %s AS bucket, type, COUNT(*) AS sessions, COALESCE(SUM(duration), 0) AS seconds
+		FROM training_records WHERE timestamp >= @start AND timestamp < @end GROUP BY 1, 2`,
+		BucketExpr(r.Interval, "timestamp")), args).Scan(&training).Error; err != nil {
+		return nil, err
+	}
+	ts.TrainingSessions = make([]int64, len(buckets))
+	for _, row := range training {
+		i, ok := index[row.Bucket]
+		if !ok {
+			continue
+		}
+		if ts.TrainingMinutes[row.Type] == nil {
+			ts.TrainingMinutes[row.Type] = make([]float64, len(buckets))
+		}
+		minutes := row.Seconds / 60
+		ts.TrainingMinutes[row.Type][i] += minutes
+		ts.TrainingMinutes["total"][i] += minutes
+		ts.TrainingSessions[i] += row.Sessions
+	}
+	for _, values := range ts.TrainingMinutes {
+		for i := range values {
+			values[i] = math.Round(values[i]*10) / 10
+		}
+	}
+
+	return ts, nil
+}
@@ -0,0 +1,214 @@
+// Package audit 提供操作审计的上下文注解和快照对比工具。
+// 审计日志统一由 middleware.Audit 在请求结束时写入，处理函数只需通过本包补充
+// 操作名称、资源、前后快照等信息。
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const contextKey = "auditEntry"
+
+// Redacted 敏感字段在快照中的占位值
+const Redacted = "[REDACTED]"
+
+// sensitiveFields 不允许以明文写入审计日志的字段
+var sensitiveFields = map[string]bool{
+	"password_hash":      true,
+	"totp_secret":        true,
+	"totp_last_step":     true,
+	"refresh_token_hash": true,
+	"code_hash":          true,
+}
+
+// Entry 当前请求的审计信息
+type Entry struct {
+	Action     string
+	Resource   string
+	ResourceID string
+	Details    string
+	Status     string // "Success", "Failure"；为空时由响应结果推断
+	Before     map[string]interface{}
+	After      map[string]interface{}
+	// beforeSet/afterSet 标记处理函数已显式提供快照，中间件不再自动加载
+	beforeSet bool
+	afterSet  bool
+}
+
+// BeforeSet 处理函数是否已提供变更前快照
+func (e *Entry) BeforeSet() bool { return e.beforeSet }
+
+// AfterSet 处理函数是否已提供变更后快照
+func (e *Entry) AfterSet() bool { return e.afterSet }
+
+// Begin 为请求创建审计条目，由中间件调用
+func Begin(c *gin.Context) *Entry {
+	entry := &Entry{}
+	c.Set(contextKey, entry)
+	return entry
+}
+
+// FromContext 获取当前请求的审计条目；未启用审计的路由返回 nil
+func FromContext(c *gin.Context) *Entry {
+	v, ok := c.Get(contextKey)
+	if !ok {
+		return nil
+	}
+	entry, _ := v.(*Entry)
+	return entry
+}
+
+// Annotate 设置操作名称、资源、描述和结果，空字符串的参数保持原值
+func Annotate(c *gin.Context, action, resource, resourceID, details, status string) {
+	entry := FromContext(c)
+	if entry == nil {
+		return
+	}
+	if action != "" {
+		entry.Action = action
+	}
+	if resource != "" {
+		entry.Resource = resource
+	}
+	if resourceID != "" {
+		entry.ResourceID = resourceID
+	}
+	if details != "" {
+		entry.Details = details
+	}
+	if status != "" {
+		entry.Status = status
+	}
+}
+
+// SetBefore 记录变更前的实体快照
+func SetBefore(c *gin.Context, v interface{}) {
+	if entry := FromContext(c); entry != nil {
+		entry.Before = ToMap(v)
+		entry.beforeSet = true
+	}
+}
+
+// SetAfter 记录变更后的实体快照
+func SetAfter(c *gin.Context, v interface{}) {
+	if entry := FromContext(c); entry != nil {
+		entry.After = ToMap(v)
+		entry.afterSet = true
+	}
+}
+
+// DiscardSnapshots 不记录本次请求的实体快照，用于擦除个人数据等不应在审计日志中留存内容的操作
+func DiscardSnapshots(c *gin.Context) {
+	if entry := FromContext(c); entry != nil {
+		entry.Before, entry.After = nil, nil
+		entry.beforeSet, entry.afterSet = true, true
+	}
+}
+
+// Snapshot 按主键从数据库读取一行作为快照，记录不存在时返回 nil
+func Snapshot(db *gorm.DB, model interface{}, column string, key interface{}) map[string]interface{} {
+	row := map[string]interface{}{}
+	if err := db.Model(model).Where(column+" = ?", key).Take(&row).Error; err != nil {
+		return nil
+	}
+	return normalize(row)
+}
+
+// ToMap 把结构体或 map 转换为以 JSON 字段名为键的 map
+func ToMap(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return normalize(m)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return map[string]interface{}{"value": v}
+	}
+	return m
+}
+
+// Diff 对比前后快照，返回 {字段: {"before": 旧值, "after": 新值}}；敏感字段只标记变化不记录值
+func Diff(before, after map[string]interface{}) map[string]interface{} {
+	diff := map[string]interface{}{}
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	for k := range keys {
+		b, bok := before[k]
+		a, aok := after[k]
+		if bok == aok && reflect.DeepEqual(b, a) {
+			continue
+		}
+		if sensitiveFields[k] {
+			b, a = Redacted, Redacted
+		}
+		diff[k] = map[string]interface{}{"before": b, "after": a}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// Redact 返回去除敏感字段值的快照副本
+func Redact(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if sensitiveFields[k] {
+			v = Redacted
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// normalize 把数据库原始值（[]byte、JSON 字符串、时间等）统一为 JSON 兼容的值，便于比较和存储
+func normalize(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		switch val := v.(type) {
+		case []byte:
+			m[k] = decodeJSONString(string(val))
+		case string:
+			m[k] = decodeJSONString(val)
+		}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return m
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return m
+	}
+	return out
+}
+
+// decodeJSONString jsonb 列可能以字符串形式返回，尽量还原为结构化值
+func decodeJSONString(s string) interface{} {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var v interface{}
+		if err := json.Unmarshal([]byte(trimmed), &v); err == nil {
+			return v
+		}
+	}
+	return s
+}
@@ -22,6 +22,34 @@ type Config struct {
 		Name     string `mapstructure:"DB_NAME"`
 		SSLMode  string `mapstructure:"DB_SSLMODE"`
 	} `mapstructure:",squash"`
+
+	// RecycleBinRetentionDays 回收站保留天数，超过后由 cmd/purge-deleted 彻底删除
+	RecycleBinRetentionDays int `mapstructure:"RECYCLE_BIN_RETENTION_DAYS"`
+
+	// SensitiveScanIntervalSeconds 后台敏感词扫描的间隔秒数，0 表示不启动后台扫描
+	SensitiveScanIntervalSeconds int `mapstructure:"SENSITIVE_SCAN_INTERVAL_SECONDS"`
+
+	// SanctionSweepIntervalSeconds 处罚到期检查的间隔秒数，0 表示不启动后台检查
+	SanctionSweepIntervalSeconds int `mapstructure:"SANCTION_SWEEP_INTERVAL_SECONDS"`
+
+	// StatsRollupIntervalSeconds 每日统计汇总（重新计算昨天和今天）的间隔秒数，0 表示不启动后台汇总
+	StatsRollupIntervalSeconds int `mapstructure:"STATS_ROLLUP_INTERVAL_SECONDS"`
+
+	// JobWorkers 本进程的后台任务工作协程数，0 表示只提交任务、由其他进程执行
+	JobWorkers int `mapstructure:"JOB_WORKERS"`
+	// JobPollIntervalSeconds 工作协程空闲时检查队列的间隔秒数
+	JobPollIntervalSeconds int `mapstructure:"JOB_POLL_INTERVAL_SECONDS"`
+	// JobRetentionDays 已结束的任务记录（及导出文件）保留天数
+	JobRetentionDays int `mapstructure:"JOB_RETENTION_DAYS"`
+	// JobFilesDir 导出任务生成文件的目录，多个进程共享队列时需指向同一目录
+	JobFilesDir string `mapstructure:"JOB_FILES_DIR"`
+
+	// SettingsCacheSeconds 应用设置的进程内缓存秒数，其他进程的修改最多延迟这么久生效
+	SettingsCacheSeconds int `mapstructure:"SETTINGS_CACHE_SECONDS"`
+
+	// ServiceToken 主站后端等内部服务调用服务接口（如功能开关求值）时在 X-Service-Token 请求头中携带的凭证，
+	// 为空时服务接口不可用
+	ServiceToken string `mapstructure:"SERVICE_TOKEN"`
 }
 
 func Load() (*Config, error) {
@@ -58,6 +86,16 @@ func setDefaults() {
 	viper.SetDefault("DB_PASSWORD", "postgres")
 	viper.SetDefault("DB_NAME", "fluent_life")
 	viper.SetDefault("DB_SSLMODE", "disable")
+	viper.SetDefault("RECYCLE_BIN_RETENTION_DAYS", 30)
+	viper.SetDefault("SENSITIVE_SCAN_INTERVAL_SECONDS", 30)
+	viper.SetDefault("SANCTION_SWEEP_INTERVAL_SECONDS", 60)
+	viper.SetDefault("STATS_ROLLUP_INTERVAL_SECONDS", 300)
+	viper.SetDefault("JOB_WORKERS", 2)
+	viper.SetDefault("JOB_POLL_INTERVAL_SECONDS", 5)
+	viper.SetDefault("JOB_RETENTION_DAYS", 7)
+	viper.SetDefault("JOB_FILES_DIR", "./data/jobs")
+	viper.SetDefault("SETTINGS_CACHE_SECONDS", 30)
+	viper.SetDefault("SERVICE_TOKEN", "")
 }
 
 func overrideFromEnv(cfg *Config) {
@@ -0,0 +1,242 @@
+// Package export 把后台列表以 CSV 或 XLSX 流式写出。
+//
+// 数据按批从数据库游标读取，每批写完即刷新到响应，内存占用与总行数无关。
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 导出格式
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// batchSize 每批读取的行数
+const batchSize = 500
+
+// timeLayout 导出时间的格式（北京时间）
+const timeLayout = "2006-01-02 15:04:05"
+
+var location = time.FixedZone("Asia/Shanghai", 8*60*60)
+
+// Column 导出的一列
+type Column[T any] struct {
+	Header string
+	// Number 数值列，XLSX 中写为数字单元格
+	Number bool
+	Value  func(row *T) string
+}
+
+// Table 一个列表的导出定义
+type Table[T any] struct {
+	// Name 文件名前缀和工作表名称
+	Name    string
+	Columns []Column[T]
+	// Prepare 每批写出前调用，用于批量加载关联数据（游标查询不支持 Preload）
+	Prepare func(batch []T) error
+	// Progress 每批写出后调用，参数为已写出的行数；返回错误时中止导出
+	Progress func(written int) error
+}
+
+// ParseFormat 校验导出格式，默认 csv
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", CSV:
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q", format)
+}
+
+// ContentType 导出格式对应的 Content-Type
+func ContentType(format string) string {
+	if format == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FileName 带导出时间的文件名，例如 users-20260101-150405.csv
+func FileName(name, format string, now time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", name, now.In(location).Format("20060102-150405"), format)
+}
+
+// Stream 按 query 的筛选和排序逐批读取数据并写出，返回写出的数据行数
+func Stream[T any](w io.Writer, query *gorm.DB, table Table[T], format string) (int, error) {
+	// 先执行查询，查询失败时还没有写出任何内容，调用方仍可返回错误响应
+	rows, err := query.Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	out, err := newWriter(w, format, table.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	headers := make([]string, len(table.Columns))
+	numeric := make([]bool, len(table.Columns))
+	for i, col := range table.Columns {
+		headers[i] = col.Header
+		numeric[i] = col.Number
+	}
+	if err := out.writeRow(headers, nil); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	batch := make([]T, 0, batchSize)
+	values := make([]string, len(table.Columns))
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if table.Prepare != nil {
+			if err := table.Prepare(batch); err != nil {
+				return err
+			}
+		}
+		for i := range batch {
+			for j, col := range table.Columns {
+				values[j] = col.Value(&batch[i])
+			}
+			if err := out.writeRow(values, numeric); err != nil {
+				return err
+			}
+			written++
+		}
+		batch = batch[:0]
+		if err := out.flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		if table.Progress != nil {
+			return table.Progress(written)
+		}
+		return nil
+	}
+
+	for rows.Next() {
+		var row T
+		if err := query.ScanRows(rows, &row); err != nil {
+			return written, err
+		}
+		batch = append(batch, row)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return written, err
+	}
+	if err := flush(); err != nil {
+		return written, err
+	}
+	return written, out.close()
+}
+
+// JSONKeys 查询 JSONB 列在 query 筛选范围内出现过的顶层键，按字母排序
+func JSONKeys(db, query *gorm.DB, column string) ([]string, error) {
+	var keys []string
+	err := db.Raw(`SELECT DISTINCT k FROM (?) AS src,
+		jsonb_object_keys(CASE WHEN jsonb_typeof(src.doc) = 'object' THEN src.doc ELSE '{}' END) AS k ORDER BY k`,
+		query.Session(&gorm.Session{}).Select(column+" AS doc")).Scan(&keys).Error
+	return keys, err
+}
+
+// JSONColumns 把 JSONB 字段的每个顶层键展开为一列，表头为 prefix.键；嵌套的对象和数组写为 JSON
+func JSONColumns[T any](prefix string, keys []string, get func(row *T) map[string]interface{}) []Column[T] {
+	columns := make([]Column[T], 0, len(keys))
+	for _, key := range keys {
+		key := key
+		columns = append(columns, Column[T]{
+			Header: prefix + "." + key,
+			Value: func(row *T) string {
+				doc := get(row)
+				if doc == nil {
+					return ""
+				}
+				return Value(doc[key])
+			},
+		})
+	}
+	return columns
+}
+
+// Value 把任意值转换为单元格文本
+func Value(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return Bool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return Time(v)
+	case *time.Time:
+		return TimePtr(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// Time 按北京时间格式化，零值为空
+func Time(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(location).Format(timeLayout)
+}
+
+// TimePtr 同 Time，nil 为空
+func TimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return Time(*t)
+}
+
+// String 可空字符串
+func String(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Int 整数
+func Int[N int | int64](n N) string {
+	return strconv.FormatInt(int64(n), 10)
+}
+
+// Bool 是/否
+func Bool(b bool) string {
+	if b {
+		return "是"
+	}
+	return "否"
+}
@@ -0,0 +1,154 @@
+// Package featureflag 对客户端功能开关求值。
+//
+// 求值顺序：总开关关闭 → 关；黑名单 → 关；白名单 → 开；语言或客户端版本不符合 → 关；
+// 最后按灰度比例分桶。分桶用开关键和用户ID的哈希，同一用户在同一开关上的结果稳定，
+// 调大比例时已开启的用户保持开启，不同开关之间互不相关。
+package featureflag
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fluent-life-admin-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 求值原因
+const (
+	ReasonDisabled   = "disabled"    // 总开关关闭
+	ReasonDenied     = "denied"      // 在黑名单中
+	ReasonAllowed    = "allowed"     // 在白名单中
+	ReasonLanguage   = "language"    // 语言不在目标范围内
+	ReasonAppVersion = "app_version" // 客户端版本不在目标范围内
+	ReasonRollout    = "rollout"     // 按灰度比例
+)
+
+// Subject 求值对象
+type Subject struct {
+	UserID     uuid.UUID
+	Language   string
+	AppVersion string
+}
+
+// Result 一个开关对某个用户的求值结果
+type Result struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+	// Bucket 用户在该开关上的分桶 0-99，小于灰度比例时开启
+	Bucket int `json:"bucket"`
+}
+
+// Evaluate 对单个开关求值
+func Evaluate(flag *models.FeatureFlag, s Subject) Result {
+	user := s.UserID.String()
+	r := Result{Key: flag.Key, Bucket: Bucket(flag.Key, s.UserID)}
+	switch {
+	case !flag.Enabled:
+		r.Reason = ReasonDisabled
+	case containsFold(flag.DenyUsers, user):
+		r.Reason = ReasonDenied
+	case containsFold(flag.AllowUsers, user):
+		r.Enabled, r.Reason = true, ReasonAllowed
+	case len(flag.Languages) > 0 && !containsFold(flag.Languages, s.Language):
+		r.Reason = ReasonLanguage
+	case !versionInRange(s.AppVersion, flag.MinAppVersion, flag.MaxAppVersion):
+		r.Reason = ReasonAppVersion
+	default:
+		r.Enabled, r.Reason = r.Bucket < flag.RolloutPercent, ReasonRollout
+	}
+	return r
+}
+
+// EvaluateAll 对全部开关求值，Language 为空时从用户设置中读取
+func EvaluateAll(db *gorm.DB, s Subject) ([]Result, error) {
+	if s.Language == "" {
+		var settings models.UserSettings
+		if err := db.Select("language").Where("user_id = ?", s.UserID).Limit(1).Find(&settings).Error; err != nil {
+			return nil, err
+		}
+		s.Language = settings.Language
+	}
+
+	var flags []models.FeatureFlag
+	if err := db.Order("key ASC").Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(flags))
+	for i := range flags {
+		results[i] = Evaluate(&flags[i], s)
+	}
+	return results, nil
+}
+
+// Bucket 用户在开关上的分桶 0-99
+func Bucket(key string, userID uuid.UUID) int {
+	sum := sha256.Sum256([]byte(key + ":" + userID.String()))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 100)
+}
+
+// ParseVersion 解析 x.y.z 格式的版本号（段数 1-3，缺少的段按 0 处理）
+func ParseVersion(v string) ([3]int, error) {
+	var parts [3]int
+	fields := strings.Split(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".")
+	if len(fields) > 3 {
+		return parts, fmt.Errorf("无效的版本号 %q", v)
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("无效的版本号 %q", v)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
+
+// CompareVersions 比较两个已解析的版本号，返回 -1、0 或 1
+func CompareVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionInRange 限定了版本范围时，未上报或无法解析的客户端版本视为不符合
+func versionInRange(v, min, max string) bool {
+	if min == "" && max == "" {
+		return true
+	}
+	version, err := ParseVersion(v)
+	if err != nil {
+		return false
+	}
+	if min != "" {
+		if lo, err := ParseVersion(min); err != nil || CompareVersions(version, lo) < 0 {
+			return false
+		}
+	}
+	if max != "" {
+		if hi, err := ParseVersion(max); err != nil || CompareVersions(version, hi) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/pkg/auth"
+	"fluent-life-admin-api/pkg/response"
+	"fluent-life-admin-api/pkg/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// totpIssuer 显示在身份验证器应用中的发行方名称
+	totpIssuer = "FluentLife Admin"
+	// totpSkew 允许前后各一个时间步（30 秒）的时钟偏差
+	totpSkew = 1
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+
+	revokeReason2FAReset = "2fa_reset"
+)
+
+// completeLogin 登录成功后的收尾：清除失败计数、更新最后登录时间、签发会话并记录日志
+func (h *AdminHandler) completeLogin(c *gin.Context, user *models.User) {
+	h.loginGuard.Succeed(user.Username)
+
+	now := time.Now()
+	if err := h.db.Model(user).Update("last_login_at", now).Error; err != nil {
+		log.Printf("更新最后登录时间失败: %v", err)
+	}
+	user.LastLoginAt = &now
+
+	// 生成JWT Token并记录会话
+	result, err := issueSession(h.db, c, user)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成认证令牌失败")
+		return
+	}
+
+	h.logLogin(c, user, user.Username, "登录成功", "Success")
+	response.Success(c, result, "登录成功")
+}
+
+// verifySecondFactor 校验 TOTP 验证码或恢复码，成功时记录已使用的时间步或恢复码
+func verifySecondFactor(db *gorm.DB, user *models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew); ok {
+		// 条件更新保证同一验证码只能使用一次
+		res := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if res.Error != nil {
+			return false, res.Error
+		}
+		return res.RowsAffected == 1, nil
+	}
+
+	res := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// normalizeRecoveryCode 忽略大小写和分隔符
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// regenerateRecoveryCodes 替换用户的全部恢复码，返回明文（仅此一次可见）
+func regenerateRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: auth.HashToken(raw)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// currentUser 读取当前登录账号
+func (h *AdminHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, _ := c.Get("userID")
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return nil, false
+	}
+	return &user, true
+}
+
+// LoginTwoFactor 两步验证登录的第二步，提交挑战令牌和验证码（或恢复码）
+// POST /api/v1/admin/login/2fa
+func (h *AdminHandler) LoginTwoFactor(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	userID, err := auth.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, "验证已过期，请重新登录")
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ? AND role <> ?", userID, "user").First(&user).Error; err != nil {
+		response.Error(c, http.StatusUnauthorized, "用户不存在或无管理员权限")
+		return
+	}
+	if user.Status == 0 {
+		h.logLogin(c, &user, user.Username, "账号已被禁用", "Failure")
+		response.Error(c, http.StatusForbidden, "账号已被禁用")
+		return
+	}
+	if !user.TOTPEnabled {
+		response.Error(c, http.StatusBadRequest, "该账号未启用两步验证，请重新登录")
+		return
+	}
+
+	ip := c.ClientIP()
+	if wait := h.loginGuard.RetryAfter(user.Username, ip); wait > 0 {
+		h.logLogin(c, &user, user.Username, "两步验证被限流，剩余等待 "+wait.Round(time.Second).String(), "Failure")
+		response.Error(c, http.StatusTooManyRequests, "验证失败次数过多，请在 "+wait.Round(time.Second).String()+" 后重试")
+		return
+	}
+
+	ok, err := verifySecondFactor(h.db, &user, req.Code)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "验证失败")
+		return
+	}
+	if !ok {
+		h.loginGuard.Fail(user.Username, ip)
+		h.logLogin(c, &user, user.Username, "两步验证码错误", "Failure")
+		response.Error(c, http.StatusUnauthorized, "验证码错误")
+		return
+	}
+
+	h.completeLogin(c, &user)
+}
+
+// GetTwoFactorStatus 获取当前账号的两步验证状态
+// GET /api/v1/admin/2fa
+func (h *AdminHandler) GetTwoFactorStatus(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var remaining int64
+	h.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+
+	response.Success(c, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	}, "获取成功")
+}
+
+// SetupTwoFactor 生成新的 TOTP 密钥，需调用 EnableTwoFactor 确认后才会生效
+// POST /api/v1/admin/2fa/setup
+func (h *AdminHandler) SetupTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		response.Error(c, http.StatusBadRequest, "两步验证已启用")
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成密钥失败")
+		return
+	}
+	if err := h.db.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "保存密钥失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer, user.Username, secret),
+	}, "请使用身份验证器扫描并输入验证码完成启用")
+}
+
+// EnableTwoFactor 校验验证码后启用两步验证，并返回恢复码（仅显示一次）
+// POST /api/v1/admin/2fa/enable
+func (h *AdminHandler) EnableTwoFactor(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		response.Error(c, http.StatusBadRequest, "两步验证已启用")
+		return
+	}
+	if user.TOTPSecret == "" {
+		response.Error(c, http.StatusBadRequest, "请先生成两步验证密钥")
+		return
+	}
+
+	step, valid := totp.Validate(user.TOTPSecret, req.Code, time.Now(), totpSkew)
+	if !valid {
+		h.logOperation(c, "EnableTwoFactor", "User", user.ID.String(), "验证码错误", "Failure")
+		response.Error(c, http.StatusBadRequest, "验证码错误")
+		return
+	}
+
+	var codes []string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = regenerateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		h.logOperation(c, "EnableTwoFactor", "User", user.ID.String(), "启用两步验证失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "启用两步验证失败")
+		return
+	}
+
+	h.logOperation(c, "EnableTwoFactor", "User", user.ID.String(), "启用两步验证", "Success")
+	response.Success(c, gin.H{"recovery_codes": codes}, "两步验证已启用，请妥善保存恢复码")
+}
+
+// DisableTwoFactor 关闭当前账号的两步验证，需要同时提供密码和验证码
+// POST /api/v1/admin/2fa/disable
+func (h *AdminHandler) DisableTwoFactor(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		response.Error(c, http.StatusBadRequest, "两步验证未启用")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		h.logOperation(c, "DisableTwoFactor", "User", user.ID.String(), "密码错误", "Failure")
+		response.Error(c, http.StatusBadRequest, "密码错误")
+		return
+	}
+	valid, err := verifySecondFactor(h.db, user, req.Code)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "验证失败")
+		return
+	}
+	if !valid {
+		h.logOperation(c, "DisableTwoFactor", "User", user.ID.String(), "验证码错误", "Failure")
+		response.Error(c, http.StatusBadRequest, "验证码错误")
+		return
+	}
+
+	if err := clearTwoFactor(h.db, user.ID); err != nil {
+		response.Error(c, http.StatusInternalServerError, "关闭两步验证失败")
+		return
+	}
+
+	h.logOperation(c, "DisableTwoFactor", "User", user.ID.String(), "关闭两步验证", "Success")
+	response.Success(c, nil, "两步验证已关闭")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+// POST /api/v1/admin/2fa/recovery-codes
+func (h *AdminHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		response.Error(c, http.StatusBadRequest, "两步验证未启用")
+		return
+	}
+	// 只接受 TOTP 验证码，避免用最后一个恢复码无限续期
+	step, valid := totp.Validate(user.TOTPSecret, req.Code, time.Now(), totpSkew)
+	if !valid || step <= user.TOTPLastStep {
+		response.Error(c, http.StatusBadRequest, "验证码错误")
+		return
+	}
+
+	var codes []string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("totp_last_step", step).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = regenerateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成恢复码失败")
+		return
+	}
+
+	h.logOperation(c, "RegenerateRecoveryCodes", "User", user.ID.String(), "重新生成恢复码", "Success")
+	response.Success(c, gin.H{"recovery_codes": codes}, "恢复码已重新生成，请妥善保存")
+}
+
+// ResetUserTwoFactor 超级管理员重置其他管理员的两步验证（例如丢失设备），并吊销其所有会话
+// POST /api/v1/admin/users/:id/2fa/reset
+func (h *AdminHandler) ResetUserTwoFactor(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	currentUserID, _ := c.Get("userID")
+	if currentUserID == id {
+		response.Error(c, http.StatusBadRequest, "不能重置自己的两步验证，请使用关闭功能")
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", id).First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+	if !requireAccountAccess(h.db, c, &user) {
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearTwoFactor(tx, user.ID); err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID, revokeReason2FAReset)
+	})
+	if err != nil {
+		h.logOperation(c, "ResetUserTwoFactor", "User", user.ID.String(), "重置两步验证失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "重置两步验证失败")
+		return
+	}
+
+	h.logOperation(c, "ResetUserTwoFactor", "User", user.ID.String(), "重置用户 "+user.Username+" 的两步验证", "Success")
+	response.Success(c, nil, "两步验证已重置")
+}
+
+// clearTwoFactor 清除用户的 TOTP 密钥和恢复码
+func clearTwoFactor(db *gorm.DB, userID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
@@ -1,274 +1,552 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"fluent-life-admin-api/internal/airole"
 	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/voicetype"
 	"fluent-life-admin-api/pkg/response"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// AISimulationRole 表示一个AI角色配置
-type AISimulationRole struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	SystemPrompt string `json:"system_prompt"`
-	VoiceType   string `json:"voice_type"`
-	Enabled     bool   `json:"enabled"`
+// aiRoleRequest 创建和修改AI角色的参数，修改时只更新传入的字段
+type aiRoleRequest struct {
+	ID           string  `json:"id"`
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	Category     *string `json:"category"`
+	Avatar       *string `json:"avatar"`
+	OpeningLine  *string `json:"opening_line"`
+	VoiceType    *string `json:"voice_type"`
+	Enabled      *bool   `json:"enabled"`
+	Order        *int    `json:"order"`
+	SystemPrompt *string `json:"system_prompt"`
+	// Comment 提示词变更说明
+	Comment string `json:"comment"`
 }
 
-// GetAIRoles 获取所有AI角色配置（管理员）
+// GetAIRoles 获取AI角色列表（管理员），支持 category、enabled、q 筛选
 // GET /api/v1/admin/ai-roles
 func (h *AdminHandler) GetAIRoles(c *gin.Context) {
-	roles, err := h.loadRolesFromDB()
-	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "获取AI角色配置失败: "+err.Error())
+	query := h.db.Model(&models.AIRole{})
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if enabled := c.Query("enabled"); enabled != "" {
+		query = query.Where("enabled = ?", enabled == "true")
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("id ILIKE ? OR name ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+
+	var roles []models.AIRole
+	if err := query.Order(`"order" ASC, created_at ASC`).Find(&roles).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取AI角色失败")
+		return
+	}
+	response.Success(c, gin.H{"roles": roles, "total": len(roles)}, "获取成功")
+}
+
+// GetAIRoleCategories 获取AI角色的分类及各分类的角色数
+// GET /api/v1/admin/ai-roles/categories
+func (h *AdminHandler) GetAIRoleCategories(c *gin.Context) {
+	var categories []struct {
+		Category string `json:"category"`
+		Count    int64  `json:"count"`
+	}
+	if err := h.db.Model(&models.AIRole{}).Select("category, COUNT(*) AS count").
+		Group("category").Order("category ASC").Scan(&categories).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取分类失败")
 		return
 	}
-	response.Success(c, gin.H{"roles": roles}, "获取成功")
+	response.Success(c, gin.H{"categories": categories}, "获取成功")
 }
 
-// CreateAIRole 创建AI角色（管理员）
+// GetAIRole 获取AI角色详情
+// GET /api/v1/admin/ai-roles/:id
+func (h *AdminHandler) GetAIRole(c *gin.Context) {
+	var role models.AIRole
+	if err := h.db.Where("id = ?", c.Param("id")).First(&role).Error; err != nil {
+		aiRoleError(c, err, "获取AI角色失败")
+		return
+	}
+	response.Success(c, role, "获取成功")
+}
+
+// CreateAIRole 创建AI角色（管理员），system_prompt 作为第 1 版提示词并发布；
+// 不传 enabled 时默认启用，不传 order 时排在最后
 // POST /api/v1/admin/ai-roles
 func (h *AdminHandler) CreateAIRole(c *gin.Context) {
-	var role AISimulationRole
-	if err := c.ShouldBindJSON(&role); err != nil {
+	var req aiRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
 		return
 	}
 
 	// 验证必填字段
-	if role.ID == "" || role.Name == "" || role.SystemPrompt == "" {
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" || req.Name == nil || req.SystemPrompt == nil {
 		response.Error(c, http.StatusBadRequest, "id、name和system_prompt为必填项")
 		return
 	}
-
-	roles, err := h.loadRolesFromDB()
-	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "加载角色配置失败: "+err.Error())
+	if len([]rune(req.ID)) > 50 {
+		response.Error(c, http.StatusBadRequest, "id 最长 50 个字符")
 		return
 	}
 
-	// 检查ID是否已存在
-	for _, r := range roles {
-		if r.ID == role.ID {
-			response.Error(c, http.StatusBadRequest, "角色ID已存在")
-			return
-		}
+	role := models.AIRole{ID: req.ID, Enabled: true}
+	if msg := applyAIRoleRequest(&role, &req); msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+	role.SystemPrompt = *req.SystemPrompt
+	if !h.checkVoiceType(c, &role) {
+		return
 	}
 
-	// 验证音色类型是否存在且启用
-	if role.VoiceType != "" {
-		var voiceType models.VoiceType
-		if err := h.db.Where("type = ? AND enabled = ?", role.VoiceType, true).First(&voiceType).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				response.Error(c, http.StatusBadRequest, "音色类型不存在或未启用")
-				return
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.AIRole
+		if err := tx.Select("id").Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, r := range existing {
+			if r.ID == role.ID {
+				return errAIRoleExists
 			}
-			response.Error(c, http.StatusInternalServerError, "验证音色类型失败: "+err.Error())
-			return
 		}
-	} else {
-		// 如果没有指定音色，尝试使用第一个启用的音色
-		var defaultVoiceType models.VoiceType
-		if err := h.db.Where("enabled = ?", true).First(&defaultVoiceType).Error; err == nil {
-			role.VoiceType = defaultVoiceType.Type
-		} else {
-			// 如果数据库中没有音色，使用默认值
-			role.VoiceType = "zh_female_wanqudashu_moon_bigtts"
+		if len(existing) >= airole.MaxRoles {
+			return errTooManyAIRoles
 		}
-	}
-
-	roles = append(roles, role)
-	if err := h.saveRolesToDB(roles); err != nil {
-		response.Error(c, http.StatusInternalServerError, "保存角色配置失败: "+err.Error())
+		if req.Order == nil {
+			if err := tx.Model(&models.AIRole{}).Select(`COALESCE(MAX("order"), 0) + 1`).Scan(&role.Order).Error; err != nil {
+				return err
+			}
+		}
+		return airole.Create(tx, &role, req.Comment, settingActor(c))
+	})
+	if err != nil {
+		aiRoleError(c, err, "创建AI角色失败")
 		return
 	}
 
+	h.syncAIRoleSetting(c)
+	h.logOperation(c, "CreateAIRole", "AIRole", role.ID, "创建AI角色: "+role.Name, "Success")
 	response.Success(c, role, "创建成功")
 }
 
-// UpdateAIRole 更新AI角色（管理员）
+// UpdateAIRole 更新AI角色（管理员）。传入的 system_prompt 与已发布版本不同时，
+// 新建一个提示词版本并发布
 // PUT /api/v1/admin/ai-roles/:id
 func (h *AdminHandler) UpdateAIRole(c *gin.Context) {
 	roleID := c.Param("id")
 
-	var req AISimulationRole
+	var req aiRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
 		return
 	}
 
-	roles, err := h.loadRolesFromDB()
+	var role *models.AIRole
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if role, err = airole.Lock(tx, roleID); err != nil {
+			return err
+		}
+		if msg := applyAIRoleRequest(role, &req); msg != "" {
+			return aiRoleInvalid(msg)
+		}
+		if req.VoiceType != nil {
+			if role.VoiceType == "" {
+				role.VoiceType = airole.DefaultVoiceType(tx)
+			} else if !voicetype.Enabled(tx, role.VoiceType) {
+				return errVoiceTypeUnavailable
+			}
+		}
+		if err := tx.Model(role).Select("name", "description", "category", "avatar", "opening_line",
+			"voice_type", "enabled", "order", "updated_at").Updates(role).Error; err != nil {
+			return err
+		}
+		if req.SystemPrompt != nil && *req.SystemPrompt != role.SystemPrompt {
+			comment := req.Comment
+			if comment == "" {
+				comment = "编辑角色时修改"
+			}
+			_, err = airole.AddVersion(tx, role, *req.SystemPrompt, comment, settingActor(c), nil, true)
+		}
+		return err
+	})
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "加载角色配置失败: "+err.Error())
+		aiRoleError(c, err, "更新AI角色失败")
 		return
 	}
 
-	// 验证音色类型是否存在且启用
-	if req.VoiceType != "" {
-		var voiceType models.VoiceType
-		if err := h.db.Where("type = ? AND enabled = ?", req.VoiceType, true).First(&voiceType).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				response.Error(c, http.StatusBadRequest, "音色类型不存在或未启用")
-				return
-			}
-			response.Error(c, http.StatusInternalServerError, "验证音色类型失败: "+err.Error())
-			return
+	h.syncAIRoleSetting(c)
+	h.logOperation(c, "UpdateAIRole", "AIRole", role.ID, "更新AI角色: "+role.Name, "Success")
+	response.Success(c, role, "更新成功")
+}
+
+// DeleteAIRole 删除AI角色及其提示词历史（管理员）
+// DELETE /api/v1/admin/ai-roles/:id
+func (h *AdminHandler) DeleteAIRole(c *gin.Context) {
+	roleID := c.Param("id")
+
+	var role *models.AIRole
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if role, err = airole.Lock(tx, roleID); err != nil {
+			return err
 		}
+		return airole.Delete(tx, roleID)
+	})
+	if err != nil {
+		aiRoleError(c, err, "删除AI角色失败")
+		return
 	}
 
-	// 查找并更新角色
-	found := false
-	for i := range roles {
-		if roles[i].ID == roleID {
-			// 保留原有ID，更新其他字段
-			req.ID = roleID
-			roles[i] = req
-			found = true
-			break
+	h.syncAIRoleSetting(c)
+	h.logOperation(c, "DeleteAIRole", "AIRole", roleID, "删除AI角色: "+role.Name, "Success")
+	response.Success(c, nil, "删除成功")
+}
+
+// ReorderAIRoles 调整AI角色顺序，ids 需包含全部角色
+// PUT /api/v1/admin/ai-roles/order
+func (h *AdminHandler) ReorderAIRoles(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 ids")
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return reorder(tx, tx.Model(&models.AIRole{}), req.IDs)
+	})
+	if err != nil {
+		if errors.Is(err, errIncompleteOrder) {
+			response.Error(c, http.StatusBadRequest, "ids 必须包含全部角色且不能重复")
+			return
 		}
+		response.Error(c, http.StatusInternalServerError, "调整顺序失败")
+		return
 	}
 
-	if !found {
-		response.Error(c, http.StatusNotFound, "角色不存在")
+	h.syncAIRoleSetting(c)
+	h.logOperation(c, "ReorderAIRoles", "AIRole", "", fmt.Sprintf("调整 %d 个AI角色的顺序", len(req.IDs)), "Success")
+	response.Success(c, nil, "调整成功")
+}
+
+// GetAIRolePrompts 获取AI角色的提示词历史，按版本倒序
+// GET /api/v1/admin/ai-roles/:id/prompts
+func (h *AdminHandler) GetAIRolePrompts(c *gin.Context) {
+	var role models.AIRole
+	if err := h.db.Where("id = ?", c.Param("id")).First(&role).Error; err != nil {
+		aiRoleError(c, err, "获取AI角色失败")
 		return
 	}
 
-	if err := h.saveRolesToDB(roles); err != nil {
-		response.Error(c, http.StatusInternalServerError, "保存角色配置失败: "+err.Error())
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	var versions []models.AIRolePromptVersion
+	var total int64
+
+	query := h.db.Model(&models.AIRolePromptVersion{}).Where("role_id = ?", role.ID)
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(pageSize).Order("version DESC").Find(&versions).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
 		return
 	}
+	for i := range versions {
+		versions[i].Published = versions[i].Version == role.PublishedVersion
+	}
 
-	response.Success(c, req, "更新成功")
+	response.Success(c, gin.H{
+		"items":             versions,
+		"total":             total,
+		"page":              page,
+		"page_size":         pageSize,
+		"published_version": role.PublishedVersion,
+	}, "获取成功")
 }
 
-// DeleteAIRole 删除AI角色（管理员）
-// DELETE /api/v1/admin/ai-roles/:id
-func (h *AdminHandler) DeleteAIRole(c *gin.Context) {
-	roleID := c.Param("id")
+// CreateAIRolePrompt 新建提示词版本，publish 为 true 时同时发布
+// POST /api/v1/admin/ai-roles/:id/prompts
+func (h *AdminHandler) CreateAIRolePrompt(c *gin.Context) {
+	var req struct {
+		SystemPrompt string `json:"system_prompt" binding:"required"`
+		Comment      string `json:"comment" binding:"max=255"`
+		Publish      bool   `json:"publish"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 system_prompt")
+		return
+	}
+	if msg := checkSystemPrompt(req.SystemPrompt); msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	h.changeAIRolePrompt(c, "CreateAIRolePrompt", func(tx *gorm.DB, role *models.AIRole) (*models.AIRolePromptVersion, error) {
+		return airole.AddVersion(tx, role, req.SystemPrompt, req.Comment, settingActor(c), nil, req.Publish)
+	})
+}
 
-	roles, err := h.loadRolesFromDB()
+// PublishAIRolePrompt 发布提示词的某个版本
+// POST /api/v1/admin/ai-roles/:id/prompts/:version/publish
+func (h *AdminHandler) PublishAIRolePrompt(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "加载角色配置失败: "+err.Error())
+		response.Error(c, http.StatusBadRequest, "无效的版本号")
 		return
 	}
 
-	// 过滤掉要删除的角色
-	newRoles := make([]AISimulationRole, 0)
-	found := false
-	for _, r := range roles {
-		if r.ID != roleID {
-			newRoles = append(newRoles, r)
-		} else {
-			found = true
+	h.changeAIRolePrompt(c, "PublishAIRolePrompt", func(tx *gorm.DB, role *models.AIRole) (*models.AIRolePromptVersion, error) {
+		return airole.Publish(tx, role, version)
+	})
+}
+
+// RestoreAIRolePrompt 以历史版本的内容新建一个版本，publish 为 true 时同时发布
+// POST /api/v1/admin/ai-roles/:id/prompts/:version/restore
+func (h *AdminHandler) RestoreAIRolePrompt(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的版本号")
+		return
+	}
+	var req struct {
+		Comment string `json:"comment" binding:"max=255"`
+		Publish bool   `json:"publish"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "参数错误")
+			return
 		}
 	}
 
-	if !found {
-		response.Error(c, http.StatusNotFound, "角色不存在")
+	h.changeAIRolePrompt(c, "RestoreAIRolePrompt", func(tx *gorm.DB, role *models.AIRole) (*models.AIRolePromptVersion, error) {
+		old, err := airole.FindVersion(tx, role.ID, version)
+		if err != nil {
+			return nil, err
+		}
+		comment := req.Comment
+		if comment == "" {
+			comment = fmt.Sprintf("恢复自版本 %d", version)
+		}
+		return airole.AddVersion(tx, role, old.SystemPrompt, comment, settingActor(c), &version, req.Publish)
+	})
+}
+
+// DiffAIRolePrompts 比较两个提示词版本，to 默认为最新版本，from 默认为已发布版本
+// GET /api/v1/admin/ai-roles/:id/prompts/diff?from=1&to=2
+func (h *AdminHandler) DiffAIRolePrompts(c *gin.Context) {
+	var role models.AIRole
+	if err := h.db.Where("id = ?", c.Param("id")).First(&role).Error; err != nil {
+		aiRoleError(c, err, "获取AI角色失败")
 		return
 	}
 
-	if err := h.saveRolesToDB(newRoles); err != nil {
-		response.Error(c, http.StatusInternalServerError, "保存角色配置失败: "+err.Error())
+	from, to := role.PublishedVersion, role.LatestVersion
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"from", &from}, {"to", &to}} {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "无效的版本号: "+p.name)
+				return
+			}
+			*p.dst = n
+		}
+	}
+
+	fromVersion, err := airole.FindVersion(h.db, role.ID, from)
+	if err != nil {
+		aiRoleError(c, err, "获取提示词版本失败")
+		return
+	}
+	toVersion, err := airole.FindVersion(h.db, role.ID, to)
+	if err != nil {
+		aiRoleError(c, err, "获取提示词版本失败")
 		return
 	}
 
-	response.Success(c, nil, "删除成功")
+	response.Success(c, gin.H{
+		"from":  fromVersion.Version,
+		"to":    toVersion.Version,
+		"lines": airole.Diff(fromVersion.SystemPrompt, toVersion.SystemPrompt),
+	}, "获取成功")
 }
 
-// InitAIRolesFromConfig 从配置文件初始化AI角色（管理员）
+// InitAIRolesFromConfig 写入预置AI角色（管理员），已存在的角色保持不变，可重复执行
 // POST /api/v1/admin/ai-roles/init-from-config
 func (h *AdminHandler) InitAIRolesFromConfig(c *gin.Context) {
-	// 默认角色配置
-	defaultRoles := []AISimulationRole{
-		{
-			ID:          "interviewer",
-			Name:        "面试官",
-			Description: "专业的面试官，帮助提升面试技巧",
-			SystemPrompt: "你现在是一名面试官，请根据用户的问题进行提问和追问，并对用户的回答进行评价和指导。你的目标是模拟一场真实的面试，帮助用户提升面试技巧。",
-			VoiceType:   "zh_female_wanqudashu_moon_bigtts",
-			Enabled:     true,
-		},
-		{
-			ID:          "language_tutor",
-			Name:        "语言导师",
-			Description: "专业的语言导师，帮助练习口语和纠正语法",
-			SystemPrompt: "你现在是一名语言导师，请帮助用户练习口语，纠正语法错误，并提供词汇和表达建议。你的目标是帮助用户提高语言流利度和准确性。",
-			VoiceType:   "zh_female_wanqudashu_moon_bigtts",
-			Enabled:     true,
-		},
-		{
-			ID:          "presentation_coach",
-			Name:        "演讲教练",
-			Description: "专业的演讲教练，帮助准备演讲和提升表达能力",
-			SystemPrompt: "你现在是一名演讲教练，请帮助用户准备演讲，提供演讲稿修改建议，并指导用户如何更好地表达。你的目标是帮助用户提升演讲能力和自信心。",
-			VoiceType:   "zh_female_wanqudashu_moon_bigtts",
-			Enabled:     true,
-		},
-	}
-
-	if err := h.saveRolesToDB(defaultRoles); err != nil {
-		response.Error(c, http.StatusInternalServerError, "初始化AI角色配置失败: "+err.Error())
+	created, err := airole.Seed(h.db, settingActor(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "初始化AI角色失败: "+err.Error())
 		return
 	}
 
-	response.Success(c, gin.H{"roles": defaultRoles}, "初始化成功")
+	var roles []models.AIRole
+	if err := h.db.Order(`"order" ASC, created_at ASC`).Find(&roles).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取AI角色失败")
+		return
+	}
+
+	if len(created) > 0 {
+		h.syncAIRoleSetting(c)
+		h.logOperation(c, "InitAIRoles", "AIRole", "", "写入预置AI角色: "+strings.Join(created, ", "), "Success")
+	}
+	response.Success(c, gin.H{
+		"created": created,
+		"skipped": len(airole.DefaultRoles) - len(created),
+		"roles":   roles,
+	}, "初始化成功")
 }
 
-// loadRolesFromDB 从数据库加载角色配置
-func (h *AdminHandler) loadRolesFromDB() ([]AISimulationRole, error) {
-	roles := make([]AISimulationRole, 0)
-
-	// 尝试从数据库读取配置
-	var setting models.AppSetting
-	err := h.db.Where("key = ?", "ai_simulation_roles").First(&setting).Error
-	if err == nil {
-		// 如果数据库中有配置，则使用数据库配置
-		var dbRoles []AISimulationRole
-		if err := json.Unmarshal([]byte(setting.Value), &dbRoles); err == nil {
-			if len(dbRoles) > 0 {
-				roles = dbRoles
-			}
-		} else {
-			// JSON解析失败，返回错误
-			return nil, fmt.Errorf("解析数据库中的AI角色配置失败: %w", err)
+// changeAIRolePrompt 锁定角色后修改提示词版本，写入响应
+func (h *AdminHandler) changeAIRolePrompt(c *gin.Context, action string, change func(tx *gorm.DB, role *models.AIRole) (*models.AIRolePromptVersion, error)) {
+	var role *models.AIRole
+	var version *models.AIRolePromptVersion
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if role, err = airole.Lock(tx, c.Param("id")); err != nil {
+			return err
 		}
-	} else if err != gorm.ErrRecordNotFound {
-		// 如果是其他错误，返回错误
-		return nil, fmt.Errorf("从数据库加载AI角色配置失败: %w", err)
+		version, err = change(tx, role)
+		return err
+	})
+	if err != nil {
+		aiRoleError(c, err, "修改提示词失败")
+		return
 	}
-	// 如果没找到（ErrRecordNotFound），返回空列表
 
-	return roles, nil
+	if version.Published {
+		h.syncAIRoleSetting(c)
+	}
+	h.logOperation(c, action, "AIRole", role.ID,
+		fmt.Sprintf("AI角色 %s 提示词版本 %d（已发布版本 %d）", role.Name, version.Version, role.PublishedVersion), "Success")
+	response.Success(c, gin.H{"role": role, "version": version}, "操作成功")
 }
 
-// saveRolesToDB 保存角色配置到数据库
-func (h *AdminHandler) saveRolesToDB(roles []AISimulationRole) error {
-	rolesJSON, err := json.Marshal(roles)
-	if err != nil {
-		return err
+// syncAIRoleSetting 把角色写回 ai_simulation_roles 设置，失败时只记录日志，下次修改角色时会重新同步
+func (h *AdminHandler) syncAIRoleSetting(c *gin.Context) {
+	if err := airole.SyncSetting(h.db, h.settings, settingActor(c)); err != nil {
+		log.Printf("同步 ai_simulation_roles 设置失败: %v", err)
+	}
+}
+
+// checkVoiceType 校验角色的音色存在且已启用，未指定时使用默认音色；失败时已写入响应
+func (h *AdminHandler) checkVoiceType(c *gin.Context, role *models.AIRole) bool {
+	if role.VoiceType == "" {
+		role.VoiceType = airole.DefaultVoiceType(h.db)
+		return true
+	}
+	if !voicetype.Enabled(h.db, role.VoiceType) {
+		response.Error(c, http.StatusBadRequest, "音色类型不存在或未启用")
+		return false
+	}
+	return true
+}
+
+var (
+	errAIRoleExists         = errors.New("ai role already exists")
+	errTooManyAIRoles       = errors.New("too many ai roles")
+	errVoiceTypeUnavailable = errors.New("voice type unavailable")
+)
+
+// aiRoleInvalid 参数校验错误，错误信息直接返回给管理员
+type aiRoleInvalid string
+
+func (e aiRoleInvalid) Error() string { return string(e) }
+
+// aiRoleError 把AI角色操作的错误转换为响应
+func aiRoleError(c *gin.Context, err error, msg string) {
+	var invalid aiRoleInvalid
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(c, http.StatusNotFound, "角色不存在")
+	case errors.Is(err, airole.ErrVersionNotFound):
+		response.Error(c, http.StatusNotFound, "提示词版本不存在")
+	case errors.Is(err, errAIRoleExists):
+		response.Error(c, http.StatusBadRequest, "角色ID已存在")
+	case errors.Is(err, errTooManyAIRoles):
+		response.Error(c, http.StatusBadRequest, fmt.Sprintf("最多 %d 个角色", airole.MaxRoles))
+	case errors.Is(err, errVoiceTypeUnavailable):
+		response.Error(c, http.StatusBadRequest, "音色类型不存在或未启用")
+	case errors.As(err, &invalid):
+		response.Error(c, http.StatusBadRequest, invalid.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, msg)
 	}
+}
 
-	var setting models.AppSetting
-	err = h.db.Where("key = ?", "ai_simulation_roles").First(&setting).Error
-	if err == gorm.ErrRecordNotFound {
-		// 如果不存在，创建新记录
-		setting = models.AppSetting{
-			Key:         "ai_simulation_roles",
-			Value:       string(rolesJSON),
-			Description: "AI实战模拟角色配置",
+// applyAIRoleRequest 把请求中传入的角色信息写入 role 并校验（不含提示词内容），返回错误提示
+func applyAIRoleRequest(role *models.AIRole, req *aiRoleRequest) string {
+	fields := []struct {
+		name string
+		src  *string
+		dst  *string
+		max  int
+	}{
+		{"name", req.Name, &role.Name, 50},
+		{"description", req.Description, &role.Description, 255},
+		{"category", req.Category, &role.Category, 50},
+		{"avatar", req.Avatar, &role.Avatar, 500},
+		{"opening_line", req.OpeningLine, &role.OpeningLine, 2000},
+		{"voice_type", req.VoiceType, &role.VoiceType, 100},
+	}
+	for _, f := range fields {
+		if f.src == nil {
+			continue
 		}
-		return h.db.Create(&setting).Error
-	} else if err != nil {
-		return err
+		v := strings.TrimSpace(*f.src)
+		if len([]rune(v)) > f.max {
+			return fmt.Sprintf("%s 最长 %d 个字符", f.name, f.max)
+		}
+		*f.dst = v
+	}
+	if role.Name == "" {
+		return "name 不能为空"
+	}
+	if req.Enabled != nil {
+		role.Enabled = *req.Enabled
+	}
+	if req.Order != nil {
+		role.Order = *req.Order
 	}
+	if req.SystemPrompt != nil {
+		return checkSystemPrompt(*req.SystemPrompt)
+	}
+	return ""
+}
 
-	// 如果存在，更新
-	setting.Value = string(rolesJSON)
-	return h.db.Save(&setting).Error
+// checkSystemPrompt 与 ai_simulation_roles 设置的校验规则一致
+func checkSystemPrompt(prompt string) string {
+	n := len([]rune(prompt))
+	if strings.TrimSpace(prompt) == "" {
+		return "system_prompt 不能为空"
+	}
+	if n > 4000 {
+		return "system_prompt 最长 4000 个字符"
+	}
+	return ""
 }
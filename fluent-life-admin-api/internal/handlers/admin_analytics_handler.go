@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fluent-life-admin-api/internal/analytics"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdminAnalyticsHandler 数据分析
+type AdminAnalyticsHandler struct {
+	db *gorm.DB
+}
+
+func NewAdminAnalyticsHandler(db *gorm.DB) *AdminAnalyticsHandler {
+	return &AdminAnalyticsHandler{db: db}
+}
+
+// GetTimeSeries 按天/周/月统计新增用户、活跃用户、训练时长、帖子、评论和 AI 对话
+// GET /api/v1/admin/analytics/timeseries?start=2026-01-01&end=2026-01-31&interval=day
+func (h *AdminAnalyticsHandler) GetTimeSeries(c *gin.Context) {
+	r, err := analytics.ParseRange(c.Query("start"), c.Query("end"), c.Query("interval"), time.Now())
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := analytics.Compute(h.db, r)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "统计失败")
+		return
+	}
+
+	response.Success(c, series, "获取成功")
+}
+
+// GetCohorts 按注册周分组统计学员的第 N 天训练留存，默认最近 12 周、第 1/7/30 天
+// GET /api/v1/admin/analytics/cohorts?start=2026-01-01&end=2026-03-31&days=1,7,30&gender=&difficulty_level=&first_training_type=
+func (h *AdminAnalyticsHandler) GetCohorts(c *gin.Context) {
+	now := time.Now()
+	start := c.Query("start")
+	if start == "" {
+		start = now.In(analytics.Location).AddDate(0, 0, -7*11).Format("2006-01-02")
+	}
+	r, err := analytics.ParseRange(start, c.Query("end"), analytics.Week, now)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	days := analytics.DefaultRetentionDays
+	if raw := c.Query("days"); raw != "" {
+		days = nil
+		for _, part := range strings.Split(raw, ",") {
+			d, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || d < 1 || d > 365 {
+				response.Error(c, http.StatusBadRequest, "days 需为 1-365 之间的整数，用逗号分隔")
+				return
+			}
+			days = append(days, d)
+		}
+		if len(days) > 10 {
+			response.Error(c, http.StatusBadRequest, "days 最多 10 个")
+			return
+		}
+	}
+
+	filter := analytics.CohortFilter{
+		Gender:            c.Query("gender"),
+		DifficultyLevel:   c.Query("difficulty_level"),
+		FirstTrainingType: c.Query("first_training_type"),
+	}
+	matrix, err := analytics.Cohorts(h.db, r, days, filter, now)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "统计失败")
+		return
+	}
+
+	response.Success(c, matrix, "获取成功")
+}
+
+// GetUserProfile 用户学习档案：连续训练、各类训练时长、每日目标达成、冥想进度、成就、脱敏场景和社区活动
+// GET /api/v1/admin/users/:id/profile
+func (h *AdminAnalyticsHandler) GetUserProfile(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	profile, err := analytics.UserProfile(h.db, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, analytics.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "统计失败")
+		return
+	}
+
+	response.Success(c, profile, "获取成功")
+}
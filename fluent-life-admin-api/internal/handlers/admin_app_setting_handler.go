@@ -1,115 +1,200 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"strings"
+	"strconv"
 
+	"fluent-life-admin-api/internal/audit"
 	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/settings"
 	"fluent-life-admin-api/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type AdminAppSettingHandler struct {
-	db *gorm.DB
+	db    *gorm.DB
+	store *settings.Store
 }
 
-func NewAdminAppSettingHandler(db *gorm.DB) *AdminAppSettingHandler {
-	return &AdminAppSettingHandler{db: db}
+func NewAdminAppSettingHandler(db *gorm.DB, store *settings.Store) *AdminAppSettingHandler {
+	return &AdminAppSettingHandler{db: db, store: store}
 }
 
-// GetAppSettings 获取所有应用设置（管理员）
+// GetAppSettings 获取所有应用设置（管理员），包括未修改过、使用默认值的设置项
 // GET /api/v1/admin/app-settings
 func (h *AdminAppSettingHandler) GetAppSettings(c *gin.Context) {
-	var settings []models.AppSetting
-	if err := h.db.Order("key ASC").Find(&settings).Error; err != nil {
+	entries, err := h.store.Entries()
+	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取应用设置失败")
 		return
 	}
-	response.Success(c, gin.H{"settings": settings, "total": len(settings)}, "获取成功")
+	response.Success(c, gin.H{"settings": entries, "total": len(entries)}, "获取成功")
 }
 
-// CreateAppSetting 创建应用设置（管理员）
-// POST /api/v1/admin/app-settings
-func (h *AdminAppSettingHandler) CreateAppSetting(c *gin.Context) {
-	var req struct {
-		Key         string `json:"key" binding:"required"`
-		Value       string `json:"value" binding:"required"`
-		Description string `json:"description"`
+// GetAppSetting 获取单个应用设置（管理员）
+// GET /api/v1/admin/app-settings/:key
+func (h *AdminAppSettingHandler) GetAppSetting(c *gin.Context) {
+	entry, err := h.store.Entry(c.Param("key"))
+	if err != nil {
+		settingError(c, err, "获取应用设置失败")
+		return
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "参数错误")
+	response.Success(c, entry, "获取成功")
+}
+
+// UpdateAppSetting 修改应用设置（管理员），value 按设置项的类型传 JSON 值；
+// 传入 version 时，设置已被他人修改会返回 409
+// PUT /api/v1/admin/app-settings/:key
+func (h *AdminAppSettingHandler) UpdateAppSetting(c *gin.Context) {
+	key := c.Param("key")
+	if readOnlySetting(c, key) {
 		return
 	}
-	key := strings.TrimSpace(req.Key)
-	if key == "" {
-		response.Error(c, http.StatusBadRequest, "key 不能为空")
+
+	var req struct {
+		Value   json.RawMessage `json:"value"`
+		Version *int            `json:"version"`
+		Comment string          `json:"comment" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Value) == 0 {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 value")
 		return
 	}
 
-	setting := models.AppSetting{Key: key, Value: req.Value, Description: req.Description}
-	if err := h.db.Create(&setting).Error; err != nil {
-		response.Error(c, http.StatusInternalServerError, "创建应用设置失败")
+	entry, err := h.store.Set(settingActor(c), key, req.Value, req.Version, req.Comment)
+	if err != nil {
+		settingError(c, err, "更新应用设置失败")
 		return
 	}
-	response.Success(c, setting, "创建成功")
+	audit.Annotate(c, "UpdateAppSetting", "AppSetting", key, fmt.Sprintf("修改设置 %s（版本 %d）", key, entry.Version), "Success")
+	response.Success(c, entry, "更新成功")
 }
 
-// UpdateAppSetting 更新应用设置（管理员）
-// PUT /api/v1/admin/app-settings/:id
-func (h *AdminAppSettingHandler) UpdateAppSetting(c *gin.Context) {
-	id := c.Param("id")
-
-	var req struct {
-		Key         *string `json:"key"`
-		Value       *string `json:"value"`
-		Description *string `json:"description"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "参数错误")
+// ResetAppSetting 恢复应用设置的默认值（管理员），可选 query 参数 version 用于检查并发修改
+// DELETE /api/v1/admin/app-settings/:key
+func (h *AdminAppSettingHandler) ResetAppSetting(c *gin.Context) {
+	key := c.Param("key")
+	if readOnlySetting(c, key) {
 		return
 	}
 
-	var setting models.AppSetting
-	if err := h.db.Where("id = ?", id).First(&setting).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			response.Error(c, http.StatusNotFound, "应用设置不存在")
+	var expect *int
+	if v := c.Query("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "无效的版本号")
 			return
 		}
-		response.Error(c, http.StatusInternalServerError, "获取应用设置失败")
-		return
+		expect = &n
 	}
 
-	if req.Key != nil {
-		k := strings.TrimSpace(*req.Key)
-		if k == "" {
-			response.Error(c, http.StatusBadRequest, "key 不能为空")
-			return
-		}
-		setting.Key = k
+	entry, err := h.store.Reset(settingActor(c), key, expect, c.Query("comment"))
+	if err != nil {
+		settingError(c, err, "恢复默认值失败")
+		return
 	}
-	if req.Value != nil {
-		setting.Value = *req.Value
+	audit.Annotate(c, "ResetAppSetting", "AppSetting", key, fmt.Sprintf("设置 %s 恢复默认值", key), "Success")
+	response.Success(c, entry, "已恢复默认值")
+}
+
+// GetAppSettingHistory 获取应用设置的变更历史，按版本倒序
+// GET /api/v1/admin/app-settings/:key/history
+func (h *AdminAppSettingHandler) GetAppSettingHistory(c *gin.Context) {
+	key := c.Param("key")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
 	}
-	if req.Description != nil {
-		setting.Description = *req.Description
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
 	}
+	offset := (page - 1) * pageSize
 
-	if err := h.db.Save(&setting).Error; err != nil {
-		response.Error(c, http.StatusInternalServerError, "更新应用设置失败")
+	var revisions []models.AppSettingRevision
+	var total int64
+
+	query := h.db.Model(&models.AppSettingRevision{}).Where("key = ?", key)
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(pageSize).Order("version DESC").Find(&revisions).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
 		return
 	}
-	response.Success(c, setting, "更新成功")
+
+	response.Success(c, gin.H{
+		"items":     revisions,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
 }
 
-// DeleteAppSetting 删除应用设置（管理员）
-// DELETE /api/v1/admin/app-settings/:id
-func (h *AdminAppSettingHandler) DeleteAppSetting(c *gin.Context) {
-	id := c.Param("id")
+// RollbackAppSetting 把应用设置回滚到历史版本（管理员），回滚本身记为一个新版本
+// POST /api/v1/admin/app-settings/:key/rollback
+func (h *AdminAppSettingHandler) RollbackAppSetting(c *gin.Context) {
+	key := c.Param("key")
+	if readOnlySetting(c, key) {
+		return
+	}
+
+	var req struct {
+		Version int    `json:"version" binding:"required,min=1"`
+		Comment string `json:"comment" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供要回滚到的 version")
+		return
+	}
 
-	if err := h.db.Delete(&models.AppSetting{}, "id = ?", id).Error; err != nil {
-		response.Error(c, http.StatusInternalServerError, "删除应用设置失败")
+	entry, err := h.store.Rollback(settingActor(c), key, req.Version, req.Comment)
+	if err != nil {
+		settingError(c, err, "回滚应用设置失败")
 		return
 	}
-	response.Success(c, nil, "删除成功")
+	audit.Annotate(c, "RollbackAppSetting", "AppSetting", key,
+		fmt.Sprintf("设置 %s 回滚到版本 %d（新版本 %d）", key, req.Version, entry.Version), "Success")
+	response.Success(c, entry, "回滚成功")
+}
+
+// readOnlySetting 只读设置项返回 400
+func readOnlySetting(c *gin.Context, key string) bool {
+	if def, ok := settings.Lookup(key); ok && def.ReadOnly {
+		response.Error(c, http.StatusBadRequest, "该设置项由系统同步生成，不能直接修改")
+		return true
+	}
+	return false
+}
+
+// settingError 把设置读写的错误转换为响应
+func settingError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, settings.ErrUnknownKey):
+		response.Error(c, http.StatusBadRequest, "未注册的设置项")
+	case errors.Is(err, settings.ErrRevisionNotFound):
+		response.Error(c, http.StatusNotFound, "历史版本不存在")
+	case errors.Is(err, settings.ErrVersionConflict):
+		response.Error(c, http.StatusConflict, "设置已被其他管理员修改，请刷新后重试")
+	case settings.IsInvalid(err):
+		response.Error(c, http.StatusBadRequest, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, msg)
+	}
+}
+
+// settingActor 当前管理员，记录到设置的变更历史
+func settingActor(c *gin.Context) settings.Actor {
+	var actor settings.Actor
+	if v, ok := c.Get("userID"); ok {
+		actor.ID, _ = v.(uuid.UUID)
+	}
+	if v, ok := c.Get("username"); ok {
+		actor.Name, _ = v.(string)
+	}
+	return actor
 }
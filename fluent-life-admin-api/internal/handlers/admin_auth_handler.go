@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/pkg/auth"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 会话吊销原因
+const (
+	revokeReasonLogout          = "logout"
+	revokeReasonRotated         = "rotated"
+	revokeReasonPasswordChanged = "password_changed"
+	revokeReasonRoleChanged     = "role_changed"
+	revokeReasonDisabled        = "disabled"
+	revokeReasonDeleted         = "deleted"
+)
+
+// issueSession 为用户签发访问令牌和刷新令牌，并持久化会话记录
+func issueSession(db *gorm.DB, c *gin.Context, user *models.User) (gin.H, error) {
+	refreshToken, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.AuthSession{
+		UserID:           user.ID,
+		JTI:              uuid.New().String(),
+		RefreshTokenHash: auth.HashToken(refreshToken),
+		ExpiresAt:        now.Add(auth.AccessTokenTTL),
+		RefreshExpiresAt: now.Add(auth.RefreshTokenTTL),
+		IP:               c.ClientIP(),
+		UserAgent:        truncate(c.Request.UserAgent(), 255),
+	}
+
+	token, err := auth.GenerateToken(user.ID, user.Role, session.JTI)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":                token,
+		"refresh_token":        refreshToken,
+		"expires_at":           session.ExpiresAt,
+		"refresh_expires_at":   session.RefreshExpiresAt,
+		"user_id":              user.ID,
+		"username":             user.Username,
+		"role":                 user.Role,
+		"must_change_password": user.MustChangePassword,
+	}, nil
+}
+
+// revokeUserSessions 吊销用户所有未失效的会话
+func revokeUserSessions(db *gorm.DB, userID uuid.UUID, reason string) error {
+	return db.Model(&models.AuthSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+// logLogin 记录登录尝试。登录接口不经过认证和审计中间件，因此直接写入操作日志；
+// 用户不存在时 UserID 记为空 UUID，用户名取请求中的值
+func (h *AdminHandler) logLogin(c *gin.Context, user *models.User, username, details, status string) {
+	logEntry := models.OperationLog{
+		Username:  truncate(username, 50),
+		UserRole:  "unknown",
+		Action:    "Login",
+		Resource:  "Auth",
+		Details:   details,
+		Status:    status,
+		IP:        c.ClientIP(),
+		UserAgent: truncate(c.Request.UserAgent(), 255),
+		Method:    c.Request.Method,
+		Path:      c.FullPath(),
+	}
+	if user != nil {
+		logEntry.UserID = user.ID
+		logEntry.UserRole = user.Role
+		logEntry.ResourceID = user.ID.String()
+	}
+	if err := h.db.Create(&logEntry).Error; err != nil {
+		log.Printf("写入登录日志失败: %v", err)
+	}
+}
+
+// truncate 按字节截断字符串，避免超出字段长度
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对（旧会话随即吊销）
+// POST /api/v1/admin/refresh
+func (h *AdminHandler) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	var session models.AuthSession
+	if err := h.db.Where("refresh_token_hash = ?", auth.HashToken(req.RefreshToken)).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, http.StatusUnauthorized, "刷新令牌无效")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+
+	if session.RevokedAt != nil {
+		// 已轮换过的刷新令牌被再次使用，可能已泄露，吊销该用户全部会话
+		if session.RevokeReason == revokeReasonRotated {
+			revokeUserSessions(h.db, session.UserID, revokeReasonRotated)
+		}
+		response.Error(c, http.StatusUnauthorized, "刷新令牌已失效")
+		return
+	}
+	if time.Now().After(session.RefreshExpiresAt) {
+		response.Error(c, http.StatusUnauthorized, "刷新令牌已过期")
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", session.UserID).First(&user).Error; err != nil {
+		response.Error(c, http.StatusUnauthorized, "用户不存在")
+		return
+	}
+	if user.Status == 0 || user.Role == "user" {
+		revokeUserSessions(h.db, user.ID, revokeReasonDisabled)
+		response.Error(c, http.StatusUnauthorized, "账号已被禁用或无管理员权限")
+		return
+	}
+
+	var result gin.H
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证同一刷新令牌并发使用时只有一次成功
+		res := tx.Model(&models.AuthSession{}).
+			Where("id = ? AND revoked_at IS NULL", session.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": revokeReasonRotated})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var err error
+		result, err = issueSession(tx, c, &user)
+		return err
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, http.StatusUnauthorized, "刷新令牌已失效")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "刷新令牌失败")
+		return
+	}
+
+	response.Success(c, result, "刷新成功")
+}
+
+// Logout 注销当前会话
+// POST /api/v1/admin/logout
+func (h *AdminHandler) Logout(c *gin.Context) {
+	jti, _ := c.Get("tokenJTI")
+
+	if err := h.db.Model(&models.AuthSession{}).
+		Where("jti = ? AND revoked_at IS NULL", jti).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": revokeReasonLogout}).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "注销失败")
+		return
+	}
+
+	response.Success(c, nil, "已退出登录")
+}
+
+// ChangePassword 修改当前登录账号的密码，成功后吊销旧会话并返回新的令牌对
+// POST /api/v1/admin/change-password
+func (h *AdminHandler) ChangePassword(c *gin.Context) {
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+	if len(req.NewPassword) < 8 {
+		response.Error(c, http.StatusBadRequest, "新密码长度不能少于8位")
+		return
+	}
+	if req.NewPassword == req.OldPassword || req.NewPassword == auth.DefaultAdminPassword {
+		response.Error(c, http.StatusBadRequest, "新密码不能与旧密码或默认密码相同")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)); err != nil {
+		h.logOperation(c, "ChangePassword", "User", user.ID.String(), "旧密码错误", "Failure")
+		response.Error(c, http.StatusBadRequest, "旧密码错误")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "密码哈希失败")
+		return
+	}
+
+	var result gin.H
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password_hash":        string(hashedPassword),
+			"must_change_password": false,
+		}).Error; err != nil {
+			return err
+		}
+		if err := revokeUserSessions(tx, user.ID, revokeReasonPasswordChanged); err != nil {
+			return err
+		}
+		user.MustChangePassword = false
+		var err error
+		result, err = issueSession(tx, c, &user)
+		return err
+	})
+	if err != nil {
+		h.logOperation(c, "ChangePassword", "User", user.ID.String(), "修改密码失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "修改密码失败")
+		return
+	}
+
+	h.logOperation(c, "ChangePassword", "User", user.ID.String(), "修改密码成功", "Success")
+	response.Success(c, result, "密码修改成功")
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"fluent-life-admin-api/internal/export"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// trainingTypeLabels 训练类型的中文名称
+var trainingTypeLabels = map[string]string{
+	"meditation": "冥想",
+	"airflow":    "气流练习",
+	"exposure":   "脱敏练习",
+	"practice":   "朗读练习",
+}
+
+// exportFunc 按已确定的筛选条件写出导出文件，progress 在每批写出后调用
+type exportFunc func(w io.Writer, format string, progress func(written int) error) (int, error)
+
+// exportSource 一个可导出的列表。同步导出接口和后台导出任务共用
+type exportSource struct {
+	// Name 文件名前缀，同时是后台任务类型 export.<Name> 的后缀
+	Name  string
+	Label string
+	// Permission 导出需要的权限，与同步导出接口一致
+	Permission string
+	// build 按列表筛选参数构造导出，参数无效时返回错误提示
+	build func(h *AdminHandler, q url.Values) (exportFunc, string)
+}
+
+var exportSources = []exportSource{
+	{Name: "users", Label: "用户", Permission: "user:export", build: (*AdminHandler).usersExport},
+	{Name: "training-records", Label: "训练记录", Permission: "training:read", build: (*AdminHandler).trainingRecordsExport},
+	{Name: "posts", Label: "帖子", Permission: "post:read", build: (*AdminHandler).postsExport},
+	{Name: "comments", Label: "评论", Permission: "comment:read", build: (*AdminHandler).commentsExport},
+	{Name: "feedback", Label: "反馈", Permission: "feedback:read", build: (*AdminHandler).feedbackExport},
+	{Name: "operation-logs", Label: "操作日志", Permission: "log:read", build: (*AdminHandler).operationLogsExport},
+	{Name: "random-match", Label: "随机匹配记录", Permission: "training:read", build: (*AdminHandler).randomMatchExport},
+}
+
+// findExportSource 按名称查找可导出的列表
+func findExportSource(name string) (exportSource, bool) {
+	for _, src := range exportSources {
+		if src.Name == name {
+			return src, true
+		}
+	}
+	return exportSource{}, false
+}
+
+// ExportUsers 导出用户列表，筛选参数与用户列表相同
+// GET /api/v1/admin/users/export?format=csv|xlsx
+func (h *AdminHandler) ExportUsers(c *gin.Context) { h.serveExport(c, "users") }
+
+// ExportTrainingRecords 导出训练记录，data 中的每个字段单独成列
+// GET /api/v1/admin/training/records/export?format=csv|xlsx
+func (h *AdminHandler) ExportTrainingRecords(c *gin.Context) { h.serveExport(c, "training-records") }
+
+// ExportPosts 导出帖子列表
+// GET /api/v1/admin/posts/export?format=csv|xlsx
+func (h *AdminHandler) ExportPosts(c *gin.Context) { h.serveExport(c, "posts") }
+
+// ExportComments 导出评论列表
+// GET /api/v1/admin/comments/export?format=csv|xlsx
+func (h *AdminHandler) ExportComments(c *gin.Context) { h.serveExport(c, "comments") }
+
+// ExportFeedback 导出反馈列表
+// GET /api/v1/admin/feedback/export?format=csv|xlsx
+func (h *AdminHandler) ExportFeedback(c *gin.Context) { h.serveExport(c, "feedback") }
+
+// ExportOperationLogs 导出操作日志，变更快照写为 JSON
+// GET /api/v1/admin/operation-logs/export?format=csv|xlsx
+func (h *AdminHandler) ExportOperationLogs(c *gin.Context) { h.serveExport(c, "operation-logs") }
+
+// ExportRandomMatchRecords 导出随机匹配记录
+// GET /api/v1/admin/random-match/export?format=csv|xlsx
+func (h *AdminHandler) ExportRandomMatchRecords(c *gin.Context) { h.serveExport(c, "random-match") }
+
+// serveExport 按 format 参数（csv 或 xlsx，默认 csv）流式写出导出文件；async=true 时提交后台导出任务
+func (h *AdminHandler) serveExport(c *gin.Context, name string) {
+	src, _ := findExportSource(name)
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "不支持的导出格式，可选 csv、xlsx")
+		return
+	}
+	params := c.Request.URL.Query()
+	params.Del("format")
+	params.Del("async")
+	write, msg := src.build(h, params)
+	if msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if c.Query("async") == "true" {
+		h.submitJob(c, exportJobPrefix+src.Name, models.JSONB{"format": format, "query": params.Encode()})
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", export.ContentType(format))
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName(src.Name, format, time.Now())))
+	header.Set("Cache-Control", "no-store")
+
+	rows, err := write(c.Writer, format, nil)
+	if err == nil {
+		return
+	}
+	if !c.Writer.Written() {
+		header.Del("Content-Type")
+		header.Del("Content-Disposition")
+		response.Error(c, http.StatusInternalServerError, "导出失败")
+		return
+	}
+	// 文件已经开始传输，无法再返回错误响应，只能中断并记录日志
+	log.Printf("导出 %s 中断，已写出 %d 行: %v", src.Name, rows, err)
+}
+
+// streamTable 把导出定义包装为 exportFunc
+func streamTable[T any](query *gorm.DB, table export.Table[T]) exportFunc {
+	return func(w io.Writer, format string, progress func(written int) error) (int, error) {
+		table.Progress = progress
+		return export.Stream(w, query, table, format)
+	}
+}
+
+func (h *AdminHandler) usersExport(q url.Values) (exportFunc, string) {
+	return streamTable(h.userListQuery(q).Order("created_at DESC"), export.Table[models.User]{
+		Name: "users",
+		Columns: []export.Column[models.User]{
+			{Header: "用户ID", Value: func(u *models.User) string { return u.ID.String() }},
+			{Header: "用户名", Value: func(u *models.User) string { return u.Username }},
+			{Header: "邮箱", Value: func(u *models.User) string { return export.String(u.Email) }},
+			{Header: "手机号", Value: func(u *models.User) string { return export.String(u.Phone) }},
+			{Header: "性别", Value: func(u *models.User) string { return export.String(u.Gender) }},
+			{Header: "角色", Value: func(u *models.User) string { return u.Role }},
+			{Header: "状态", Value: func(u *models.User) string {
+				if u.Status == 1 {
+					return "正常"
+				}
+				return "禁用"
+			}},
+			{Header: "两步验证", Value: func(u *models.User) string { return export.Bool(u.TOTPEnabled) }},
+			{Header: "注册时间", Value: func(u *models.User) string { return export.Time(u.CreatedAt) }},
+			{Header: "最近登录时间", Value: func(u *models.User) string { return export.TimePtr(u.LastLoginAt) }},
+		},
+	}), ""
+}
+
+func (h *AdminHandler) trainingRecordsExport(q url.Values) (exportFunc, string) {
+	return func(w io.Writer, format string, progress func(written int) error) (int, error) {
+		// data 的字段在写出表头前确定
+		keys, err := export.JSONKeys(h.db, h.trainingRecordListQuery(q), "data")
+		if err != nil {
+			return 0, err
+		}
+
+		names := map[uuid.UUID]string{}
+		columns := []export.Column[models.TrainingRecord]{
+			{Header: "记录ID", Value: func(r *models.TrainingRecord) string { return r.ID.String() }},
+			{Header: "用户ID", Value: func(r *models.TrainingRecord) string { return r.UserID.String() }},
+			{Header: "用户名", Value: func(r *models.TrainingRecord) string { return names[r.UserID] }},
+			{Header: "类型", Value: func(r *models.TrainingRecord) string {
+				if label, ok := trainingTypeLabels[r.Type]; ok {
+					return label
+				}
+				return r.Type
+			}},
+			{Header: "时长（秒）", Number: true, Value: func(r *models.TrainingRecord) string { return export.Int(r.Duration) }},
+			{Header: "训练时间", Value: func(r *models.TrainingRecord) string { return export.Time(r.Timestamp) }},
+			{Header: "创建时间", Value: func(r *models.TrainingRecord) string { return export.Time(r.CreatedAt) }},
+		}
+		columns = append(columns, export.JSONColumns("数据", keys, func(r *models.TrainingRecord) map[string]interface{} { return r.Data })...)
+
+		return streamTable(h.trainingRecordListQuery(q).Order("created_at DESC"), export.Table[models.TrainingRecord]{
+			Name:    "training-records",
+			Columns: columns,
+			Prepare: func(batch []models.TrainingRecord) error {
+				ids := make([]uuid.UUID, len(batch))
+				for i := range batch {
+					ids[i] = batch[i].UserID
+				}
+				return h.loadUsernames(names, ids)
+			},
+		})(w, format, progress)
+	}, ""
+}
+
+func (h *AdminHandler) postsExport(q url.Values) (exportFunc, string) {
+	names := map[uuid.UUID]string{}
+	return streamTable(h.postListQuery(q).Order("created_at DESC"), export.Table[models.Post]{
+		Name: "posts",
+		Columns: []export.Column[models.Post]{
+			{Header: "帖子ID", Value: func(p *models.Post) string { return p.ID.String() }},
+			{Header: "用户ID", Value: func(p *models.Post) string { return p.UserID.String() }},
+			{Header: "用户名", Value: func(p *models.Post) string { return names[p.UserID] }},
+			{Header: "内容", Value: func(p *models.Post) string { return p.Content }},
+			{Header: "标签", Value: func(p *models.Post) string { return p.Tag }},
+			{Header: "点赞数", Number: true, Value: func(p *models.Post) string { return export.Int(p.LikesCount) }},
+			{Header: "评论数", Number: true, Value: func(p *models.Post) string { return export.Int(p.CommentsCount) }},
+			{Header: "审核状态", Value: func(p *models.Post) string { return p.ModerationStatus }},
+			{Header: "风险分", Number: true, Value: func(p *models.Post) string { return export.Int(p.RiskScore) }},
+			{Header: "审核原因", Value: func(p *models.Post) string { return moderationReasonLabel(p.ModerationReason) }},
+			{Header: "发布时间", Value: func(p *models.Post) string { return export.Time(p.CreatedAt) }},
+		},
+		Prepare: func(batch []models.Post) error {
+			ids := make([]uuid.UUID, len(batch))
+			for i := range batch {
+				ids[i] = batch[i].UserID
+			}
+			return h.loadUsernames(names, ids)
+		},
+	}), ""
+}
+
+func (h *AdminHandler) commentsExport(q url.Values) (exportFunc, string) {
+	names := map[uuid.UUID]string{}
+	return streamTable(h.commentListQuery(q).Order("created_at DESC"), export.Table[models.Comment]{
+		Name: "comments",
+		Columns: []export.Column[models.Comment]{
+			{Header: "评论ID", Value: func(m *models.Comment) string { return m.ID.String() }},
+			{Header: "帖子ID", Value: func(m *models.Comment) string { return m.PostID.String() }},
+			{Header: "用户ID", Value: func(m *models.Comment) string { return m.UserID.String() }},
+			{Header: "用户名", Value: func(m *models.Comment) string { return names[m.UserID] }},
+			{Header: "内容", Value: func(m *models.Comment) string { return m.Content }},
+			{Header: "点赞数", Number: true, Value: func(m *models.Comment) string { return export.Int(m.LikesCount) }},
+			{Header: "审核状态", Value: func(m *models.Comment) string { return m.ModerationStatus }},
+			{Header: "风险分", Number: true, Value: func(m *models.Comment) string { return export.Int(m.RiskScore) }},
+			{Header: "审核原因", Value: func(m *models.Comment) string { return moderationReasonLabel(m.ModerationReason) }},
+			{Header: "发布时间", Value: func(m *models.Comment) string { return export.Time(m.CreatedAt) }},
+		},
+		Prepare: func(batch []models.Comment) error {
+			ids := make([]uuid.UUID, len(batch))
+			for i := range batch {
+				ids[i] = batch[i].UserID
+			}
+			return h.loadUsernames(names, ids)
+		},
+	}), ""
+}
+
+func (h *AdminHandler) feedbackExport(q url.Values) (exportFunc, string) {
+	names := map[uuid.UUID]string{}
+	return streamTable(h.feedbackListQuery(q).Order("created_at DESC"), export.Table[models.Feedback]{
+		Name: "feedback",
+		Columns: []export.Column[models.Feedback]{
+			{Header: "反馈ID", Value: func(f *models.Feedback) string { return f.ID.String() }},
+			{Header: "用户ID", Value: func(f *models.Feedback) string { return f.UserID.String() }},
+			{Header: "用户名", Value: func(f *models.Feedback) string { return names[f.UserID] }},
+			{Header: "类型", Value: func(f *models.Feedback) string { return f.Type }},
+			{Header: "状态", Value: func(f *models.Feedback) string { return f.Status }},
+			{Header: "内容", Value: func(f *models.Feedback) string { return f.Content }},
+			{Header: "回复", Value: func(f *models.Feedback) string { return export.String(f.Response) }},
+			{Header: "提交时间", Value: func(f *models.Feedback) string { return export.Time(f.CreatedAt) }},
+			{Header: "更新时间", Value: func(f *models.Feedback) string { return export.Time(f.UpdatedAt) }},
+		},
+		Prepare: func(batch []models.Feedback) error {
+			ids := make([]uuid.UUID, len(batch))
+			for i := range batch {
+				ids[i] = batch[i].UserID
+			}
+			return h.loadUsernames(names, ids)
+		},
+	}), ""
+}
+
+func (h *AdminHandler) operationLogsExport(q url.Values) (exportFunc, string) {
+	query, msg := h.operationLogListQuery(q)
+	if msg != "" {
+		return nil, msg
+	}
+	return streamTable(query.Order("created_at DESC"), export.Table[models.OperationLog]{
+		Name: "operation-logs",
+		Columns: []export.Column[models.OperationLog]{
+			{Header: "日志ID", Value: func(l *models.OperationLog) string { return l.ID.String() }},
+			{Header: "时间", Value: func(l *models.OperationLog) string { return export.Time(l.CreatedAt) }},
+			{Header: "管理员", Value: func(l *models.OperationLog) string { return l.Username }},
+			{Header: "管理员ID", Value: func(l *models.OperationLog) string { return l.UserID.String() }},
+			{Header: "角色", Value: func(l *models.OperationLog) string { return l.UserRole }},
+			{Header: "操作", Value: func(l *models.OperationLog) string { return l.Action }},
+			{Header: "资源", Value: func(l *models.OperationLog) string { return l.Resource }},
+			{Header: "资源ID", Value: func(l *models.OperationLog) string { return l.ResourceID }},
+			{Header: "结果", Value: func(l *models.OperationLog) string { return l.Status }},
+			{Header: "描述", Value: func(l *models.OperationLog) string { return l.Details }},
+			{Header: "请求方法", Value: func(l *models.OperationLog) string { return l.Method }},
+			{Header: "路由", Value: func(l *models.OperationLog) string { return l.Path }},
+			{Header: "IP", Value: func(l *models.OperationLog) string { return l.IP }},
+			{Header: "User-Agent", Value: func(l *models.OperationLog) string { return l.UserAgent }},
+			{Header: "变更字段", Value: func(l *models.OperationLog) string { return jsonCell(l.Diff) }},
+			{Header: "变更前", Value: func(l *models.OperationLog) string { return jsonCell(l.Before) }},
+			{Header: "变更后", Value: func(l *models.OperationLog) string { return jsonCell(l.After) }},
+		},
+	}), ""
+}
+
+func (h *AdminHandler) randomMatchExport(q url.Values) (exportFunc, string) {
+	names := map[uuid.UUID]string{}
+	return streamTable(h.randomMatchListQuery(q).Order("random_match_records.created_at DESC"), export.Table[models.RandomMatchRecord]{
+		Name: "random-match",
+		Columns: []export.Column[models.RandomMatchRecord]{
+			{Header: "记录ID", Value: func(r *models.RandomMatchRecord) string { return r.ID.String() }},
+			{Header: "用户ID", Value: func(r *models.RandomMatchRecord) string { return r.UserID.String() }},
+			{Header: "用户名", Value: func(r *models.RandomMatchRecord) string { return names[r.UserID] }},
+			{Header: "匹配用户ID", Value: func(r *models.RandomMatchRecord) string {
+				if r.MatchedUserID == nil {
+					return ""
+				}
+				return r.MatchedUserID.String()
+			}},
+			{Header: "匹配用户名", Value: func(r *models.RandomMatchRecord) string {
+				if r.MatchedUserID == nil {
+					return ""
+				}
+				return names[*r.MatchedUserID]
+			}},
+			{Header: "状态", Value: func(r *models.RandomMatchRecord) string { return r.Status }},
+			{Header: "等待时长（秒）", Number: true, Value: func(r *models.RandomMatchRecord) string {
+				if r.WaitSeconds == nil {
+					return ""
+				}
+				return export.Int(*r.WaitSeconds)
+			}},
+			{Header: "匹配时间", Value: func(r *models.RandomMatchRecord) string { return export.TimePtr(r.MatchedAt) }},
+			{Header: "创建时间", Value: func(r *models.RandomMatchRecord) string { return export.Time(r.CreatedAt) }},
+		},
+		Prepare: func(batch []models.RandomMatchRecord) error {
+			ids := make([]uuid.UUID, 0, len(batch)*2)
+			for i := range batch {
+				ids = append(ids, batch[i].UserID)
+				if batch[i].MatchedUserID != nil {
+					ids = append(ids, *batch[i].MatchedUserID)
+				}
+			}
+			return h.loadUsernames(names, ids)
+		},
+	}), ""
+}
+
+// loadUsernames 批量查询当前批次的用户名，已删除的用户记为"已注销用户"。
+// names 只保留当前批次，内存占用不随导出行数增长
+func (h *AdminHandler) loadUsernames(names map[uuid.UUID]string, ids []uuid.UUID) error {
+	clear(names)
+	missing := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := names[id]; !ok {
+			names[id] = "已注销用户"
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	var users []models.User
+	if err := h.db.Select("id", "username").Where("id IN ?", missing).Find(&users).Error; err != nil {
+		return err
+	}
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	return nil
+}
+
+// moderationReasonLabel 审核原因代码对应的中文名称
+func moderationReasonLabel(reason string) string {
+	if label, ok := models.ModerationReasons[reason]; ok {
+		return label
+	}
+	return reason
+}
+
+// jsonCell 把 JSONB 字段写为一个单元格，空值为空
+func jsonCell(doc models.JSONB) string {
+	if len(doc) == 0 {
+		return ""
+	}
+	return export.Value(map[string]interface{}(doc))
+}
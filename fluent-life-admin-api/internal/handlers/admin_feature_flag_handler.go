@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/featureflag"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var featureFlagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.]{0,99}$`)
+
+type AdminFeatureFlagHandler struct {
+	db *gorm.DB
+}
+
+func NewAdminFeatureFlagHandler(db *gorm.DB) *AdminFeatureFlagHandler {
+	return &AdminFeatureFlagHandler{db: db}
+}
+
+// featureFlagRequest 创建和修改开关的参数，修改时只更新传入的字段
+type featureFlagRequest struct {
+	Key            *string   `json:"key"`
+	Name           *string   `json:"name"`
+	Description    *string   `json:"description"`
+	Enabled        *bool     `json:"enabled"`
+	RolloutPercent *int      `json:"rollout_percent"`
+	AllowUsers     *[]string `json:"allow_users"`
+	DenyUsers      *[]string `json:"deny_users"`
+	Languages      *[]string `json:"languages"`
+	MinAppVersion  *string   `json:"min_app_version"`
+	MaxAppVersion  *string   `json:"max_app_version"`
+}
+
+// GetFeatureFlags 获取全部功能开关
+// GET /api/v1/admin/feature-flags
+func (h *AdminFeatureFlagHandler) GetFeatureFlags(c *gin.Context) {
+	query := h.db.Model(&models.FeatureFlag{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("key ILIKE ? OR name ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+
+	var flags []models.FeatureFlag
+	if err := query.Order("key ASC").Find(&flags).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取功能开关失败")
+		return
+	}
+	response.Success(c, gin.H{"flags": flags, "total": len(flags)}, "获取成功")
+}
+
+// GetFeatureFlag 获取功能开关详情
+// GET /api/v1/admin/feature-flags/:id
+func (h *AdminFeatureFlagHandler) GetFeatureFlag(c *gin.Context) {
+	flag, ok := h.loadFlag(c)
+	if !ok {
+		return
+	}
+	response.Success(c, flag, "获取成功")
+}
+
+// CreateFeatureFlag 创建功能开关，不传 rollout_percent 时为 100（开启后对所有人生效）
+// POST /api/v1/admin/feature-flags
+func (h *AdminFeatureFlagHandler) CreateFeatureFlag(c *gin.Context) {
+	var req featureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+	if req.Key == nil || req.Name == nil {
+		response.Error(c, http.StatusBadRequest, "key 和 name 为必填项")
+		return
+	}
+
+	flag := models.FeatureFlag{
+		RolloutPercent: 100,
+		AllowUsers:     models.StringList{},
+		DenyUsers:      models.StringList{},
+		Languages:      models.StringList{},
+	}
+	if msg := applyFeatureFlagRequest(&flag, &req); msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.FeatureFlag{}).Where("key = ?", flag.Key).Count(&count)
+	if count > 0 {
+		response.Error(c, http.StatusBadRequest, "开关键已存在")
+		return
+	}
+
+	if err := h.db.Create(&flag).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "创建功能开关失败")
+		return
+	}
+
+	audit.Annotate(c, "CreateFeatureFlag", "FeatureFlag", flag.ID.String(), "创建功能开关 "+describeFeatureFlag(&flag), "Success")
+	response.Success(c, flag, "创建成功")
+}
+
+// UpdateFeatureFlag 修改功能开关
+// PUT /api/v1/admin/feature-flags/:id
+func (h *AdminFeatureFlagHandler) UpdateFeatureFlag(c *gin.Context) {
+	var req featureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+	flag, ok := h.loadFlag(c)
+	if !ok {
+		return
+	}
+
+	oldKey := flag.Key
+	if msg := applyFeatureFlagRequest(flag, &req); msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+	if flag.Key != oldKey {
+		var count int64
+		h.db.Model(&models.FeatureFlag{}).Where("key = ? AND id <> ?", flag.Key, flag.ID).Count(&count)
+		if count > 0 {
+			response.Error(c, http.StatusBadRequest, "开关键已存在")
+			return
+		}
+	}
+
+	if err := h.db.Save(flag).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "更新功能开关失败")
+		return
+	}
+
+	audit.Annotate(c, "UpdateFeatureFlag", "FeatureFlag", flag.ID.String(), "修改功能开关 "+describeFeatureFlag(flag), "Success")
+	response.Success(c, flag, "更新成功")
+}
+
+// DeleteFeatureFlag 删除功能开关，客户端之后取不到该开关，按各自的默认值处理
+// DELETE /api/v1/admin/feature-flags/:id
+func (h *AdminFeatureFlagHandler) DeleteFeatureFlag(c *gin.Context) {
+	flag, ok := h.loadFlag(c)
+	if !ok {
+		return
+	}
+	if err := h.db.Delete(flag).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "删除功能开关失败")
+		return
+	}
+
+	audit.Annotate(c, "DeleteFeatureFlag", "FeatureFlag", flag.ID.String(), "删除功能开关 "+flag.Key, "Success")
+	response.Success(c, nil, "删除成功")
+}
+
+// EvaluateFeatureFlags 对指定用户求值全部开关，返回每个开关的结果和原因，用于排查
+// GET /api/v1/admin/feature-flags/evaluate?user_id=...&app_version=...&language=...
+func (h *AdminFeatureFlagHandler) EvaluateFeatureFlags(c *gin.Context) {
+	userID, err := uuid.Parse(c.Query("user_id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	results, err := featureflag.EvaluateAll(h.db, featureflag.Subject{
+		UserID:     userID,
+		Language:   c.Query("language"),
+		AppVersion: c.Query("app_version"),
+	})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "功能开关求值失败")
+		return
+	}
+	response.Success(c, gin.H{"user_id": userID, "flags": results}, "获取成功")
+}
+
+// GetUserFeatureFlags 主站后端获取指定用户的功能开关，返回 key 到是否开启的映射，需要服务凭证；
+// 客户端版本通过 app_version 参数或 X-App-Version 请求头传入
+// GET /api/v1/feature-flags?user_id=...&app_version=...&language=...
+func (h *AdminFeatureFlagHandler) GetUserFeatureFlags(c *gin.Context) {
+	userID, err := uuid.Parse(c.Query("user_id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	appVersion := c.Query("app_version")
+	if appVersion == "" {
+		appVersion = c.GetHeader("X-App-Version")
+	}
+
+	results, err := featureflag.EvaluateAll(h.db, featureflag.Subject{
+		UserID:     userID,
+		Language:   c.Query("language"),
+		AppVersion: appVersion,
+	})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取功能开关失败")
+		return
+	}
+	flags := make(map[string]bool, len(results))
+	for _, r := range results {
+		flags[r.Key] = r.Enabled
+	}
+	response.Success(c, gin.H{"user_id": userID, "flags": flags}, "获取成功")
+}
+
+// loadFlag 按路径参数 id 加载开关，失败时已写入响应
+func (h *AdminFeatureFlagHandler) loadFlag(c *gin.Context) (*models.FeatureFlag, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的开关ID")
+		return nil, false
+	}
+	var flag models.FeatureFlag
+	if err := h.db.Where("id = ?", id).First(&flag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "功能开关不存在")
+		} else {
+			response.Error(c, http.StatusInternalServerError, "获取功能开关失败")
+		}
+		return nil, false
+	}
+	return &flag, true
+}
+
+// applyFeatureFlagRequest 把请求中传入的字段写入开关并校验，返回错误提示
+func applyFeatureFlagRequest(flag *models.FeatureFlag, req *featureFlagRequest) string {
+	if req.Key != nil {
+		key := strings.TrimSpace(*req.Key)
+		if !featureFlagKeyPattern.MatchString(key) {
+			return "key 只能包含小写字母、数字、下划线和点，以字母开头，最长 100 个字符"
+		}
+		flag.Key = key
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len([]rune(name)) > 100 {
+			return "name 不能为空且最长 100 个字符"
+		}
+		flag.Name = name
+	}
+	if req.Description != nil {
+		if len([]rune(*req.Description)) > 255 {
+			return "description 最长 255 个字符"
+		}
+		flag.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	if req.RolloutPercent != nil {
+		if *req.RolloutPercent < 0 || *req.RolloutPercent > 100 {
+			return "rollout_percent 应在 0-100 之间"
+		}
+		flag.RolloutPercent = *req.RolloutPercent
+	}
+	for _, list := range []struct {
+		name string
+		src  *[]string
+		dst  *models.StringList
+	}{
+		{"allow_users", req.AllowUsers, &flag.AllowUsers},
+		{"deny_users", req.DenyUsers, &flag.DenyUsers},
+	} {
+		if list.src == nil {
+			continue
+		}
+		ids, msg := normalizeUserIDs(list.name, *list.src)
+		if msg != "" {
+			return msg
+		}
+		*list.dst = ids
+	}
+	if req.Languages != nil {
+		languages := models.StringList{}
+		for _, l := range *req.Languages {
+			if l = strings.TrimSpace(l); l != "" && len(l) <= 10 {
+				languages = append(languages, l)
+			} else {
+				return fmt.Sprintf("无效的语言 %q", l)
+			}
+		}
+		flag.Languages = languages
+	}
+	if req.MinAppVersion != nil {
+		flag.MinAppVersion = strings.TrimSpace(*req.MinAppVersion)
+	}
+	if req.MaxAppVersion != nil {
+		flag.MaxAppVersion = strings.TrimSpace(*req.MaxAppVersion)
+	}
+
+	var lo, hi [3]int
+	var err error
+	if flag.MinAppVersion != "" {
+		if lo, err = featureflag.ParseVersion(flag.MinAppVersion); err != nil {
+			return "min_app_version 格式应为 x.y.z"
+		}
+	}
+	if flag.MaxAppVersion != "" {
+		if hi, err = featureflag.ParseVersion(flag.MaxAppVersion); err != nil {
+			return "max_app_version 格式应为 x.y.z"
+		}
+		if flag.MinAppVersion != "" && featureflag.CompareVersions(hi, lo) < 0 {
+			return "max_app_version 不能小于 min_app_version"
+		}
+	}
+	return ""
+}
+
+// normalizeUserIDs 校验并去重用户ID列表，最多 1000 个
+func normalizeUserIDs(field string, ids []string) (models.StringList, string) {
+	if len(ids) > 1000 {
+		return nil, field + " 最多 1000 个用户"
+	}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	out := make(models.StringList, 0, len(ids))
+	for _, s := range ids {
+		id, err := uuid.Parse(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Sprintf("%s 中的 %q 不是有效的用户ID", field, s)
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id.String())
+		}
+	}
+	return out, ""
+}
+
+// describeFeatureFlag 审计日志中的开关摘要
+func describeFeatureFlag(f *models.FeatureFlag) string {
+	state := "关闭"
+	if f.Enabled {
+		state = "开启"
+	}
+	return fmt.Sprintf("%s（%s，灰度 %d%%，白名单 %d 人，黑名单 %d 人）",
+		f.Key, state, f.RolloutPercent, len(f.AllowUsers), len(f.DenyUsers))
+}
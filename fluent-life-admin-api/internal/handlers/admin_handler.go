@@ -1,55 +1,60 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"fluent-life-admin-api/internal/analytics"
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/jobs"
 	"fluent-life-admin-api/internal/models"
-	"fluent-life-admin-api/pkg/auth" // Import auth package
+	"fluent-life-admin-api/internal/permission"
+	"fluent-life-admin-api/internal/recyclebin"
+	"fluent-life-admin-api/internal/sanction"
+	"fluent-life-admin-api/internal/settings"
+	"fluent-life-admin-api/internal/voicetype"
+	"fluent-life-admin-api/pkg/auth"
 	"fluent-life-admin-api/pkg/response"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt" // Add bcrypt for password hashing
 	"gorm.io/gorm"
 )
 
 type AdminHandler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	loginGuard *auth.LoginGuard
+	settings   *settings.Store
+	// jobs 后台任务队列，由 RegisterJobs 设置；为空时 async=true 的请求返回 503
+	jobs        *jobs.Queue
+	jobFilesDir string
 }
 
-func NewAdminHandler(db *gorm.DB) *AdminHandler {
-	return &AdminHandler{db: db}
+func NewAdminHandler(db *gorm.DB, settingStore *settings.Store) *AdminHandler {
+	return &AdminHandler{
+		db:         db,
+		loginGuard: auth.NewLoginGuard(auth.DefaultUsernamePolicy, auth.DefaultIPPolicy),
+		settings:   settingStore,
+	}
 }
 
-// logOperation 记录管理员操作日志
+// logOperation 记录管理员操作日志。日志由 middleware.Audit 在请求结束时统一写入，
+// 这里只为当前请求的审计条目补充操作名称、资源和描述
 func (h *AdminHandler) logOperation(c *gin.Context, action, resource, resourceID, details, status string) {
-	userID, _ := c.Get("userID")
-	username, _ := c.Get("username")
-	userRole, _ := c.Get("userRole")
-
-	logEntry := models.OperationLog{
-		UserID:     userID.(uuid.UUID),
-		Username:   username.(string),
-		UserRole:   userRole.(string),
-		Action:     action,
-		Resource:   resource,
-		ResourceID: resourceID,
-		Details:    details,
-		Status:     status,
-	}
-	h.db.Create(&logEntry) // Log asynchronously, errors here should not block main operation
+	audit.Annotate(c, action, resource, resourceID, details, status)
 }
 
 // GetRandomMatchRecords 获取 1v1 随机匹配记录
 func (h *AdminHandler) GetRandomMatchRecords(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	status := c.Query("status")
-	keyword := c.Query("keyword")
 
 	if page < 1 {
 		page = 1
@@ -58,21 +63,7 @@ func (h *AdminHandler) GetRandomMatchRecords(c *gin.Context) {
 		pageSize = 20
 	}
 
-	query := h.db.Model(&models.RandomMatchRecord{}).Preload("User").Preload("MatchedUser")
-
-	// 按用户ID筛选
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	if status != "" {
-		query = query.Where("status = ?", status)
-	}
-	if keyword != "" {
-		kw := "%" + strings.ToLower(keyword) + "%"
-		query = query.Joins("LEFT JOIN users u ON u.id = random_match_records.user_id").
-			Where("LOWER(u.username) LIKE ?", kw)
-	}
+	query := h.randomMatchListQuery(c.Request.URL.Query()).Preload("User").Preload("MatchedUser")
 
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -81,7 +72,7 @@ func (h *AdminHandler) GetRandomMatchRecords(c *gin.Context) {
 	}
 
 	var records []models.RandomMatchRecord
-	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error; err != nil {
+	if err := query.Order("random_match_records.created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error; err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取匹配记录失败")
 		return
 	}
@@ -94,17 +85,75 @@ func (h *AdminHandler) GetRandomMatchRecords(c *gin.Context) {
 	}, "获取成功")
 }
 
-// isValidRole 检查角色是否有效
-func isValidRole(role string) bool {
-	switch role {
-	case "user", "admin", "super_admin":
+// randomMatchListQuery 随机匹配记录列表和导出共用的筛选条件。按用户名搜索时连接 users 表，列名需带表名
+func (h *AdminHandler) randomMatchListQuery(q url.Values) *gorm.DB {
+	query := h.db.Model(&models.RandomMatchRecord{})
+
+	// 按用户ID筛选
+	if userID := q.Get("user_id"); userID != "" {
+		query = query.Where("random_match_records.user_id = ?", userID)
+	}
+
+	if status := q.Get("status"); status != "" {
+		query = query.Where("random_match_records.status = ?", status)
+	}
+	if keyword := q.Get("keyword"); keyword != "" {
+		kw := "%" + strings.ToLower(keyword) + "%"
+		query = query.Joins("LEFT JOIN users u ON u.id = random_match_records.user_id").
+			Where("LOWER(u.username) LIKE ?", kw)
+	}
+	return query
+}
+
+// isValidRole 检查角色是否在 roles 表中。普通用户（users.role 的默认值）和超级管理员
+// 在 roles 表尚未初始化时也视为有效，与权限校验保持一致
+func (h *AdminHandler) isValidRole(role string) bool {
+	if role == "user" || role == permission.SuperAdminRole {
 		return true
-	default:
-		return false
 	}
+	var count int64
+	h.db.Model(&models.Role{}).Where("code = ?", role).Count(&count)
+	return count > 0
+}
+
+// userConflict 检查用户名、邮箱、手机号是否已被其他用户使用，返回冲突提示，未冲突时返回空字符串。
+// 三者都是唯一索引，回收站中的用户恢复前仍然占用，因此查询包括已软删除的用户
+func (h *AdminHandler) userConflict(username, email, phone *string, excludeID uuid.UUID) string {
+	checks := []struct {
+		column string
+		value  *string
+		msg    string
+	}{
+		{"username", username, "用户名已存在"},
+		{"email", email, "邮箱已被使用"},
+		{"phone", phone, "手机号已被使用"},
+	}
+	for _, check := range checks {
+		if check.value == nil {
+			continue
+		}
+		var existing models.User
+		query := h.db.Unscoped().Select("id, deleted_at").Where(check.column+" = ?", *check.value)
+		if excludeID != uuid.Nil {
+			query = query.Where("id <> ?", excludeID)
+		}
+		if query.Limit(1).Find(&existing).RowsAffected > 0 {
+			if existing.DeletedAt.Valid {
+				return check.msg + "（属于回收站中的用户）"
+			}
+			return check.msg
+		}
+	}
+	return ""
+}
+
+// isDuplicateKey 是否违反唯一约束，用于并发创建时检查之后仍发生的冲突
+func isDuplicateKey(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
 
-// CreateUser 创建新用户
+// CreateUser 创建新用户，指定 user 以外的角色时需要 permission:write 且不能超出当前管理员的权限
 func (h *AdminHandler) CreateUser(c *gin.Context) {
 	var req struct {
 		Username string  `json:"username" binding:"required"`
@@ -122,15 +171,19 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 	}
 
 	// Validate Role if provided
-	if req.Role != nil && !isValidRole(*req.Role) {
+	if req.Role != nil && !h.isValidRole(*req.Role) {
 		response.Error(c, http.StatusBadRequest, "无效的用户角色")
 		return
 	}
 
-	// 检查用户名是否已存在
-	var existingUser models.User
-	if h.db.Where("username = ?", req.Username).First(&existingUser).Error == nil {
-		response.Error(c, http.StatusConflict, "用户名已存在")
+	// 设置默认角色以外的角色需要有分配该角色的权限
+	if req.Role != nil && *req.Role != "user" && !requireRoleGrant(h.db, c, []string{*req.Role}) {
+		return
+	}
+
+	// 检查用户名、邮箱、手机号是否已存在
+	if msg := h.userConflict(&req.Username, req.Email, req.Phone, uuid.Nil); msg != "" {
+		response.Error(c, http.StatusConflict, msg)
 		return
 	}
 
@@ -157,6 +210,10 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 
 	if err := h.db.Create(&user).Error; err != nil {
 		h.logOperation(c, "CreateUser", "User", "", "创建用户失败: "+err.Error(), "Failure")
+		if isDuplicateKey(err) {
+			response.Error(c, http.StatusConflict, "用户名、邮箱或手机号已存在")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "创建用户失败: "+err.Error())
 		return
 	}
@@ -165,7 +222,7 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 	response.Success(c, user, "用户创建成功")
 }
 
-// UpdateUser 更新用户信息
+// UpdateUser 更新用户信息。修改主角色的要求与 CreateUser 相同，超级管理员账号只能由超级管理员修改
 func (h *AdminHandler) UpdateUser(c *gin.Context) {
 	id := c.Param("id")
 	var req struct {
@@ -184,7 +241,7 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// Validate Role if provided
-	if req.Role != nil && !isValidRole(*req.Role) {
+	if req.Role != nil && !h.isValidRole(*req.Role) {
 		response.Error(c, http.StatusBadRequest, "无效的用户角色")
 		return
 	}
@@ -194,15 +251,24 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		response.Error(c, http.StatusNotFound, "用户不存在")
 		return
 	}
+	if !requireAccountAccess(h.db, c, &user) {
+		return
+	}
+	if req.Role != nil && *req.Role != user.Role && !requireRoleGrant(h.db, c, []string{*req.Role}) {
+		return
+	}
+
+	// 密码、角色变更或禁用账号后需要吊销已签发的令牌
+	revokeReason := ""
+
+	// 检查新的用户名、邮箱、手机号是否已被其他用户使用
+	if msg := h.userConflict(req.Username, req.Email, req.Phone, user.ID); msg != "" {
+		response.Error(c, http.StatusConflict, msg)
+		return
+	}
 
 	// 更新字段
 	if req.Username != nil {
-		// 检查新用户名是否已存在且不属于当前用户
-		var existingUser models.User
-		if h.db.Where("username = ? AND id != ?", *req.Username, id).First(&existingUser).Error == nil {
-			response.Error(c, http.StatusConflict, "用户名已存在")
-			return
-		}
 		user.Username = *req.Username
 	}
 	if req.Email != nil {
@@ -212,12 +278,18 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		user.Phone = req.Phone
 	}
 	if req.Status != nil {
+		if *req.Status == 0 && user.Status != 0 {
+			revokeReason = revokeReasonDisabled
+		}
 		user.Status = *req.Status
 	}
 	if req.Gender != nil {
 		user.Gender = req.Gender
 	}
 	if req.Role != nil { // Update Role field
+		if *req.Role != user.Role {
+			revokeReason = revokeReasonRoleChanged
+		}
 		user.Role = *req.Role
 	}
 	if req.Password != nil && len(*req.Password) >= 6 {
@@ -227,13 +299,33 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 			return
 		}
 		user.PasswordHash = string(hashedPassword)
+		revokeReason = revokeReasonPasswordChanged
 	} else if req.Password != nil && len(*req.Password) < 6 {
 		response.Error(c, http.StatusBadRequest, "密码长度不能少于6位")
 		return
 	}
 
-	if err := h.db.Save(&user).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		// 手动禁用后，封禁结束时不再自动恢复账号
+		if req.Status != nil && *req.Status == 0 {
+			if err := sanction.KeepDisabled(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		if revokeReason != "" {
+			return revokeUserSessions(tx, user.ID, revokeReason)
+		}
+		return nil
+	})
+	if err != nil {
 		h.logOperation(c, "UpdateUser", "User", user.ID.String(), "更新用户失败: "+err.Error(), "Failure")
+		if isDuplicateKey(err) {
+			response.Error(c, http.StatusConflict, "用户名、邮箱或手机号已存在")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "更新用户失败: "+err.Error())
 		return
 	}
@@ -254,10 +346,21 @@ func (h *AdminHandler) Login(c *gin.Context) {
 		return
 	}
 
+	ip := c.ClientIP()
+	if wait := h.loginGuard.RetryAfter(req.Username, ip); wait > 0 {
+		h.logLogin(c, nil, req.Username, "登录被限流，剩余等待 "+wait.Round(time.Second).String(), "Failure")
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds()+0.5)))
+		response.Error(c, http.StatusTooManyRequests, "登录失败次数过多，请在 "+wait.Round(time.Second).String()+" 后重试")
+		return
+	}
+
 	var user models.User
-	// 查找用户，并确保其角色是admin或super_admin
-	if err := h.db.Where("username = ? AND (role = ? OR role = ?)", req.Username, "admin", "super_admin").First(&user).Error; err != nil {
+	// 查找用户，并确保其拥有后台角色（普通用户不能登录后台）
+	if err := h.db.Where("username = ? AND role <> ?", req.Username, "user").First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			// 用户不存在也计入失败次数，避免通过响应差异枚举账号
+			h.loginGuard.Fail(req.Username, ip)
+			h.logLogin(c, nil, req.Username, "用户不存在或无管理员权限", "Failure")
 			response.Error(c, http.StatusUnauthorized, "用户名或密码错误，或无管理员权限")
 			return
 		}
@@ -267,23 +370,38 @@ func (h *AdminHandler) Login(c *gin.Context) {
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		details := "密码错误"
+		if h.loginGuard.Fail(req.Username, ip) {
+			details = "密码错误，账号已被临时锁定"
+		}
+		h.logLogin(c, &user, req.Username, details, "Failure")
 		response.Error(c, http.StatusUnauthorized, "用户名或密码错误，或无管理员权限")
 		return
 	}
 
-	// 生成JWT Token
-	token, err := auth.GenerateToken(user.ID, user.Role)
-	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "生成认证令牌失败")
+	if user.Status == 0 {
+		h.logLogin(c, &user, req.Username, "账号已被禁用", "Failure")
+		response.Error(c, http.StatusForbidden, "账号已被禁用")
 		return
 	}
 
-	response.Success(c, gin.H{
-		"token":    token,
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     user.Role,
-	}, "登录成功")
+	// 已启用两步验证的账号只返回挑战令牌，需调用 /admin/login/2fa 完成登录
+	if user.TOTPEnabled {
+		challenge, expiresAt, err := auth.GenerateChallengeToken(user.ID)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "生成认证令牌失败")
+			return
+		}
+		h.logLogin(c, &user, req.Username, "密码验证通过，等待两步验证", "Success")
+		response.Success(c, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_at":          expiresAt,
+		}, "请输入两步验证码")
+		return
+	}
+
+	h.completeLogin(c, &user)
 }
 
 // 获取用户列表
@@ -295,13 +413,7 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 	var users []models.User
 	var total int64
 
-	query := h.db.Model(&models.User{})
-
-	// 搜索
-	if keyword := c.Query("keyword"); keyword != "" {
-		query = query.Where("username LIKE ? OR email LIKE ? OR phone LIKE ?",
-			"%"+keyword+"%", "%"+keyword+"%", "%"+keyword+"%")
-	}
+	query := h.userListQuery(c.Request.URL.Query())
 
 	query.Count(&total)
 
@@ -318,12 +430,29 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 	}, "获取成功")
 }
 
+// userListQuery 用户列表和导出共用的筛选条件
+func (h *AdminHandler) userListQuery(q url.Values) *gorm.DB {
+	query := h.db.Model(&models.User{})
+
+	// 搜索
+	if keyword := q.Get("keyword"); keyword != "" {
+		query = query.Where("username LIKE ? OR email LIKE ? OR phone LIKE ?",
+			"%"+keyword+"%", "%"+keyword+"%", "%"+keyword+"%")
+	}
+
+	// 按生效中的处罚筛选：sanctioned=true 表示有任意处罚，sanction_type 指定类型
+	if sanctionType := q.Get("sanction_type"); sanctionType != "" || q.Get("sanctioned") == "true" {
+		query = query.Where("id IN (?)", sanction.ActiveUserIDs(h.db, time.Now(), sanctionType))
+	}
+	return query
+}
+
 // 获取用户详情
 func (h *AdminHandler) GetUser(c *gin.Context) {
 	id := c.Param("id")
 
 	var user models.User
-	if err := h.db.Where("id = ?", id).First(&user).Error; err != nil {
+	if err := h.db.Where("id = ?", id).Preload("Roles").First(&user).Error; err != nil {
 		response.Error(c, http.StatusNotFound, "用户不存在")
 		return
 	}
@@ -331,11 +460,34 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 	response.Success(c, user, "获取成功")
 }
 
-// 删除用户
+// 删除用户（软删除，用户的帖子和评论一并移入回收站），超级管理员账号只能由超级管理员删除
 func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
+	var user models.User
+	if err := h.db.Where("id = ?", id).First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+	if !requireAccountAccess(h.db, c, &user) {
+		return
+	}
 
-	if err := h.db.Where("id = ?", id).Delete(&models.User{}).Error; err != nil {
+	if c.Query("async") == "true" {
+		h.submitJob(c, jobDeleteUsers, models.JSONB{"ids": []string{id}})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND revoked_at IS NULL", id).Model(&models.AuthSession{}).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": revokeReasonDeleted}).Error; err != nil {
+			return err
+		}
+		return analytics.Reroll(tx, &models.User{}, []string{id}, func() error {
+			_, err := recyclebin.SoftDeleteUsers(tx, []string{id})
+			return err
+		})
+	})
+	if err != nil {
 		h.logOperation(c, "DeleteUser", "User", id, "删除用户失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除失败")
 		return
@@ -366,10 +518,18 @@ func (h *AdminHandler) CreatePost(c *gin.Context) {
 	// 打印用户ID以验证取值
 	log.Printf("从上下文获取的userID: %v, 类型: %T", userID, userID)
 
+	// 管理员发布的帖子无需再审核
+	moderatorID := userID.(uuid.UUID)
+	now := time.Now()
 	post := models.Post{
 		UserID:  userID.(uuid.UUID),
 		Content: req.Content,
 		Tag:     req.Tag,
+		Moderation: models.Moderation{
+			ModerationStatus: models.ModerationApproved,
+			ModeratedBy:      &moderatorID,
+			ModeratedAt:      &now,
+		},
 	}
 
 	if err := h.db.Create(&post).Error; err != nil {
@@ -401,8 +561,9 @@ func (h *AdminHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	if req.Content != nil {
+	if req.Content != nil && *req.Content != post.Content {
 		post.Content = *req.Content
+		post.ScannedAt = nil // 内容变更后重新做敏感词扫描
 	}
 	if req.Tag != nil {
 		post.Tag = *req.Tag
@@ -420,6 +581,10 @@ func (h *AdminHandler) UpdatePost(c *gin.Context) {
 
 // 删除所有绕口令
 func (h *AdminHandler) DeleteAllTongueTwisters(c *gin.Context) {
+	if c.Query("async") == "true" {
+		h.submitJob(c, jobDeleteAllTongueTwisters, nil)
+		return
+	}
 	if err := h.db.Where("1 = 1").Delete(&models.TongueTwister{}).Error; err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除所有绕口令失败")
 		return
@@ -436,17 +601,7 @@ func (h *AdminHandler) GetPosts(c *gin.Context) {
 	var posts []models.Post
 	var total int64
 
-	query := h.db.Model(&models.Post{}).Preload("User")
-
-	// 按用户ID筛选
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	// 搜索
-	if keyword := c.Query("keyword"); keyword != "" {
-		query = query.Where("content LIKE ?", "%"+keyword+"%")
-	}
+	query := h.postListQuery(c.Request.URL.Query()).Preload("User")
 
 	query.Count(&total)
 
@@ -463,6 +618,27 @@ func (h *AdminHandler) GetPosts(c *gin.Context) {
 	}, "获取成功")
 }
 
+// postListQuery 帖子列表和导出共用的筛选条件
+func (h *AdminHandler) postListQuery(q url.Values) *gorm.DB {
+	query := h.db.Model(&models.Post{})
+
+	// 按用户ID筛选
+	if userID := q.Get("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	// 搜索
+	if keyword := q.Get("keyword"); keyword != "" {
+		query = query.Where("content LIKE ?", "%"+keyword+"%")
+	}
+
+	// 按审核状态筛选
+	if status := q.Get("moderation_status"); status != "" {
+		query = query.Where("moderation_status = ?", status)
+	}
+	return query
+}
+
 // 获取帖子详情
 func (h *AdminHandler) GetPost(c *gin.Context) {
 	id := c.Param("id")
@@ -487,46 +663,36 @@ func (h *AdminHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	tx := h.db.Begin()
-	if tx.Error != nil {
-		response.Error(c, http.StatusInternalServerError, "删除失败")
-		return
-	}
-
-	// 先删除相关的点赞记录
-	if err := tx.Where("post_id IN ?", req.IDs).Delete(&models.PostLike{}).Error; err != nil {
-		tx.Rollback()
-		response.Error(c, http.StatusInternalServerError, "删除相关点赞失败")
-		return
-	}
-
-	// 再删除相关的评论记录
-	if err := tx.Where("post_id IN ?", req.IDs).Delete(&models.Comment{}).Error; err != nil {
-		tx.Rollback()
-		response.Error(c, http.StatusInternalServerError, "删除相关评论失败")
-		return
-	}
-
-	// 再删除相关的收藏记录
-	if err := tx.Where("post_id IN ?", req.IDs).Delete(&models.PostCollection{}).Error; err != nil {
-		tx.Rollback()
-		response.Error(c, http.StatusInternalServerError, "删除相关收藏失败")
-		return
-	}
-
-	// 最后删除帖子
-	if err := tx.Where("id IN ?", req.IDs).Delete(&models.Post{}).Error; err != nil {
-		tx.Rollback()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return softDeletePosts(tx, req.IDs)
+	})
+	if err != nil {
 		h.logOperation(c, "DeletePost", "Post", strings.Join(req.IDs, ","), "删除帖子失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除帖子失败")
 		return
 	}
 
-	tx.Commit()
 	h.logOperation(c, "DeletePost", "Post", strings.Join(req.IDs, ","), "删除帖子成功", "Success")
 	response.Success(c, nil, "删除成功")
 }
 
+// softDeletePosts 软删除帖子及其评论；点赞和收藏保留到彻底清除时再删除，以便恢复
+func softDeletePosts(tx *gorm.DB, ids []string) error {
+	return analytics.Reroll(tx, &models.Post{}, ids, func() error {
+		_, err := recyclebin.SoftDeletePosts(tx, ids)
+		return err
+	})
+}
+
+// deleteWithStats 删除计入每日统计的记录，并重新计算这些记录创建日期以来的每日统计
+func deleteWithStats(db *gorm.DB, model interface{}, ids []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return analytics.Reroll(tx, model, ids, func() error {
+			return tx.Where("id IN ?", ids).Delete(model).Error
+		})
+	})
+}
+
 // 获取房间列表
 func (h *AdminHandler) GetRooms(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -593,32 +759,26 @@ func (h *AdminHandler) DeleteRoom(c *gin.Context) {
 		return
 	}
 
-	tx := h.db.Begin()
-	if tx.Error != nil {
-		response.Error(c, http.StatusInternalServerError, "删除失败")
-		return
-	}
-
-	// 先删除相关的房间成员记录
-	if err := tx.Where("room_id IN ?", req.IDs).Delete(&models.PracticeRoomMember{}).Error; err != nil {
-		tx.Rollback()
-		response.Error(c, http.StatusInternalServerError, "删除相关房间成员失败")
-		return
-	}
-
-	// 最后删除房间
-	if err := tx.Where("id IN ?", req.IDs).Delete(&models.PracticeRoom{}).Error; err != nil {
-		tx.Rollback()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return softDeleteRooms(tx, req.IDs)
+	})
+	if err != nil {
 		h.logOperation(c, "DeleteRoom", "PracticeRoom", strings.Join(req.IDs, ","), "删除房间失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除房间失败")
 		return
 	}
 
-	tx.Commit()
 	h.logOperation(c, "DeleteRoom", "PracticeRoom", strings.Join(req.IDs, ","), "删除房间成功", "Success")
 	response.Success(c, nil, "删除成功")
 }
 
+// softDeleteRooms 软删除房间；成员记录保留到彻底清除时再删除，以便恢复
+func softDeleteRooms(tx *gorm.DB, ids []string) error {
+	return analytics.Reroll(tx, &models.PracticeRoom{}, ids, func() error {
+		return tx.Where("id IN ?", ids).Delete(&models.PracticeRoom{}).Error
+	})
+}
+
 // 关闭/开启房间
 func (h *AdminHandler) ToggleRoom(c *gin.Context) {
 	id := c.Param("id")
@@ -708,8 +868,9 @@ func (h *AdminHandler) UpdateRoom(c *gin.Context) {
 		return
 	}
 
-	if req.Title != nil {
+	if req.Title != nil && *req.Title != room.Title {
 		room.Title = *req.Title
+		room.ScannedAt = nil // 标题或描述变更后重新做敏感词扫描
 	}
 	if req.Theme != nil {
 		room.Theme = *req.Theme
@@ -717,8 +878,9 @@ func (h *AdminHandler) UpdateRoom(c *gin.Context) {
 	if req.Type != nil {
 		room.Type = *req.Type
 	}
-	if req.Description != nil {
+	if req.Description != nil && *req.Description != room.Description {
 		room.Description = *req.Description
+		room.ScannedAt = nil
 	}
 	if req.MaxMembers != nil {
 		room.MaxMembers = *req.MaxMembers
@@ -1042,7 +1204,7 @@ func (h *AdminHandler) DeleteAIConversation(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("id IN ?", req.IDs).Delete(&models.AIConversation{}).Error; err != nil {
+	if err := deleteWithStats(h.db, &models.AIConversation{}, req.IDs); err != nil {
 		h.logOperation(c, "DeleteAIConversation", "AIConversation", strings.Join(req.IDs, ","), "删除AI对话失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除AI对话失败")
 		return
@@ -1139,12 +1301,18 @@ func (h *AdminHandler) GetTrainingStats(c *gin.Context) {
 		PracticeCount   int64 `json:"practice_count"`
 	}
 
-	h.db.Model(&models.TrainingRecord{}).Count(&stats.TotalRecords)
-	h.db.Model(&models.User{}).Count(&stats.TotalUsers)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "meditation").Count(&stats.MeditationCount)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "airflow").Count(&stats.AirflowCount)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "exposure").Count(&stats.ExposureCount)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "practice").Count(&stats.PracticeCount)
+	// 从每日汇总表读取，避免每次全表计数
+	totals, err := analytics.SumDailyStats(h.db, time.Time{})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	stats.TotalRecords = totals.TrainingRecords
+	stats.TotalUsers = totals.NewUsers
+	stats.MeditationCount = totals.MeditationCount
+	stats.AirflowCount = totals.AirflowCount
+	stats.ExposureCount = totals.ExposureCount
+	stats.PracticeCount = totals.PracticeCount
 
 	response.Success(c, stats, "获取成功")
 }
@@ -1159,25 +1327,7 @@ func (h *AdminHandler) GetTrainingRecords(c *gin.Context) {
 	var total int64
 
 	// 使用 Preload 预加载用户信息，确保关联数据正确加载
-	query := h.db.Model(&models.TrainingRecord{}).Preload("User")
-
-	// 按类型筛选
-	if recordType := c.Query("type"); recordType != "" {
-		query = query.Where("type = ?", recordType)
-	}
-
-	// 按用户ID筛选
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	// 按日期范围筛选
-	if startDate := c.Query("start_date"); startDate != "" {
-		query = query.Where("timestamp >= ?", startDate)
-	}
-	if endDate := c.Query("end_date"); endDate != "" {
-		query = query.Where("timestamp <= ?", endDate)
-	}
+	query := h.trainingRecordListQuery(c.Request.URL.Query()).Preload("User")
 
 	query.Count(&total)
 
@@ -1232,6 +1382,30 @@ func (h *AdminHandler) GetTrainingRecords(c *gin.Context) {
 	}, "获取成功")
 }
 
+// trainingRecordListQuery 训练记录列表和导出共用的筛选条件
+func (h *AdminHandler) trainingRecordListQuery(q url.Values) *gorm.DB {
+	query := h.db.Model(&models.TrainingRecord{})
+
+	// 按类型筛选
+	if recordType := q.Get("type"); recordType != "" {
+		query = query.Where("type = ?", recordType)
+	}
+
+	// 按用户ID筛选
+	if userID := q.Get("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	// 按日期范围筛选
+	if startDate := q.Get("start_date"); startDate != "" {
+		query = query.Where("timestamp >= ?", startDate)
+	}
+	if endDate := q.Get("end_date"); endDate != "" {
+		query = query.Where("timestamp <= ?", endDate)
+	}
+	return query
+}
+
 // 获取训练记录详情
 func (h *AdminHandler) GetTrainingRecord(c *gin.Context) {
 	id := c.Param("id")
@@ -1300,7 +1474,7 @@ func (h *AdminHandler) DeleteTrainingRecord(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("id IN ?", req.IDs).Delete(&models.TrainingRecord{}).Error; err != nil {
+	if err := deleteWithStats(h.db, &models.TrainingRecord{}, req.IDs); err != nil {
 		h.logOperation(c, "DeleteTrainingRecord", "TrainingRecord", strings.Join(req.IDs, ","), "删除训练记录失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除失败")
 		return
@@ -1321,49 +1495,101 @@ func (h *AdminHandler) GetOperationLogs(c *gin.Context) {
 	var logs []models.OperationLog
 	var total int64
 
+	query, msg := h.operationLogListQuery(c.Request.URL.Query())
+	if msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&logs).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"logs":      logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// operationLogListQuery 操作日志列表和导出共用的筛选条件，参数无效时返回错误提示
+func (h *AdminHandler) operationLogListQuery(q url.Values) (*gorm.DB, string) {
 	query := h.db.Model(&models.OperationLog{})
 
 	// 按操作类型筛选
-	if action := c.Query("action"); action != "" {
+	if action := q.Get("action"); action != "" {
 		query = query.Where("action LIKE ?", "%"+action+"%")
 	}
 
 	// 按资源类型筛选
-	if resource := c.Query("resource"); resource != "" {
+	if resource := q.Get("resource"); resource != "" {
 		query = query.Where("resource = ?", resource)
 	}
 
+	// 按资源ID筛选，查看某个实体的完整变更历史
+	if resourceID := q.Get("resource_id"); resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+
+	// 按请求方法和路由筛选
+	if method := q.Get("method"); method != "" {
+		query = query.Where("method = ?", strings.ToUpper(method))
+	}
+	if path := q.Get("path"); path != "" {
+		query = query.Where("path LIKE ?", "%"+path+"%")
+	}
+
 	// 按状态筛选
-	if status := c.Query("status"); status != "" {
+	if status := q.Get("status"); status != "" {
 		query = query.Where("status = ?", status)
 	}
 
 	// 按管理员筛选
-	if username := c.Query("username"); username != "" {
+	if username := q.Get("username"); username != "" {
 		query = query.Where("username LIKE ?", "%"+username+"%")
 	}
+	if actorID := q.Get("user_id"); actorID != "" {
+		if _, err := uuid.Parse(actorID); err != nil {
+			return nil, "无效的用户ID"
+		}
+		query = query.Where("user_id = ?", actorID)
+	}
 
-	// 按时间范围筛选
-	if startDate := c.Query("start_date"); startDate != "" {
-		query = query.Where("created_at >= ?", startDate)
+	// 按时间范围筛选，支持日期（2006-01-02）或 RFC3339 时间
+	if startDate := q.Get("start_date"); startDate != "" {
+		start, ok := parseLogTime(startDate, false)
+		if !ok {
+			return nil, "无效的开始时间"
+		}
+		query = query.Where("created_at >= ?", start)
 	}
-	if endDate := c.Query("end_date"); endDate != "" {
-		query = query.Where("created_at <= ?", endDate)
+	if endDate := q.Get("end_date"); endDate != "" {
+		end, ok := parseLogTime(endDate, true)
+		if !ok {
+			return nil, "无效的结束时间"
+		}
+		query = query.Where("created_at <= ?", end)
 	}
+	return query, ""
+}
 
-	query.Count(&total)
-
-	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&logs).Error; err != nil {
-		response.Error(c, http.StatusInternalServerError, "查询失败")
-		return
+// parseLogTime 解析日志筛选时间；只给出日期时，结束时间取当天最后一刻
+func parseLogTime(value string, endOfDay bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
 	}
-
-	response.Success(c, gin.H{
-		"logs":      logs,
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-	}, "获取成功")
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, true
 }
 
 // 获取操作日志详情
@@ -1390,22 +1616,7 @@ func (h *AdminHandler) GetComments(c *gin.Context) {
 	var comments []models.Comment
 	var total int64
 
-	query := h.db.Model(&models.Comment{}).Preload("User").Preload("Post")
-
-	// 按帖子ID筛选
-	if postID := c.Query("post_id"); postID != "" {
-		query = query.Where("post_id = ?", postID)
-	}
-
-	// 按用户ID筛选
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
-
-	// 搜索评论内容
-	if keyword := c.Query("keyword"); keyword != "" {
-		query = query.Where("content LIKE ?", "%"+keyword+"%")
-	}
+	query := h.commentListQuery(c.Request.URL.Query()).Preload("User").Preload("Post")
 
 	query.Count(&total)
 
@@ -1422,6 +1633,32 @@ func (h *AdminHandler) GetComments(c *gin.Context) {
 	}, "获取成功")
 }
 
+// commentListQuery 评论列表和导出共用的筛选条件
+func (h *AdminHandler) commentListQuery(q url.Values) *gorm.DB {
+	query := h.db.Model(&models.Comment{})
+
+	// 按帖子ID筛选
+	if postID := q.Get("post_id"); postID != "" {
+		query = query.Where("post_id = ?", postID)
+	}
+
+	// 按用户ID筛选
+	if userID := q.Get("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	// 搜索评论内容
+	if keyword := q.Get("keyword"); keyword != "" {
+		query = query.Where("content LIKE ?", "%"+keyword+"%")
+	}
+
+	// 按审核状态筛选
+	if status := q.Get("moderation_status"); status != "" {
+		query = query.Where("moderation_status = ?", status)
+	}
+	return query
+}
+
 // 获取评论详情
 func (h *AdminHandler) GetComment(c *gin.Context) {
 	id := c.Param("id")
@@ -1453,8 +1690,9 @@ func (h *AdminHandler) UpdateComment(c *gin.Context) {
 		return
 	}
 
-	if req.Content != nil {
+	if req.Content != nil && *req.Content != comment.Content {
 		comment.Content = *req.Content
+		comment.ScannedAt = nil // 内容变更后重新做敏感词扫描
 	}
 
 	if err := h.db.Save(&comment).Error; err != nil {
@@ -1478,32 +1716,26 @@ func (h *AdminHandler) DeleteComment(c *gin.Context) {
 		return
 	}
 
-	tx := h.db.Begin()
-	if tx.Error != nil {
-		response.Error(c, http.StatusInternalServerError, "删除失败")
-		return
-	}
-
-	// 先删除相关的点赞记录
-	if err := tx.Where("comment_id IN ?", req.IDs).Delete(&models.CommentLike{}).Error; err != nil {
-		tx.Rollback()
-		response.Error(c, http.StatusInternalServerError, "删除相关点赞失败")
-		return
-	}
-
-	// 再删除评论
-	if err := tx.Where("id IN ?", req.IDs).Delete(&models.Comment{}).Error; err != nil {
-		tx.Rollback()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return softDeleteComments(tx, req.IDs)
+	})
+	if err != nil {
 		h.logOperation(c, "DeleteComment", "Comment", strings.Join(req.IDs, ","), "删除评论失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除评论失败")
 		return
 	}
 
-	tx.Commit()
 	h.logOperation(c, "DeleteComment", "Comment", strings.Join(req.IDs, ","), "删除评论成功", "Success")
 	response.Success(c, nil, "删除成功")
 }
 
+// softDeleteComments 软删除评论；点赞记录保留到彻底清除时再删除，以便恢复
+func softDeleteComments(tx *gorm.DB, ids []string) error {
+	return analytics.Reroll(tx, &models.Comment{}, ids, func() error {
+		return tx.Where("id IN ?", ids).Delete(&models.Comment{}).Error
+	})
+}
+
 // ========== 关注/收藏关系管理 ==========
 
 // 获取关注列表
@@ -1553,7 +1785,7 @@ func (h *AdminHandler) DeleteFollow(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("id IN ?", req.IDs).Delete(&models.Follow{}).Error; err != nil {
+	if err := deleteWithStats(h.db, &models.Follow{}, req.IDs); err != nil {
 		h.logOperation(c, "DeleteFollow", "Follow", strings.Join(req.IDs, ","), "删除关注关系失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除失败")
 		return
@@ -1610,7 +1842,7 @@ func (h *AdminHandler) DeletePostCollection(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("id IN ?", req.IDs).Delete(&models.PostCollection{}).Error; err != nil {
+	if err := deleteWithStats(h.db, &models.PostCollection{}, req.IDs); err != nil {
 		h.logOperation(c, "DeletePostCollection", "PostCollection", strings.Join(req.IDs, ","), "删除收藏失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除失败")
 		return
@@ -1669,7 +1901,7 @@ func (h *AdminHandler) DeletePostLike(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("id IN ?", req.IDs).Delete(&models.PostLike{}).Error; err != nil {
+	if err := deleteWithStats(h.db, &models.PostLike{}, req.IDs); err != nil {
 		h.logOperation(c, "DeletePostLike", "PostLike", strings.Join(req.IDs, ","), "删除点赞失败: "+err.Error(), "Failure")
 		response.Error(c, http.StatusInternalServerError, "删除失败")
 		return
@@ -1686,7 +1918,7 @@ func (h *AdminHandler) GetDetailedStats(c *gin.Context) {
 	var stats struct {
 		// 用户统计
 		TotalUsers        int64 `json:"total_users"`
-		ActiveUsers       int64 `json:"active_users"`         // 最近7天活跃用户
+		ActiveUsers       int64 `json:"active_users"`         // 最近7天有训练、发帖、评论或登录的用户
 		NewUsersToday     int64 `json:"new_users_today"`      // 今日新增用户
 		NewUsersThisWeek  int64 `json:"new_users_this_week"`  // 本周新增用户
 		NewUsersThisMonth int64 `json:"new_users_this_month"` // 本月新增用户
@@ -1715,48 +1947,58 @@ func (h *AdminHandler) GetDetailedStats(c *gin.Context) {
 		// 内容统计
 		TotalTongueTwisters   int64 `json:"total_tongue_twisters"`
 		TotalDailyExpressions int64 `json:"total_daily_expressions"`
-	}
-
-	// 用户统计
-	h.db.Model(&models.User{}).Count(&stats.TotalUsers)
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
-	h.db.Model(&models.User{}).Where("created_at >= ?", sevenDaysAgo).Count(&stats.ActiveUsers)
 
-	today := time.Now().Format("2006-01-02")
-	h.db.Model(&models.User{}).Where("DATE(created_at) = ?", today).Count(&stats.NewUsersToday)
+		StatsUpdatedAt time.Time `json:"stats_updated_at"` // 每日汇总数据的最近计算时间
+	}
 
-	weekStart := time.Now().AddDate(0, 0, -int(time.Now().Weekday()))
-	h.db.Model(&models.User{}).Where("created_at >= ?", weekStart).Count(&stats.NewUsersThisWeek)
+	// 累计和新增数据从每日汇总表读取（北京时间自然日，周从周一开始）
+	now := time.Now()
+	totals, err := analytics.SumDailyStats(h.db, time.Time{})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	for since, count := range map[string]*int64{
+		analytics.Day:   &stats.NewUsersToday,
+		analytics.Week:  &stats.NewUsersThisWeek,
+		analytics.Month: &stats.NewUsersThisMonth,
+	} {
+		sum, err := analytics.SumDailyStats(h.db, analytics.StartOf(now, since))
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "查询失败")
+			return
+		}
+		*count = sum.NewUsers
+	}
+	stats.StatsUpdatedAt = totals.ComputedAt
 
-	monthStart := time.Now().AddDate(0, 0, -time.Now().Day())
-	h.db.Model(&models.User{}).Where("created_at >= ?", monthStart).Count(&stats.NewUsersThisMonth)
+	// 用户统计
+	stats.TotalUsers = totals.NewUsers
+	// 最近7天的去重活跃用户无法由每日数据相加得到，仍实时统计
+	stats.ActiveUsers, _ = analytics.ActiveUsers(h.db, now.AddDate(0, 0, -7), now)
 
 	// 训练统计
-	h.db.Model(&models.TrainingRecord{}).Count(&stats.TotalRecords)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "meditation").Count(&stats.MeditationCount)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "airflow").Count(&stats.AirflowCount)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "exposure").Count(&stats.ExposureCount)
-	h.db.Model(&models.TrainingRecord{}).Where("type = ?", "practice").Count(&stats.PracticeCount)
-
-	var durationResult struct {
-		Total int64
-		Avg   float64
+	stats.TotalRecords = totals.TrainingRecords
+	stats.MeditationCount = totals.MeditationCount
+	stats.AirflowCount = totals.AirflowCount
+	stats.ExposureCount = totals.ExposureCount
+	stats.PracticeCount = totals.PracticeCount
+	stats.TotalDuration = totals.TrainingSeconds / 60 // 转换为分钟
+	if totals.TrainingRecords > 0 {
+		stats.AvgDuration = totals.TrainingSeconds / totals.TrainingRecords / 60
 	}
-	h.db.Model(&models.TrainingRecord{}).Select("COALESCE(SUM(duration), 0) as total, COALESCE(AVG(duration), 0) as avg").Scan(&durationResult)
-	stats.TotalDuration = durationResult.Total / 60 // 转换为分钟
-	stats.AvgDuration = int64(durationResult.Avg / 60)
 
 	// 社区统计
-	h.db.Model(&models.Post{}).Count(&stats.TotalPosts)
-	h.db.Model(&models.Comment{}).Count(&stats.TotalComments)
-	h.db.Model(&models.PostLike{}).Count(&stats.TotalLikes)
-	h.db.Model(&models.PostCollection{}).Count(&stats.TotalCollections)
-	h.db.Model(&models.Follow{}).Count(&stats.TotalFollows)
-	h.db.Model(&models.PracticeRoom{}).Count(&stats.TotalRooms)
+	stats.TotalPosts = totals.Posts
+	stats.TotalComments = totals.Comments
+	stats.TotalLikes = totals.Likes
+	stats.TotalCollections = totals.Collections
+	stats.TotalFollows = totals.Follows
+	stats.TotalRooms = totals.Rooms
 	h.db.Model(&models.PracticeRoom{}).Where("is_active = ?", true).Count(&stats.ActiveRooms)
 
 	// AI功能统计
-	h.db.Model(&models.AIConversation{}).Count(&stats.TotalAIConversations)
+	stats.TotalAIConversations = totals.AIConversations
 
 	// 内容统计
 	h.db.Model(&models.TongueTwister{}).Count(&stats.TotalTongueTwisters)
@@ -1851,6 +2093,11 @@ func (h *AdminHandler) BatchCreateTongueTwisters(c *gin.Context) {
 		return
 	}
 
+	if c.Query("async") == "true" {
+		h.submitJob(c, jobBatchCreateContent, models.JSONB{"kind": "tongue_twisters", "items": req})
+		return
+	}
+
 	tx := h.db.Begin()
 	if tx.Error != nil {
 		response.Error(c, http.StatusInternalServerError, "创建失败")
@@ -1922,27 +2169,44 @@ func (h *AdminHandler) DeleteTongueTwister(c *gin.Context) {
 
 // 清理绕口令（删除空白和重复的）
 func (h *AdminHandler) CleanTongueTwisters(c *gin.Context) {
-	tx := h.db.Begin()
-	if tx.Error != nil {
-		response.Error(c, http.StatusInternalServerError, "清理失败")
+	if c.Query("async") == "true" {
+		h.submitJob(c, jobCleanTongueTwisters, nil)
 		return
 	}
 
+	var blank int64
+	var duplicate int
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		blank, duplicate, err = cleanTongueTwisters(tx)
+		return err
+	})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"deleted_blank_count":     blank,
+		"deleted_duplicate_count": duplicate,
+	}, "绕口令清理成功")
+}
+
+// cleanTongueTwisters 删除空白绕口令和内容重复的绕口令，返回两类各删除的条数。同步接口和后台任务共用
+func cleanTongueTwisters(tx *gorm.DB) (int64, int, error) {
 	// 1. 删除空白绕口令
 	// 标题为空或只包含空格，或者内容为空或只包含空格
 	deleteBlankResult := tx.Where("TRIM(title) = '' OR TRIM(content) = ''").Delete(&models.TongueTwister{})
 	if deleteBlankResult.Error != nil {
-		tx.Rollback()
-		response.Error(c, http.StatusInternalServerError, "删除空白绕口令失败: "+deleteBlankResult.Error.Error())
-		return
+		return 0, 0, errors.New("删除空白绕口令失败: " + deleteBlankResult.Error.Error())
 	}
 
 	// 2. 删除重复绕口令
 	// 查找所有重复的 content，并保留每个组合中 ID 最小的一个
 	var duplicateTwisters []models.TongueTwister
-	// 使用子查询找到每个重复组中最小的 ID
+	// 使用子查询找到每个重复组中最小的 ID；重复项软删除，可在回收站恢复
 	err := tx.Raw(`
-		DELETE FROM tongue_twisters
+		UPDATE tongue_twisters SET deleted_at = NOW()
 		WHERE id IN (
 			SELECT id FROM (
 				SELECT
@@ -1950,22 +2214,16 @@ func (h *AdminHandler) CleanTongueTwisters(c *gin.Context) {
 					ROW_NUMBER() OVER(PARTITION BY content ORDER BY created_at) as rn
 				FROM
 					tongue_twisters
+				WHERE deleted_at IS NULL
 			) AS sub
 			WHERE sub.rn > 1
 		) RETURNING *;
 	`).Scan(&duplicateTwisters).Error
-
 	if err != nil {
-		tx.Rollback()
-		response.Error(c, http.StatusInternalServerError, "删除重复绕口令失败: "+err.Error())
-		return
+		return 0, 0, errors.New("删除重复绕口令失败: " + err.Error())
 	}
 
-	tx.Commit()
-	response.Success(c, gin.H{
-		"deleted_blank_count":     deleteBlankResult.RowsAffected,
-		"deleted_duplicate_count": len(duplicateTwisters),
-	}, "绕口令清理成功")
+	return deleteBlankResult.RowsAffected, len(duplicateTwisters), nil
 }
 
 // ========== 每日朗诵文案管理 ==========
@@ -2100,6 +2358,11 @@ func (h *AdminHandler) BatchCreateDailyExpressions(c *gin.Context) {
 		return
 	}
 
+	if c.Query("async") == "true" {
+		h.submitJob(c, jobBatchCreateContent, models.JSONB{"kind": "daily_expressions", "items": req})
+		return
+	}
+
 	tx := h.db.Begin()
 	if tx.Error != nil {
 		response.Error(c, http.StatusInternalServerError, "创建失败")
@@ -2238,6 +2501,11 @@ func (h *AdminHandler) BatchCreateSpeechTechniques(c *gin.Context) {
 		return
 	}
 
+	if c.Query("async") == "true" {
+		h.submitJob(c, jobBatchCreateContent, models.JSONB{"kind": "speech_techniques", "items": req})
+		return
+	}
+
 	tx := h.db.Begin()
 	if tx.Error != nil {
 		response.Error(c, http.StatusInternalServerError, "创建失败")
@@ -2369,6 +2637,11 @@ func (h *AdminHandler) UpdateUserSettings(c *gin.Context) {
 		settings.DataCollectionConsent = *req.DataCollectionConsent
 	}
 	if req.AIVoiceType != nil {
+		// 只校验修改后的音色，已有的无效引用由 cmd/check-voice-types 报告
+		if *req.AIVoiceType != settings.AIVoiceType && !voicetype.Enabled(h.db, *req.AIVoiceType) {
+			response.Error(c, http.StatusBadRequest, "音色类型不存在或未启用")
+			return
+		}
 		settings.AIVoiceType = *req.AIVoiceType
 	}
 	if req.AISpeakingSpeed != nil {
@@ -2502,22 +2775,7 @@ func (h *AdminHandler) GetFeedbackList(c *gin.Context) {
 	var feedbacks []models.Feedback
 	var total int64
 
-	query := h.db.Model(&models.Feedback{}).Preload("User")
-
-	// 按类型筛选
-	if feedbackType := c.Query("type"); feedbackType != "" {
-		query = query.Where("type = ?", feedbackType)
-	}
-
-	// 按状态筛选
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
-	}
-
-	// 按用户ID筛选
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
+	query := h.feedbackListQuery(c.Request.URL.Query()).Preload("User")
 
 	query.Count(&total)
 
@@ -2534,6 +2792,27 @@ func (h *AdminHandler) GetFeedbackList(c *gin.Context) {
 	}, "获取成功")
 }
 
+// feedbackListQuery 反馈列表和导出共用的筛选条件
+func (h *AdminHandler) feedbackListQuery(q url.Values) *gorm.DB {
+	query := h.db.Model(&models.Feedback{})
+
+	// 按类型筛选
+	if feedbackType := q.Get("type"); feedbackType != "" {
+		query = query.Where("type = ?", feedbackType)
+	}
+
+	// 按状态筛选
+	if status := q.Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// 按用户ID筛选
+	if userID := q.Get("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	return query
+}
+
 // GetFeedback 获取反馈详情
 func (h *AdminHandler) GetFeedback(c *gin.Context) {
 	id := c.Param("id")
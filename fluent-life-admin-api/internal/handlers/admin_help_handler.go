@@ -1,21 +1,58 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/pkg/auth"
 	"fluent-life-admin-api/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AdminHelpHandler struct {
 	db *gorm.DB
+	// viewGuard / voteGuard 复用登录限流的计数：按「文章+IP」去重，按 IP 限制频率
+	viewGuard *auth.LoginGuard
+	voteGuard *auth.LoginGuard
 }
 
 func NewAdminHelpHandler(db *gorm.DB) *AdminHelpHandler {
-	return &AdminHelpHandler{db: db}
+	return &AdminHelpHandler{
+		db:        db,
+		viewGuard: auth.NewLoginGuard(helpViewPolicy, helpIPPolicy),
+		voteGuard: auth.NewLoginGuard(helpVotePolicy, helpIPPolicy),
+	}
+}
+
+// helpViewPolicy 同一 IP 30 分钟内重复打开同一篇文章只计一次浏览
+var helpViewPolicy = auth.LoginGuardPolicy{
+	LockoutAttempts: 1,
+	LockoutDuration: 30 * time.Minute,
+	Window:          30 * time.Minute,
+}
+
+// helpVotePolicy 同一 IP 24 小时内对同一篇文章只能反馈一次
+var helpVotePolicy = auth.LoginGuardPolicy{
+	LockoutAttempts: 1,
+	LockoutDuration: 24 * time.Hour,
+	Window:          24 * time.Hour,
+}
+
+// helpIPPolicy 单个 IP 计入浏览数或反馈的频率：30 次以后逐步退避，120 次后 30 分钟内不再计入
+var helpIPPolicy = auth.LoginGuardPolicy{
+	FreeAttempts:    30,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAttempts: 120,
+	LockoutDuration: 30 * time.Minute,
+	Window:          10 * time.Minute,
 }
 
 // -------- categories --------
@@ -26,10 +63,10 @@ func (h *AdminHelpHandler) GetHelpCategories(c *gin.Context) {
 	withArticles := strings.ToLower(c.Query("with_articles")) == "true"
 
 	var categories []models.HelpCategory
-	q := h.db.Model(&models.HelpCategory{}).Order("`order` ASC")
+	q := h.db.Model(&models.HelpCategory{}).Order(`"order" ASC`)
 	if withArticles {
 		q = q.Preload("Articles", func(db *gorm.DB) *gorm.DB {
-			return db.Order("`order` ASC")
+			return db.Order(`"order" ASC`)
 		})
 	}
 
@@ -44,8 +81,9 @@ func (h *AdminHelpHandler) GetHelpCategories(c *gin.Context) {
 // POST /api/v1/admin/help-categories
 func (h *AdminHelpHandler) CreateHelpCategory(c *gin.Context) {
 	var req struct {
-		Name  string `json:"name" binding:"required"`
-		Order int    `json:"order"`
+		Name     string `json:"name" binding:"required"`
+		Order    *int   `json:"order"` // 为空时排在最后
+		IsActive *bool  `json:"is_active"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, "参数错误")
@@ -57,8 +95,18 @@ func (h *AdminHelpHandler) CreateHelpCategory(c *gin.Context) {
 		return
 	}
 
-	cat := models.HelpCategory{Name: name, Order: req.Order}
-	if err := h.db.Create(&cat).Error; err != nil {
+	cat := models.HelpCategory{Name: name, IsActive: true}
+	if req.IsActive != nil {
+		cat.IsActive = *req.IsActive
+	}
+	if req.Order != nil {
+		cat.Order = *req.Order
+	} else if err := h.db.Model(&models.HelpCategory{}).Select(`COALESCE(MAX("order"), 0) + 1`).Scan(&cat.Order).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "创建帮助分类失败")
+		return
+	}
+
+	if err := createHelpRow(h.db, &cat, cat.IsActive); err != nil {
 		response.Error(c, http.StatusInternalServerError, "创建帮助分类失败")
 		return
 	}
@@ -71,8 +119,9 @@ func (h *AdminHelpHandler) UpdateHelpCategory(c *gin.Context) {
 	id := c.Param("id")
 
 	var req struct {
-		Name  *string `json:"name"`
-		Order *int    `json:"order"`
+		Name     *string `json:"name"`
+		Order    *int    `json:"order"`
+		IsActive *bool   `json:"is_active"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, "参数错误")
@@ -100,6 +149,9 @@ func (h *AdminHelpHandler) UpdateHelpCategory(c *gin.Context) {
 	if req.Order != nil {
 		cat.Order = *req.Order
 	}
+	if req.IsActive != nil {
+		cat.IsActive = *req.IsActive
+	}
 
 	if err := h.db.Save(&cat).Error; err != nil {
 		response.Error(c, http.StatusInternalServerError, "更新帮助分类失败")
@@ -113,10 +165,14 @@ func (h *AdminHelpHandler) UpdateHelpCategory(c *gin.Context) {
 func (h *AdminHelpHandler) DeleteHelpCategory(c *gin.Context) {
 	id := c.Param("id")
 
-	// 先把该分类下文章的 category_id 置空/阻止删除？这里选择级联删除文章（简单）
-	h.db.Where("category_id = ?", id).Delete(&models.HelpArticle{})
-
-	if err := h.db.Delete(&models.HelpCategory{}, "id = ?", id).Error; err != nil {
+	// 分类下的文章一并删除
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ?", id).Delete(&models.HelpArticle{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.HelpCategory{}, "id = ?", id).Error
+	})
+	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除帮助分类失败")
 		return
 	}
@@ -131,7 +187,7 @@ func (h *AdminHelpHandler) GetHelpArticles(c *gin.Context) {
 	categoryID := strings.TrimSpace(c.Query("category_id"))
 	search := strings.TrimSpace(c.Query("q"))
 
-	dbq := h.db.Model(&models.HelpArticle{}).Order("`order` ASC, created_at DESC")
+	dbq := h.db.Model(&models.HelpArticle{}).Order(`"order" ASC, created_at DESC`)
 	if categoryID != "" {
 		dbq = dbq.Where("category_id = ?", categoryID)
 	}
@@ -155,7 +211,7 @@ func (h *AdminHelpHandler) CreateHelpArticle(c *gin.Context) {
 		CategoryID string `json:"category_id" binding:"required"`
 		Question   string `json:"question" binding:"required"`
 		Answer     string `json:"answer" binding:"required"`
-		Order      int    `json:"order"`
+		Order      *int   `json:"order"` // 为空时排在分类的最后
 		IsActive   *bool  `json:"is_active"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -189,11 +245,17 @@ func (h *AdminHelpHandler) CreateHelpArticle(c *gin.Context) {
 		CategoryID: cat.ID,
 		Question:   question,
 		Answer:     answer,
-		Order:      req.Order,
 		IsActive:   isActive,
 	}
+	if req.Order != nil {
+		article.Order = *req.Order
+	} else if err := h.db.Model(&models.HelpArticle{}).Where("category_id = ?", cat.ID).
+		Select(`COALESCE(MAX("order"), 0) + 1`).Scan(&article.Order).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "创建帮助文章失败")
+		return
+	}
 
-	if err := h.db.Create(&article).Error; err != nil {
+	if err := createHelpRow(h.db, &article, article.IsActive); err != nil {
 		response.Error(c, http.StatusInternalServerError, "创建帮助文章失败")
 		return
 	}
@@ -276,3 +338,214 @@ func (h *AdminHelpHandler) DeleteHelpArticle(c *gin.Context) {
 	}
 	response.Success(c, nil, "删除成功")
 }
+
+// createHelpRow 创建分类或文章。is_active 列默认值为 true，gorm 创建时会用默认值替换 false，需要单独更新
+func createHelpRow(db *gorm.DB, row interface{}, isActive bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(row).Error; err != nil {
+			return err
+		}
+		if !isActive {
+			return tx.Model(row).Update("is_active", false).Error
+		}
+		return nil
+	})
+}
+
+// -------- ordering --------
+
+// ReorderHelpCategories 调整分类顺序，ids 需包含全部分类，按数组顺序写入 order（从 1 开始）
+// PUT /api/v1/admin/help-categories/order
+func (h *AdminHelpHandler) ReorderHelpCategories(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供分类ID列表 ids")
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return reorder(tx, tx.Model(&models.HelpCategory{}), req.IDs)
+	})
+	if err != nil {
+		if errors.Is(err, errIncompleteOrder) {
+			response.Error(c, http.StatusBadRequest, "ids 必须包含全部分类且不能重复")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "调整分类顺序失败")
+		return
+	}
+	response.Success(c, nil, "排序已更新")
+}
+
+// ReorderHelpArticles 调整分类内文章的顺序，ids 需包含该分类下的全部文章
+// PUT /api/v1/admin/help-categories/:id/articles/order
+func (h *AdminHelpHandler) ReorderHelpArticles(c *gin.Context) {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的分类ID")
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供文章ID列表 ids")
+		return
+	}
+
+	var cat models.HelpCategory
+	if err := h.db.Where("id = ?", categoryID).First(&cat).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "帮助分类不存在")
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		return reorder(tx, tx.Model(&models.HelpArticle{}).Where("category_id = ?", categoryID), req.IDs)
+	})
+	if err != nil {
+		if errors.Is(err, errIncompleteOrder) {
+			response.Error(c, http.StatusBadRequest, "ids 必须包含该分类下的全部文章且不能重复")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "调整文章顺序失败")
+		return
+	}
+	response.Success(c, nil, "排序已更新")
+}
+
+// errIncompleteOrder 排序列表与现有记录不一致
+var errIncompleteOrder = errors.New("order list does not match existing rows")
+
+// reorder 按 ids 的顺序把 scope 内记录的 order 设为 1..n。scope 内的记录需与 ids 一一对应，
+// 避免并发新增的记录和未列出的记录排序混乱
+func reorder(tx *gorm.DB, scope *gorm.DB, ids []string) error {
+	var existing []string
+	if err := scope.Session(&gorm.Session{}).Clauses(clause.Locking{Strength: "UPDATE"}).Pluck("id", &existing).Error; err != nil {
+		return err
+	}
+	if len(existing) != len(ids) {
+		return errIncompleteOrder
+	}
+	known := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return errIncompleteOrder
+		}
+		delete(known, id) // 重复的 ID 第二次查不到
+	}
+
+	for i, id := range ids {
+		if err := scope.Session(&gorm.Session{}).Where("id = ?", id).UpdateColumn("order", i+1).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// -------- public --------
+
+// GetPublicHelpCenter 客户端获取帮助中心：启用的分类及其启用的文章，无需登录
+// GET /api/v1/help/categories
+func (h *AdminHelpHandler) GetPublicHelpCenter(c *gin.Context) {
+	var categories []models.HelpCategory
+	err := h.db.Where("is_active = ?", true).Order(`"order" ASC, created_at ASC`).
+		Preload("Articles", func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_active = ?", true).Order(`"order" ASC, created_at ASC`)
+		}).
+		Find(&categories).Error
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取帮助中心失败")
+		return
+	}
+
+	// 没有启用文章的分类不展示
+	visible := make([]models.HelpCategory, 0, len(categories))
+	for _, cat := range categories {
+		if len(cat.Articles) > 0 {
+			visible = append(visible, cat)
+		}
+	}
+	response.Success(c, gin.H{"categories": visible, "total": len(visible)}, "获取成功")
+}
+
+// GetPublicHelpArticle 客户端打开帮助文章，浏览数加一。
+// 同一 IP 重复打开或请求过于频繁时照常返回文章，但不计入浏览数
+// GET /api/v1/help/articles/:id
+func (h *AdminHelpHandler) GetPublicHelpArticle(c *gin.Context) {
+	article, ok := h.activeArticle(c)
+	if !ok {
+		return
+	}
+	key, ip := article.ID.String()+"|"+c.ClientIP(), c.ClientIP()
+	if h.viewGuard.RetryAfter(key, ip) == 0 {
+		if err := h.db.Model(&models.HelpArticle{}).Where("id = ?", article.ID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "获取帮助文章失败")
+			return
+		}
+		h.viewGuard.Fail(key, ip)
+		article.ViewCount++
+	}
+	response.Success(c, article, "获取成功")
+}
+
+// VoteHelpArticle 客户端反馈文章是否有帮助。接口不记录投票人，同一 IP 对同一篇文章 24 小时内只能反馈一次
+// POST /api/v1/help/articles/:id/vote
+func (h *AdminHelpHandler) VoteHelpArticle(c *gin.Context) {
+	var req struct {
+		Helpful *bool `json:"helpful" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 helpful")
+		return
+	}
+	article, ok := h.activeArticle(c)
+	if !ok {
+		return
+	}
+	key, ip := article.ID.String()+"|"+c.ClientIP(), c.ClientIP()
+	if wait := h.voteGuard.RetryAfter(key, ip); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds()+0.5)))
+		response.Error(c, http.StatusTooManyRequests, "已经反馈过或操作过于频繁，请稍后再试")
+		return
+	}
+
+	column := "not_helpful_count"
+	if *req.Helpful {
+		column = "helpful_count"
+	}
+	if err := h.db.Model(&models.HelpArticle{}).Where("id = ?", article.ID).
+		UpdateColumn(column, gorm.Expr(column+" + 1")).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "提交失败")
+		return
+	}
+	h.voteGuard.Fail(key, ip)
+	response.Success(c, nil, "感谢反馈")
+}
+
+// activeArticle 加载启用的、所属分类也启用的文章
+func (h *AdminHelpHandler) activeArticle(c *gin.Context) (*models.HelpArticle, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的文章ID")
+		return nil, false
+	}
+	var article models.HelpArticle
+	err = h.db.Where("id = ? AND is_active = ?", id, true).
+		Where("category_id IN (?)", h.db.Model(&models.HelpCategory{}).Select("id").Where("is_active = ?", true)).
+		First(&article).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "帮助文章不存在")
+			return nil, false
+		}
+		response.Error(c, http.StatusInternalServerError, "获取帮助文章失败")
+		return nil, false
+	}
+	return &article, true
+}
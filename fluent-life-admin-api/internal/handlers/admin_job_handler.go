@@ -0,0 +1,627 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"fluent-life-admin-api/internal/analytics"
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/export"
+	"fluent-life-admin-api/internal/jobs"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/permission"
+	"fluent-life-admin-api/internal/recyclebin"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 后台任务类型
+const (
+	jobCleanTongueTwisters     = "tongue_twisters.clean"
+	jobDeleteAllTongueTwisters = "tongue_twisters.delete_all"
+	jobBatchCreateContent      = "content.batch_create"
+	jobDeleteUsers             = "users.delete"
+	// exportJobPrefix 导出任务类型的前缀，后接 exportSource.Name
+	exportJobPrefix = "export."
+)
+
+const (
+	// deleteChunkSize 分批删除时每批的条数
+	deleteChunkSize = 1000
+	// maxJobUserIDs users.delete 一次最多删除的用户数
+	maxJobUserIDs = 1000
+)
+
+// AdminJobHandler 后台任务的提交、查询和取消
+type AdminJobHandler struct {
+	db       *gorm.DB
+	queue    *jobs.Queue
+	filesDir string
+}
+
+func NewAdminJobHandler(db *gorm.DB, queue *jobs.Queue, filesDir string) *AdminJobHandler {
+	return &AdminJobHandler{db: db, queue: queue, filesDir: filesDir}
+}
+
+// GetJobTypes 获取可提交的任务类型，allowed 表示当前管理员是否有权提交
+// GET /api/v1/admin/jobs/types
+func (h *AdminJobHandler) GetJobTypes(c *gin.Context) {
+	has, err := h.permissions(c)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询权限失败")
+		return
+	}
+
+	defs := h.queue.Definitions()
+	items := make([]gin.H, 0, len(defs))
+	for _, def := range defs {
+		items = append(items, gin.H{
+			"type":       def.Type,
+			"name":       def.Name,
+			"permission": def.Permission,
+			"allowed":    has(def.Permission),
+		})
+	}
+	response.Success(c, items, "获取成功")
+}
+
+// SubmitJob 提交后台任务
+// POST /api/v1/admin/jobs
+func (h *AdminJobHandler) SubmitJob(c *gin.Context) {
+	var req struct {
+		Type    string       `json:"type" binding:"required"`
+		Payload models.JSONB `json:"payload"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供任务类型 type")
+		return
+	}
+
+	def, ok := h.queue.Definition(req.Type)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "未知的任务类型")
+		return
+	}
+	has, err := h.permissions(c)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询权限失败")
+		return
+	}
+	if !has(def.Permission) {
+		response.Error(c, http.StatusForbidden, "Access denied: missing permission "+def.Permission)
+		return
+	}
+
+	submitJob(c, h.queue, req.Type, req.Payload)
+}
+
+// GetJobs 获取任务列表。没有 job:manage 权限时只能查看自己提交的任务
+// GET /api/v1/admin/jobs?type=&status=&created_by=
+func (h *AdminJobHandler) GetJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	has, err := h.permissions(c)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询权限失败")
+		return
+	}
+
+	// 参数可能很大（例如批量创建的内容），列表中不返回
+	query := h.db.Model(&models.Job{}).Omit("payload")
+	if !has("job:manage") {
+		query = query.Where("created_by = ?", currentUserID(c))
+	} else if createdBy := c.Query("created_by"); createdBy != "" {
+		query = query.Where("created_by = ?", createdBy)
+	}
+	if jobType := c.Query("type"); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var items []models.Job
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&items).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"jobs":      items,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// GetJob 获取任务详情，用于轮询进度
+// GET /api/v1/admin/jobs/:id
+func (h *AdminJobHandler) GetJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	response.Success(c, job, "获取成功")
+}
+
+// CancelJob 取消任务。排队中的任务立即取消，执行中的任务在下一次进度更新或心跳时停止
+// POST /api/v1/admin/jobs/:id/cancel
+func (h *AdminJobHandler) CancelJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.queue.Cancel(job.ID)
+	if err != nil {
+		if errors.Is(err, jobs.ErrFinished) {
+			response.Error(c, http.StatusBadRequest, "任务已结束，无法取消")
+			return
+		}
+		audit.Annotate(c, "CancelJob", "Job", job.ID.String(), "取消任务失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "取消任务失败")
+		return
+	}
+
+	audit.Annotate(c, "CancelJob", "Job", job.ID.String(), "取消后台任务 "+job.Type, "Success")
+	if cancelled.Status == models.JobCancelled {
+		response.Success(c, cancelled, "任务已取消")
+		return
+	}
+	response.Success(c, cancelled, "已请求取消，任务将在当前步骤结束后停止")
+}
+
+// DownloadJobFile 下载导出任务生成的文件
+// GET /api/v1/admin/jobs/:id/file
+func (h *AdminJobHandler) DownloadJobFile(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	file, _ := job.Result["file"].(string)
+	if job.Status != models.JobSucceeded || file == "" {
+		response.Error(c, http.StatusBadRequest, "任务没有可下载的文件")
+		return
+	}
+
+	path := filepath.Join(h.filesDir, filepath.Base(file))
+	if _, err := os.Stat(path); err != nil {
+		response.Error(c, http.StatusNotFound, "文件已过期或不存在")
+		return
+	}
+	name, _ := job.Result["file_name"].(string)
+	if name == "" {
+		name = filepath.Base(file)
+	}
+	c.FileAttachment(path, name)
+}
+
+// loadJob 按路由参数加载任务，并校验当前管理员能否查看
+func (h *AdminJobHandler) loadJob(c *gin.Context) (*models.Job, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的任务ID")
+		return nil, false
+	}
+	var job models.Job
+	if err := h.db.Where("id = ?", id).First(&job).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "任务不存在")
+		return nil, false
+	}
+
+	if job.CreatedBy != currentUserID(c) {
+		has, err := h.permissions(c)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "查询权限失败")
+			return nil, false
+		}
+		// 不暴露其他管理员的任务是否存在
+		if !has("job:manage") {
+			response.Error(c, http.StatusNotFound, "任务不存在")
+			return nil, false
+		}
+	}
+	return &job, true
+}
+
+// permissions 当前管理员的有效权限，规则与 middleware.RequirePermission 相同
+func (h *AdminJobHandler) permissions(c *gin.Context) (func(code string) bool, error) {
+	role, _ := c.Get("userRole")
+	roleCode, _ := role.(string)
+	if roleCode == permission.SuperAdminRole {
+		return func(string) bool { return true }, nil
+	}
+	set, err := permission.ForUser(h.db, currentUserID(c), roleCode)
+	if err != nil {
+		return nil, err
+	}
+	return set.Has, nil
+}
+
+func currentUserID(c *gin.Context) uuid.UUID {
+	v, _ := c.Get("userID")
+	id, _ := v.(uuid.UUID)
+	return id
+}
+
+// submitJob 以当前管理员的身份提交任务并返回任务记录
+func submitJob(c *gin.Context, queue *jobs.Queue, jobType string, payload models.JSONB) {
+	if queue == nil {
+		response.Error(c, http.StatusServiceUnavailable, "后台任务未启用")
+		return
+	}
+
+	job := &models.Job{Type: jobType, Payload: payload, CreatedBy: currentUserID(c)}
+	if v, ok := c.Get("username"); ok {
+		job.CreatedByName, _ = v.(string)
+	}
+	if err := queue.Submit(job); err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrUnknownType):
+			response.Error(c, http.StatusBadRequest, "未知的任务类型")
+		case jobs.IsPermanent(err):
+			response.Error(c, http.StatusBadRequest, err.Error())
+		default:
+			audit.Annotate(c, "SubmitJob", "Job", "", "提交后台任务失败: "+err.Error(), "Failure")
+			response.Error(c, http.StatusInternalServerError, "提交任务失败")
+		}
+		return
+	}
+
+	name := jobType
+	if def, ok := queue.Definition(jobType); ok {
+		name = def.Name
+	}
+	audit.Annotate(c, "SubmitJob", "Job", job.ID.String(), "提交后台任务："+name, "Success")
+	response.Success(c, job, "任务已提交")
+}
+
+// ========== 任务类型 ==========
+
+// RegisterJobs 注册由 AdminHandler 执行的任务类型，并允许同步接口通过 async=true 提交任务。
+// 导出任务的文件写入 filesDir，任务记录被清理时一并删除
+func (h *AdminHandler) RegisterJobs(queue *jobs.Queue, filesDir string) {
+	h.jobs = queue
+	h.jobFilesDir = filesDir
+
+	queue.Register(jobs.Definition{
+		Type:       jobCleanTongueTwisters,
+		Name:       "清理空白和重复的绕口令",
+		Permission: "content:write",
+		Run: func(ctx context.Context, run *jobs.Run) (models.JSONB, error) {
+			var blank int64
+			var duplicate int
+			err := run.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				var err error
+				blank, duplicate, err = cleanTongueTwisters(tx)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			return models.JSONB{"deleted_blank_count": blank, "deleted_duplicate_count": duplicate}, nil
+		},
+	})
+
+	queue.Register(jobs.Definition{
+		Type:       jobDeleteAllTongueTwisters,
+		Name:       "删除所有绕口令",
+		Permission: "content:write",
+		Run:        runDeleteAllTongueTwisters,
+	})
+
+	queue.Register(jobs.Definition{
+		Type:       jobBatchCreateContent,
+		Name:       "批量创建内容",
+		Permission: "content:write",
+		Validate: func(payload models.JSONB) error {
+			kind, _ := payload["kind"].(string)
+			if _, ok := batchCreateKinds[kind]; !ok {
+				return errors.New("无效的内容类型，可选 tongue_twisters、daily_expressions、speech_techniques")
+			}
+			var items []json.RawMessage
+			if err := decodePayload(payload["items"], &items); err != nil || len(items) == 0 {
+				return errors.New("items 必须是非空数组")
+			}
+			return nil
+		},
+		Run: func(ctx context.Context, run *jobs.Run) (models.JSONB, error) {
+			kind, _ := run.Job.Payload["kind"].(string)
+			create, ok := batchCreateKinds[kind]
+			if !ok {
+				return nil, jobs.Permanent(fmt.Errorf("unknown content kind %q", kind))
+			}
+			created, err := create(ctx, run, run.Job.Payload["items"])
+			if err != nil {
+				return nil, err
+			}
+			return models.JSONB{"kind": kind, "created_count": created}, nil
+		},
+	})
+
+	queue.Register(jobs.Definition{
+		Type:       jobDeleteUsers,
+		Name:       "删除用户",
+		Permission: "user:write",
+		Validate: func(payload models.JSONB) error {
+			var ids []string
+			if err := decodePayload(payload["ids"], &ids); err != nil || len(ids) == 0 {
+				return errors.New("ids 必须是非空的用户ID数组")
+			}
+			if len(ids) > maxJobUserIDs {
+				return fmt.Errorf("一次最多删除 %d 个用户", maxJobUserIDs)
+			}
+			for _, id := range ids {
+				if _, err := uuid.Parse(id); err != nil {
+					return errors.New("无效的用户ID: " + id)
+				}
+			}
+			return nil
+		},
+		Run: runDeleteUsers,
+	})
+
+	for _, src := range exportSources {
+		queue.Register(jobs.Definition{
+			Type:       exportJobPrefix + src.Name,
+			Name:       "导出" + src.Label,
+			Permission: src.Permission,
+			Validate: func(payload models.JSONB) error {
+				_, _, msg := h.exportJob(src, payload)
+				if msg != "" {
+					return errors.New(msg)
+				}
+				return nil
+			},
+			Run: func(ctx context.Context, run *jobs.Run) (models.JSONB, error) {
+				return h.runExport(ctx, run, src)
+			},
+			Cleanup: h.removeJobFile,
+		})
+	}
+}
+
+// submitJob 同步接口带 async=true 时改为提交后台任务
+func (h *AdminHandler) submitJob(c *gin.Context, jobType string, payload models.JSONB) {
+	submitJob(c, h.jobs, jobType, payload)
+}
+
+// runDeleteAllTongueTwisters 分批软删除全部绕口令，每批更新一次进度
+func runDeleteAllTongueTwisters(ctx context.Context, run *jobs.Run) (models.JSONB, error) {
+	db := run.DB.WithContext(ctx)
+	var total int64
+	if err := db.Model(&models.TongueTwister{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var deleted int64
+	for {
+		result := db.Where("id IN (?)", db.Model(&models.TongueTwister{}).Select("id").Limit(deleteChunkSize)).
+			Delete(&models.TongueTwister{})
+		if result.Error != nil {
+			return models.JSONB{"deleted_count": deleted}, result.Error
+		}
+		deleted += result.RowsAffected
+		if result.RowsAffected < deleteChunkSize {
+			break
+		}
+		if err := run.Progress(ctx, deleted, total, fmt.Sprintf("已删除 %d/%d 条", deleted, total)); err != nil {
+			return models.JSONB{"deleted_count": deleted}, err
+		}
+	}
+	return models.JSONB{"deleted_count": deleted}, nil
+}
+
+// runDeleteUsers 逐个删除用户：吊销登录会话，用户及其帖子、评论移入回收站。
+// 提交人没有全部权限时跳过拥有全部权限的账号（超级管理员），与 DeleteUser 的限制一致
+func runDeleteUsers(ctx context.Context, run *jobs.Run) (models.JSONB, error) {
+	var ids []string
+	if err := decodePayload(run.Job.Payload["ids"], &ids); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	db := run.DB.WithContext(ctx)
+	submitterAll, err := hasAllPermissions(db, run.Job.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 删除结束（包括中途失败）后重新计算这些用户注册以来的每日统计
+	since, err := analytics.Earliest(db, &models.User{}, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := analytics.RollupSince(db, since); err != nil {
+			log.Printf("删除用户后重新计算每日统计失败: %v", err)
+		}
+	}()
+
+	var deleted int64
+	skipped := make([]string, 0)
+	result := func() models.JSONB {
+		return models.JSONB{"deleted_count": deleted, "skipped_ids": skipped}
+	}
+	for i, id := range ids {
+		if !submitterAll {
+			targetID, _ := uuid.Parse(id)
+			all, err := hasAllPermissions(db, targetID)
+			if err != nil {
+				return result(), err
+			}
+			if all {
+				skipped = append(skipped, id)
+				if err := run.Progress(ctx, int64(i+1), int64(len(ids)), fmt.Sprintf("已处理 %d/%d 个用户", i+1, len(ids))); err != nil {
+					return result(), err
+				}
+				continue
+			}
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ? AND revoked_at IS NULL", id).Model(&models.AuthSession{}).
+				Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": revokeReasonDeleted}).Error; err != nil {
+				return err
+			}
+			n, err := recyclebin.SoftDeleteUsers(tx, []string{id})
+			deleted += n
+			return err
+		})
+		if err != nil {
+			return result(), fmt.Errorf("删除用户 %s 失败: %w", id, err)
+		}
+		if err := run.Progress(ctx, int64(i+1), int64(len(ids)), fmt.Sprintf("已处理 %d/%d 个用户", i+1, len(ids))); err != nil {
+			return result(), err
+		}
+	}
+	return result(), nil
+}
+
+// hasAllPermissions 用户当前是否拥有全部权限，用户不存在时返回 false
+func hasAllPermissions(db *gorm.DB, userID uuid.UUID) (bool, error) {
+	var user models.User
+	found := db.Where("id = ?", userID).Limit(1).Find(&user)
+	if found.Error != nil || found.RowsAffected == 0 {
+		return false, found.Error
+	}
+	set, err := permission.ForUser(db, user.ID, user.Role)
+	if err != nil {
+		return false, err
+	}
+	return set.All(), nil
+}
+
+// batchCreateKinds content.batch_create 支持的内容类型
+var batchCreateKinds = map[string]func(ctx context.Context, run *jobs.Run, items interface{}) (int, error){
+	"tongue_twisters":   batchCreate[models.TongueTwister],
+	"daily_expressions": batchCreate[models.DailyExpression],
+	"speech_techniques": batchCreate[models.SpeechTechnique],
+}
+
+// batchCreate 在一个事务中创建全部内容，任一条失败时整体回滚
+func batchCreate[T any](ctx context.Context, run *jobs.Run, raw interface{}) (int, error) {
+	var items []T
+	if err := decodePayload(raw, &items); err != nil {
+		return 0, jobs.Permanent(fmt.Errorf("items 格式错误: %w", err))
+	}
+	err := run.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range items {
+			if err := tx.Create(&items[i]).Error; err != nil {
+				return fmt.Errorf("第 %d 条创建失败: %w", i+1, err)
+			}
+			if done := i + 1; done%100 == 0 || done == len(items) {
+				if err := run.Progress(ctx, int64(done), int64(len(items)), fmt.Sprintf("已创建 %d/%d 条", done, len(items))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// exportJob 解析导出任务的参数：format 为 csv 或 xlsx，query 为列表接口的查询字符串
+func (h *AdminHandler) exportJob(src exportSource, payload models.JSONB) (exportFunc, string, string) {
+	formatParam, _ := payload["format"].(string)
+	format, err := export.ParseFormat(formatParam)
+	if err != nil {
+		return nil, "", "不支持的导出格式，可选 csv、xlsx"
+	}
+	rawQuery, _ := payload["query"].(string)
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, "", "无效的筛选参数 query"
+	}
+	write, msg := src.build(h, params)
+	return write, format, msg
+}
+
+// runExport 把导出文件写入任务文件目录，完成后才改为正式文件名，下载时不会读到写了一半的文件
+func (h *AdminHandler) runExport(ctx context.Context, run *jobs.Run, src exportSource) (models.JSONB, error) {
+	write, format, msg := h.exportJob(src, run.Job.Payload)
+	if msg != "" {
+		return nil, jobs.Permanent(errors.New(msg))
+	}
+
+	file := run.Job.ID.String() + "." + format
+	path := filepath.Join(h.jobFilesDir, file)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path + ".tmp")
+
+	rows, err := write(f, format, func(written int) error {
+		return run.Progress(ctx, 0, 0, fmt.Sprintf("已导出 %d 行", written))
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return models.JSONB{
+		"file":      file,
+		"file_name": export.FileName(src.Name, format, time.Now()),
+		"format":    format,
+		"rows":      rows,
+		"size":      info.Size(),
+	}, nil
+}
+
+// removeJobFile 删除导出任务生成的文件
+func (h *AdminHandler) removeJobFile(job *models.Job) {
+	file, _ := job.Result["file"].(string)
+	if file == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(h.jobFilesDir, filepath.Base(file))); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除任务文件失败 %s: %v", file, err)
+	}
+}
+
+// decodePayload 把任务参数中的值转换为具体类型。提交时参数可能是处理器传入的结构体，
+// 从数据库读出后则是 JSON 解码得到的 map 和切片，统一经过一次 JSON 编解码
+func decodePayload(v interface{}, out interface{}) error {
+	if v == nil {
+		return errors.New("missing value")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxModerationBatch 单次批量审核的最大条数
+const maxModerationBatch = 500
+
+// ModerationTarget 可审核的一类内容
+type ModerationTarget struct {
+	// Name 路由中的名称，例如 posts
+	Name string
+	// Label 中文名称，用于提示信息
+	Label string
+	// Resource 审计日志中的资源名
+	Resource string
+	// Permission 权限模块，队列需要 <Permission>:read，审核需要 <Permission>:write
+	Permission string
+	// Model 内容模型
+	Model interface{}
+	// Preloads 队列中需要预加载的关联
+	Preloads []string
+}
+
+// ModerationTargets 支持审核的内容
+var ModerationTargets = []ModerationTarget{
+	{Name: "posts", Label: "帖子", Resource: "Post", Permission: "post", Model: &models.Post{}, Preloads: []string{"User"}},
+	{Name: "comments", Label: "评论", Resource: "Comment", Permission: "comment", Model: &models.Comment{}, Preloads: []string{"User", "Post"}},
+}
+
+// moderationActions 审核动作对应的状态
+var moderationActions = map[string]string{
+	"approve": models.ModerationApproved,
+	"reject":  models.ModerationRejected,
+	"hide":    models.ModerationHidden,
+}
+
+// AdminModerationHandler 帖子和评论的审核队列
+type AdminModerationHandler struct {
+	db *gorm.DB
+}
+
+func NewAdminModerationHandler(db *gorm.DB) *AdminModerationHandler {
+	return &AdminModerationHandler{db: db}
+}
+
+// GetReasons 获取驳回/隐藏原因代码
+// GET /api/v1/admin/moderation/reasons
+func (h *AdminModerationHandler) GetReasons(c *gin.Context) {
+	response.Success(c, models.ModerationReasons, "获取成功")
+}
+
+// Queue 获取审核队列，按风险分从高到低、同分按发布时间从早到晚排序
+// GET /api/v1/admin/moderation/:target
+func (h *AdminModerationHandler) Queue(target ModerationTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 20
+		}
+		offset := (page - 1) * pageSize
+
+		status := c.DefaultQuery("status", models.ModerationPending)
+		if !validModerationStatus(status) {
+			response.Error(c, http.StatusBadRequest, "无效的审核状态")
+			return
+		}
+
+		items := reflect.New(reflect.SliceOf(reflect.TypeOf(target.Model).Elem())).Interface()
+		var total int64
+
+		query := h.db.Model(target.Model).Where("moderation_status = ?", status)
+		if minRisk := c.Query("min_risk"); minRisk != "" {
+			if v, err := strconv.Atoi(minRisk); err == nil {
+				query = query.Where("risk_score >= ?", v)
+			}
+		}
+		if userID := c.Query("user_id"); userID != "" {
+			query = query.Where("user_id = ?", userID)
+		}
+
+		query.Count(&total)
+
+		for _, preload := range target.Preloads {
+			query = query.Preload(preload)
+		}
+		if err := query.Offset(offset).Limit(pageSize).Order("risk_score DESC, created_at ASC").Find(items).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "查询失败")
+			return
+		}
+
+		response.Success(c, gin.H{
+			"items":     items,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		}, "获取成功")
+	}
+}
+
+// Moderate 通过、驳回或隐藏内容，支持批量
+// POST /api/v1/admin/moderation/:target/moderate
+func (h *AdminModerationHandler) Moderate(target ModerationTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			IDs        []string `json:"ids" binding:"required"`
+			Action     string   `json:"action" binding:"required"` // approve, reject, hide
+			ReasonCode string   `json:"reason_code"`
+			Note       string   `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+			response.Error(c, http.StatusBadRequest, "参数错误，需要提供"+target.Label+"ID列表和审核动作")
+			return
+		}
+		if len(req.IDs) > maxModerationBatch {
+			response.Error(c, http.StatusBadRequest, fmt.Sprintf("单次最多审核 %d 条", maxModerationBatch))
+			return
+		}
+		status, ok := moderationActions[req.Action]
+		if !ok {
+			response.Error(c, http.StatusBadRequest, "无效的审核动作，可选 approve、reject、hide")
+			return
+		}
+		if status != models.ModerationApproved {
+			if _, ok := models.ModerationReasons[req.ReasonCode]; !ok {
+				response.Error(c, http.StatusBadRequest, "驳回或隐藏需要提供有效的原因代码")
+				return
+			}
+		} else {
+			req.ReasonCode = ""
+		}
+		for _, id := range req.IDs {
+			if _, err := uuid.Parse(id); err != nil {
+				response.Error(c, http.StatusBadRequest, "无效的"+target.Label+"ID: "+id)
+				return
+			}
+		}
+
+		moderatorID, _ := c.Get("userID")
+		moderator, _ := moderatorID.(uuid.UUID)
+		now := time.Now()
+
+		before := map[string]interface{}{}
+		after := map[string]interface{}{}
+		var updated int64
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			var rows []struct {
+				ID               uuid.UUID
+				ModerationStatus string
+				ModerationReason string
+			}
+			if err := tx.Model(target.Model).Select("id, moderation_status, moderation_reason").
+				Where("id IN ?", req.IDs).Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				before[row.ID.String()] = map[string]interface{}{"moderation_status": row.ModerationStatus, "moderation_reason": row.ModerationReason}
+				after[row.ID.String()] = map[string]interface{}{"moderation_status": status, "moderation_reason": req.ReasonCode}
+			}
+
+			result := tx.Model(target.Model).Where("id IN ?", req.IDs).Updates(map[string]interface{}{
+				"moderation_status": status,
+				"moderation_reason": req.ReasonCode,
+				"moderation_note":   req.Note,
+				"moderated_by":      moderator,
+				"moderated_at":      now,
+			})
+			updated = result.RowsAffected
+			return result.Error
+		})
+
+		action := strings.ToUpper(req.Action[:1]) + req.Action[1:] + target.Resource
+		if err != nil {
+			audit.Annotate(c, action, target.Resource, strings.Join(req.IDs, ","), "审核"+target.Label+"失败: "+err.Error(), "Failure")
+			response.Error(c, http.StatusInternalServerError, "审核失败")
+			return
+		}
+
+		details := fmt.Sprintf("审核%s %d 条：%s", target.Label, updated, status)
+		if req.ReasonCode != "" {
+			details += "，原因 " + req.ReasonCode
+		}
+		audit.Annotate(c, action, target.Resource, strings.Join(req.IDs, ","), details, "Success")
+		audit.SetBefore(c, before)
+		audit.SetAfter(c, after)
+
+		response.Success(c, gin.H{"updated": updated, "status": status}, "审核成功")
+	}
+}
+
+// validModerationStatus 是否为有效的审核状态
+func validModerationStatus(status string) bool {
+	switch status {
+	case models.ModerationPending, models.ModerationApproved, models.ModerationRejected, models.ModerationHidden:
+		return true
+	}
+	return false
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"fluent-life-admin-api/internal/audit"
 	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/permission"
 	"fluent-life-admin-api/pkg/response"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,11 +16,12 @@ import (
 )
 
 type AdminPermissionHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	permissions *permission.Registry
 }
 
-func NewAdminPermissionHandler(db *gorm.DB) *AdminPermissionHandler {
-	return &AdminPermissionHandler{db: db}
+func NewAdminPermissionHandler(db *gorm.DB, permissions *permission.Registry) *AdminPermissionHandler {
+	return &AdminPermissionHandler{db: db, permissions: permissions}
 }
 
 // ============ 角色管理 ============
@@ -63,7 +68,9 @@ func (h *AdminPermissionHandler) GetRole(c *gin.Context) {
 	id := c.Param("id")
 
 	var role models.Role
-	if err := h.db.Where("id = ?", id).First(&role).Error; err != nil {
+	if err := h.db.Where("id = ?", id).Preload("Menus", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort ASC, created_at ASC")
+	}).First(&role).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.Error(c, http.StatusNotFound, "角色不存在")
 			return
@@ -75,14 +82,15 @@ func (h *AdminPermissionHandler) GetRole(c *gin.Context) {
 	response.Success(c, role, "获取成功")
 }
 
-// CreateRole 创建角色
+// CreateRole 创建角色，权限（含继承的上级角色）不能超出当前管理员的权限
 // POST /api/v1/admin/roles
 func (h *AdminPermissionHandler) CreateRole(c *gin.Context) {
 	var req struct {
-		Name        string   `json:"name" binding:"required"`
-		Code        string   `json:"code" binding:"required"`
-		Description string   `json:"description"`
-		Permissions []string `json:"permissions"`
+		Name        string     `json:"name" binding:"required"`
+		Code        string     `json:"code" binding:"required"`
+		Description string     `json:"description"`
+		Permissions []string   `json:"permissions"`
+		ParentID    *uuid.UUID `json:"parent_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -90,6 +98,11 @@ func (h *AdminPermissionHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
+	if unknown := h.permissions.Unknown(req.Permissions); len(unknown) > 0 {
+		response.Error(c, http.StatusBadRequest, "未知的权限代码: "+strings.Join(unknown, ", "))
+		return
+	}
+
 	// 检查角色代码是否已存在
 	var existingRole models.Role
 	if err := h.db.Where("code = ?", req.Code).First(&existingRole).Error; err == nil {
@@ -97,6 +110,15 @@ func (h *AdminPermissionHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
+	if req.ParentID != nil {
+		var count int64
+		h.db.Model(&models.Role{}).Where("id = ?", *req.ParentID).Count(&count)
+		if count == 0 {
+			response.Error(c, http.StatusBadRequest, "上级角色不存在")
+			return
+		}
+	}
+
 	// 将权限数组转换为 JSONB
 	permissionsJSONB := models.JSONB{}
 	if req.Permissions != nil {
@@ -106,10 +128,15 @@ func (h *AdminPermissionHandler) CreateRole(c *gin.Context) {
 	}
 
 	role := models.Role{
+		ID:          uuid.New(),
 		Name:        req.Name,
 		Code:        req.Code,
 		Description: req.Description,
 		Permissions: permissionsJSONB,
+		ParentID:    req.ParentID,
+	}
+	if !requireRoleEdit(h.db, c, nil, &role) {
+		return
 	}
 
 	if err := h.db.Create(&role).Error; err != nil {
@@ -120,7 +147,8 @@ func (h *AdminPermissionHandler) CreateRole(c *gin.Context) {
 	response.Success(c, role, "创建成功")
 }
 
-// UpdateRole 更新角色
+// UpdateRole 更新角色，parent_id 传全零 UUID 表示取消继承。限制同 CreateRole，
+// 且不能修改自己拥有的角色及其上级角色（拥有全部权限的管理员除外）
 // PUT /api/v1/admin/roles/:id
 func (h *AdminPermissionHandler) UpdateRole(c *gin.Context) {
 	id := c.Param("id")
@@ -135,11 +163,14 @@ func (h *AdminPermissionHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
+	original := role
+
 	var req struct {
-		Name        *string   `json:"name"`
-		Code        *string   `json:"code"`
-		Description *string   `json:"description"`
-		Permissions *[]string `json:"permissions"`
+		Name        *string    `json:"name"`
+		Code        *string    `json:"code"`
+		Description *string    `json:"description"`
+		Permissions *[]string  `json:"permissions"`
+		ParentID    *uuid.UUID `json:"parent_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -163,12 +194,31 @@ func (h *AdminPermissionHandler) UpdateRole(c *gin.Context) {
 		role.Description = *req.Description
 	}
 	if req.Permissions != nil {
+		if unknown := h.permissions.Unknown(*req.Permissions); len(unknown) > 0 {
+			response.Error(c, http.StatusBadRequest, "未知的权限代码: "+strings.Join(unknown, ", "))
+			return
+		}
 		permissionsJSONB := models.JSONB{}
 		for _, perm := range *req.Permissions {
 			permissionsJSONB[perm] = true
 		}
 		role.Permissions = permissionsJSONB
 	}
+	if req.ParentID != nil {
+		if *req.ParentID == uuid.Nil {
+			role.ParentID = nil
+		} else {
+			if msg := h.checkRoleParent(role.ID, *req.ParentID); msg != "" {
+				response.Error(c, http.StatusBadRequest, msg)
+				return
+			}
+			role.ParentID = req.ParentID
+		}
+	}
+
+	if !requireRoleEdit(h.db, c, &original, &role) {
+		return
+	}
 
 	if err := h.db.Save(&role).Error; err != nil {
 		response.Error(c, http.StatusInternalServerError, "更新角色失败")
@@ -196,12 +246,29 @@ func (h *AdminPermissionHandler) DeleteRole(c *gin.Context) {
 	// 检查是否有用户使用该角色
 	var userCount int64
 	h.db.Model(&models.User{}).Where("role = ?", role.Code).Count(&userCount)
+	if userCount == 0 {
+		h.db.Table("user_roles").Where("role_id = ?", role.ID).Count(&userCount)
+	}
 	if userCount > 0 {
 		response.Error(c, http.StatusBadRequest, "该角色正在被使用，无法删除")
 		return
 	}
 
-	if err := h.db.Delete(&role).Error; err != nil {
+	// 检查是否有角色继承该角色
+	var childCount int64
+	h.db.Model(&models.Role{}).Where("parent_id = ?", role.ID).Count(&childCount)
+	if childCount > 0 {
+		response.Error(c, http.StatusBadRequest, "有其他角色继承该角色，请先调整这些角色的上级角色")
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Association("Menus").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除角色失败")
 		return
 	}
@@ -209,6 +276,82 @@ func (h *AdminPermissionHandler) DeleteRole(c *gin.Context) {
 	response.Success(c, nil, "删除成功")
 }
 
+// ============ 权限目录 ============
+
+// GetPermissions 获取按模块分组的权限目录，以及每个权限保护的路由
+// GET /api/v1/admin/permissions
+func (h *AdminPermissionHandler) GetPermissions(c *gin.Context) {
+	routesByCode := map[string][]permission.Route{}
+	for _, route := range h.permissions.Routes() {
+		routesByCode[route.Permission] = append(routesByCode[route.Permission], route)
+	}
+
+	modules := make([]gin.H, 0, len(h.permissions.Modules()))
+	for _, module := range h.permissions.Modules() {
+		items := make([]gin.H, 0, len(module.Permissions))
+		for _, def := range module.Permissions {
+			routes := routesByCode[def.Code]
+			if routes == nil {
+				routes = []permission.Route{}
+			}
+			items = append(items, gin.H{
+				"code":        def.Code,
+				"description": def.Description,
+				"routes":      routes,
+			})
+		}
+		modules = append(modules, gin.H{
+			"code":        module.Code,
+			"name":        module.Name,
+			"permissions": items,
+		})
+	}
+
+	response.Success(c, modules, "获取成功")
+}
+
+// GetRoleRoutes 统计各角色（含继承的权限）可访问的路由，可用 role_id 只看一个角色
+// GET /api/v1/admin/permissions/role-routes
+func (h *AdminPermissionHandler) GetRoleRoutes(c *gin.Context) {
+	// 继承需要用到全部角色，因此总是加载全部角色后再筛选
+	var roles []models.Role
+	if err := h.db.Order("created_at ASC").Find(&roles).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色列表失败")
+		return
+	}
+	roleID := c.Query("role_id")
+
+	allRoutes := h.permissions.Routes()
+	items := make([]gin.H, 0, len(roles))
+	for _, role := range roles {
+		if roleID != "" && role.ID.String() != roleID {
+			continue
+		}
+		set := permission.Resolve(roles, []string{role.Code})
+
+		reachable := []permission.Route{}
+		for _, route := range allRoutes {
+			if set.Has(route.Permission) {
+				reachable = append(reachable, route)
+			}
+		}
+
+		items = append(items, gin.H{
+			"id":                  role.ID,
+			"code":                role.Code,
+			"name":                role.Name,
+			"effective_roles":     set.Roles,
+			"all_permissions":     set.All(),
+			"unknown_permissions": h.unknownGrants(set),
+			"routes":              reachable,
+			"reachable":           len(reachable),
+			"total":               len(allRoutes),
+		})
+	}
+
+	response.Success(c, items, "获取成功")
+}
+
 // ============ 菜单管理 ============
 
 // GetMenus 获取菜单列表
@@ -284,6 +427,15 @@ func (h *AdminPermissionHandler) CreateMenu(c *gin.Context) {
 		return
 	}
 
+	if req.ParentID != nil {
+		var count int64
+		h.db.Model(&models.Menu{}).Where("id = ?", *req.ParentID).Count(&count)
+		if count == 0 {
+			response.Error(c, http.StatusBadRequest, "父菜单不存在")
+			return
+		}
+	}
+
 	menu := models.Menu{
 		Name:     req.Name,
 		Path:     req.Path,
@@ -300,7 +452,7 @@ func (h *AdminPermissionHandler) CreateMenu(c *gin.Context) {
 	response.Success(c, menu, "创建成功")
 }
 
-// UpdateMenu 更新菜单
+// UpdateMenu 更新菜单，parent_id 传全零 UUID 表示移动到顶级
 // PUT /api/v1/admin/menus/:id
 func (h *AdminPermissionHandler) UpdateMenu(c *gin.Context) {
 	id := c.Param("id")
@@ -338,7 +490,15 @@ func (h *AdminPermissionHandler) UpdateMenu(c *gin.Context) {
 		menu.Icon = *req.Icon
 	}
 	if req.ParentID != nil {
-		menu.ParentID = req.ParentID
+		if *req.ParentID == uuid.Nil {
+			menu.ParentID = nil
+		} else {
+			if msg := h.checkMenuParent(menu.ID, *req.ParentID); msg != "" {
+				response.Error(c, http.StatusBadRequest, msg)
+				return
+			}
+			menu.ParentID = req.ParentID
+		}
 	}
 	if req.Sort != nil {
 		menu.Sort = *req.Sort
@@ -375,10 +535,469 @@ func (h *AdminPermissionHandler) DeleteMenu(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&menu).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_menus WHERE menu_id = ?", menu.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&menu).Error
+	})
+	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除菜单失败")
 		return
 	}
 
 	response.Success(c, nil, "删除成功")
 }
+
+// GetMenuTree 获取完整的菜单树
+// GET /api/v1/admin/menus/tree
+func (h *AdminPermissionHandler) GetMenuTree(c *gin.Context) {
+	var menus []models.Menu
+	if err := h.db.Order("sort ASC, created_at ASC").Find(&menus).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取菜单失败")
+		return
+	}
+
+	response.Success(c, buildMenuTree(menus, nil), "获取成功")
+}
+
+// GetMyMenus 获取当前管理员可见的菜单树（所有有效角色的菜单合并），用于渲染侧边栏
+// GET /api/v1/admin/my-menus
+func (h *AdminPermissionHandler) GetMyMenus(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	id, _ := userID.(uuid.UUID)
+	roleCode, _ := userRole.(string)
+
+	var menus []models.Menu
+	if err := h.db.Order("sort ASC, created_at ASC").Find(&menus).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取菜单失败")
+		return
+	}
+
+	// 拥有全部权限的角色（包括 roles 表尚未初始化时的超级管理员）能看到全部菜单，与权限校验保持一致
+	set, err := permission.ForUser(h.db, id, roleCode)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色失败")
+		return
+	}
+	if set.All() {
+		response.Success(c, buildMenuTree(menus, nil), "获取成功")
+		return
+	}
+
+	var assigned []uuid.UUID
+	if len(set.Roles) > 0 {
+		if err := h.db.Table("role_menus").
+			Joins("JOIN roles ON roles.id = role_menus.role_id").
+			Where("roles.code IN ?", set.Roles).
+			Distinct().Pluck("role_menus.menu_id", &assigned).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "获取菜单失败")
+			return
+		}
+	}
+
+	// 只分配了子菜单时补齐其所有上级分组，否则侧边栏无法挂载
+	parents := make(map[uuid.UUID]*uuid.UUID, len(menus))
+	for _, menu := range menus {
+		parents[menu.ID] = menu.ParentID
+	}
+	visible := make(map[uuid.UUID]bool, len(assigned))
+	for _, menuID := range assigned {
+		for id := &menuID; id != nil && !visible[*id]; id = parents[*id] {
+			visible[*id] = true
+		}
+	}
+
+	response.Success(c, buildMenuTree(menus, visible), "获取成功")
+}
+
+// GetRoleMenus 获取角色已分配的菜单ID
+// GET /api/v1/admin/roles/:id/menus
+func (h *AdminPermissionHandler) GetRoleMenus(c *gin.Context) {
+	id := c.Param("id")
+
+	var role models.Role
+	if err := h.db.Where("id = ?", id).Preload("Menus").First(&role).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, http.StatusNotFound, "角色不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "获取角色失败")
+		return
+	}
+
+	response.Success(c, gin.H{"menu_ids": roleMenuIDs(role.Menus)}, "获取成功")
+}
+
+// UpdateRoleMenus 批量设置角色的菜单，覆盖原有分配
+// PUT /api/v1/admin/roles/:id/menus
+func (h *AdminPermissionHandler) UpdateRoleMenus(c *gin.Context) {
+	id := c.Param("id")
+
+	var req struct {
+		MenuIDs []uuid.UUID `json:"menu_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.MenuIDs == nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 menu_ids")
+		return
+	}
+
+	var role models.Role
+	if err := h.db.Where("id = ?", id).Preload("Menus").First(&role).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, http.StatusNotFound, "角色不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "获取角色失败")
+		return
+	}
+
+	menus := []models.Menu{}
+	if len(req.MenuIDs) > 0 {
+		if err := h.db.Where("id IN ?", req.MenuIDs).Find(&menus).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "获取菜单失败")
+			return
+		}
+		unique := make(map[uuid.UUID]bool, len(req.MenuIDs))
+		for _, menuID := range req.MenuIDs {
+			unique[menuID] = true
+		}
+		if len(menus) != len(unique) {
+			response.Error(c, http.StatusBadRequest, "部分菜单不存在")
+			return
+		}
+	}
+
+	audit.SetBefore(c, gin.H{"menu_ids": roleMenuIDs(role.Menus)})
+	if err := h.db.Model(&role).Association("Menus").Replace(menus); err != nil {
+		response.Error(c, http.StatusInternalServerError, "更新角色菜单失败")
+		return
+	}
+	audit.SetAfter(c, gin.H{"menu_ids": roleMenuIDs(menus)})
+
+	response.Success(c, gin.H{"menu_ids": roleMenuIDs(menus)}, "更新成功")
+}
+
+// checkMenuParent 校验新的父菜单存在且不是菜单自身或其子孙，返回错误提示
+func (h *AdminPermissionHandler) checkMenuParent(id, parentID uuid.UUID) string {
+	if parentID == id {
+		return "不能将菜单设为自己的父菜单"
+	}
+
+	var menus []models.Menu
+	if err := h.db.Select("id", "parent_id").Find(&menus).Error; err != nil {
+		return "获取菜单失败"
+	}
+	parents := make(map[uuid.UUID]*uuid.UUID, len(menus))
+	for _, menu := range menus {
+		parents[menu.ID] = menu.ParentID
+	}
+	if _, ok := parents[parentID]; !ok {
+		return "父菜单不存在"
+	}
+
+	if hasAncestor(parents, parentID, id) {
+		return "不能将菜单移动到自己的子菜单下"
+	}
+	return ""
+}
+
+// checkRoleParent 校验新的上级角色存在且不是角色自身或继承它的角色，返回错误提示
+func (h *AdminPermissionHandler) checkRoleParent(id, parentID uuid.UUID) string {
+	if parentID == id {
+		return "角色不能继承自身"
+	}
+
+	var roles []models.Role
+	if err := h.db.Select("id", "parent_id").Find(&roles).Error; err != nil {
+		return "获取角色失败"
+	}
+	parents := make(map[uuid.UUID]*uuid.UUID, len(roles))
+	for _, role := range roles {
+		parents[role.ID] = role.ParentID
+	}
+	if _, ok := parents[parentID]; !ok {
+		return "上级角色不存在"
+	}
+
+	if hasAncestor(parents, parentID, id) {
+		return "不能继承自己的下级角色，否则会形成循环继承"
+	}
+	return ""
+}
+
+// hasAncestor 判断从 start 沿 parents 向上（含 start 自身）是否会遇到 target；
+// seen 防止已有数据中的环导致死循环
+func hasAncestor(parents map[uuid.UUID]*uuid.UUID, start, target uuid.UUID) bool {
+	seen := map[uuid.UUID]bool{}
+	for cur := &start; cur != nil && !seen[*cur]; cur = parents[*cur] {
+		if *cur == target {
+			return true
+		}
+		seen[*cur] = true
+	}
+	return false
+}
+
+// unknownGrants 有效权限中不在目录中的权限代码（例如已下线的功能），这些代码不会生效
+func (h *AdminPermissionHandler) unknownGrants(set *permission.Set) []string {
+	unknown := []string{}
+	for code := range set.Grants {
+		if !h.permissions.Known(code) {
+			unknown = append(unknown, code)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// buildMenuTree 把平铺的菜单组装成树，visible 为 nil 时包含全部菜单。
+// menus 需已按排序字段排好序，父菜单不存在或不可见的菜单不会出现在树中
+func buildMenuTree(menus []models.Menu, visible map[uuid.UUID]bool) []models.Menu {
+	children := make(map[uuid.UUID][]models.Menu)
+	var roots []models.Menu
+	for _, menu := range menus {
+		if visible != nil && !visible[menu.ID] {
+			continue
+		}
+		menu.Children = nil
+		if menu.ParentID == nil {
+			roots = append(roots, menu)
+		} else {
+			children[*menu.ParentID] = append(children[*menu.ParentID], menu)
+		}
+	}
+
+	seen := map[uuid.UUID]bool{}
+	var attach func(nodes []models.Menu) []models.Menu
+	attach = func(nodes []models.Menu) []models.Menu {
+		result := make([]models.Menu, 0, len(nodes))
+		for _, node := range nodes {
+			if seen[node.ID] {
+				continue
+			}
+			seen[node.ID] = true
+			node.Children = attach(children[node.ID])
+			result = append(result, node)
+		}
+		return result
+	}
+	return attach(roots)
+}
+
+// roleMenuIDs 提取菜单ID列表
+func roleMenuIDs(menus []models.Menu) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(menus))
+	for _, menu := range menus {
+		ids = append(ids, menu.ID)
+	}
+	return ids
+}
+
+// ============ 用户角色 ============
+
+// GetUserPermissions 计算用户的有效权限：主角色、附加角色及其继承的上级角色的权限并集
+// GET /api/v1/admin/users/:id/permissions
+func (h *AdminPermissionHandler) GetUserPermissions(c *gin.Context) {
+	var user models.User
+	if err := h.db.Where("id = ?", c.Param("id")).Preload("Roles").First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+
+	set, err := permission.ForUser(h.db, user.ID, user.Role)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "计算权限失败")
+		return
+	}
+
+	// 按目录顺序列出拥有的权限及授予它的角色
+	granted := []gin.H{}
+	for _, module := range h.permissions.Modules() {
+		for _, def := range module.Permissions {
+			if !set.Has(def.Code) {
+				continue
+			}
+			grantedBy := set.Grants[def.Code]
+			if grantedBy == nil {
+				grantedBy = set.Grants[permission.All]
+			}
+			granted = append(granted, gin.H{
+				"code":        def.Code,
+				"module":      module.Code,
+				"description": def.Description,
+				"granted_by":  grantedBy,
+			})
+		}
+	}
+
+	response.Success(c, gin.H{
+		"user_id":             user.ID,
+		"role":                user.Role,
+		"roles":               roleCodes(user.Roles),
+		"effective_roles":     set.Roles,
+		"all_permissions":     set.All(),
+		"permissions":         granted,
+		"unknown_permissions": h.unknownGrants(set),
+	}, "获取成功")
+}
+
+// UpdateUserRoles 设置用户的附加角色，覆盖原有分配；主角色仍通过更新用户接口修改。
+// 只能分配当前管理员已拥有其全部权限的角色
+// PUT /api/v1/admin/users/:id/roles
+func (h *AdminPermissionHandler) UpdateUserRoles(c *gin.Context) {
+	var req struct {
+		Roles []string `json:"roles"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Roles == nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供角色代码列表 roles")
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", c.Param("id")).Preload("Roles").First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+
+	if !requireAccountAccess(h.db, c, &user) || !requireRoleGrant(h.db, c, req.Roles) {
+		return
+	}
+
+	roles := []models.Role{}
+	if len(req.Roles) > 0 {
+		if err := h.db.Where("code IN ?", req.Roles).Find(&roles).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "获取角色失败")
+			return
+		}
+		found := make(map[string]bool, len(roles))
+		for _, role := range roles {
+			found[role.Code] = true
+		}
+		for _, code := range req.Roles {
+			if !found[code] {
+				response.Error(c, http.StatusBadRequest, "角色不存在: "+code)
+				return
+			}
+		}
+	}
+
+	audit.SetBefore(c, gin.H{"role": user.Role, "roles": roleCodes(user.Roles)})
+	if err := h.db.Model(&user).Association("Roles").Replace(roles); err != nil {
+		response.Error(c, http.StatusInternalServerError, "更新用户角色失败")
+		return
+	}
+	audit.SetAfter(c, gin.H{"role": user.Role, "roles": roleCodes(roles)})
+
+	response.Success(c, gin.H{"role": user.Role, "roles": roleCodes(roles)}, "更新成功")
+}
+
+// actorPermissions 当前管理员的有效权限
+func actorPermissions(db *gorm.DB, c *gin.Context) (*permission.Set, error) {
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	id, _ := userID.(uuid.UUID)
+	roleCode, _ := userRole.(string)
+	return permission.ForUser(db, id, roleCode)
+}
+
+// requireRoleGrant 为用户设置角色需要 permission:write，且当前管理员必须已拥有这些角色
+// （含继承的上级角色）的全部权限，避免通过给自己或他人分配角色获得自己没有的权限。
+// 未通过时已写入响应
+func requireRoleGrant(db *gorm.DB, c *gin.Context, codes []string) bool {
+	actor, err := actorPermissions(db, c)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色权限失败")
+		return false
+	}
+	if !actor.Has("permission:write") {
+		response.Error(c, http.StatusForbidden, "Access denied: missing permission permission:write")
+		return false
+	}
+	granted, err := permission.ForRoles(db, codes)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色权限失败")
+		return false
+	}
+	if !actor.Covers(granted) {
+		response.Error(c, http.StatusForbidden, "不能分配超出自身权限的角色")
+		return false
+	}
+	return true
+}
+
+// requireRoleEdit 创建或修改角色时，修改后角色（含继承的上级角色）的权限不能超出当前管理员已有的权限，
+// "*" 和继承超级管理员只有拥有全部权限的管理员可以设置；当前管理员自身拥有的角色（含其上级角色）
+// 只有拥有全部权限的管理员可以修改。original 为修改前的角色，创建时为 nil。未通过时已写入响应
+func requireRoleEdit(db *gorm.DB, c *gin.Context, original *models.Role, updated *models.Role) bool {
+	actor, err := actorPermissions(db, c)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色权限失败")
+		return false
+	}
+	if actor.All() {
+		return true
+	}
+	if original != nil {
+		for _, code := range actor.Roles {
+			if code == original.Code {
+				response.Error(c, http.StatusForbidden, "不能修改自己拥有的角色或其上级角色")
+				return false
+			}
+		}
+	}
+
+	var roles []models.Role
+	if err := db.Find(&roles).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色权限失败")
+		return false
+	}
+	// 用修改后的角色替换原记录后计算其有效权限
+	replaced := false
+	for i := range roles {
+		if roles[i].ID == updated.ID {
+			roles[i], replaced = *updated, true
+		}
+	}
+	if !replaced {
+		roles = append(roles, *updated)
+	}
+	if !actor.Covers(permission.Resolve(roles, []string{updated.Code})) {
+		response.Error(c, http.StatusForbidden, "角色的权限（含继承的上级角色）不能超出自身权限")
+		return false
+	}
+	return true
+}
+
+// requireAccountAccess 拥有全部权限的账号（超级管理员）只能由同样拥有全部权限的管理员修改，
+// 包括重置密码和两步验证。未通过时已写入响应
+func requireAccountAccess(db *gorm.DB, c *gin.Context, target *models.User) bool {
+	targetSet, err := permission.ForUser(db, target.ID, target.Role)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色权限失败")
+		return false
+	}
+	if !targetSet.All() {
+		return true
+	}
+	actor, err := actorPermissions(db, c)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取角色权限失败")
+		return false
+	}
+	if !actor.All() {
+		response.Error(c, http.StatusForbidden, "只有超级管理员可以修改超级管理员账号")
+		return false
+	}
+	return true
+}
+
+// roleCodes 提取角色代码列表
+func roleCodes(roles []models.Role) []string {
+	codes := make([]string, 0, len(roles))
+	for _, role := range roles {
+		codes = append(codes, role.Code)
+	}
+	return codes
+}
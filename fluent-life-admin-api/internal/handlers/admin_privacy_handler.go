@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/privacy"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExportUserData 导出用户的全部个人数据（zip 归档，每个实体一个 JSON 文件）
+// POST /api/v1/admin/users/:id/export
+func (h *AdminHandler) ExportUserData(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	var count int64
+	h.db.Unscoped().Model(&models.User{}).Where("id = ?", userID).Count(&count)
+	if count == 0 {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+
+	h.logOperation(c, "ExportUserData", "User", userID.String(), "导出用户个人数据", "")
+
+	filename := fmt.Sprintf("user-%s-%s.zip", userID, time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	// 归档直接写入响应流；开始写出后无法再返回 JSON 错误，只能中断响应
+	if err := privacy.Export(h.db, userID, c.Writer); err != nil {
+		h.logOperation(c, "", "", "", "导出用户个人数据失败: "+err.Error(), "Failure")
+		c.Abort()
+		return
+	}
+}
+
+// EraseUser 擦除用户的全部个人数据，不可恢复
+// POST /api/v1/admin/users/:id/erase
+func (h *AdminHandler) EraseUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	var req struct {
+		ConfirmUsername string `json:"confirm_username" binding:"required"` // 需与用户名一致，防止误操作
+		Reason          string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 confirm_username 和 reason")
+		return
+	}
+
+	// 审计中间件已加载的用户快照包含待擦除的个人信息，不能写入日志
+	audit.DiscardSnapshots(c)
+
+	var user models.User
+	if err := h.db.Unscoped().Where("id = ?", userID).First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+	if req.ConfirmUsername != user.Username {
+		response.Error(c, http.StatusBadRequest, "确认用户名不匹配")
+		return
+	}
+	if user.Role != "user" {
+		response.Error(c, http.StatusBadRequest, "只能擦除普通用户，请先将该账号的角色改为 user")
+		return
+	}
+
+	actorID, _ := c.Get("userID")
+	actorName, _ := c.Get("username")
+	requestedBy, _ := actorID.(uuid.UUID)
+	requestedByName, _ := actorName.(string)
+
+	record, err := privacy.Erase(h.db, privacy.ErasureRequest{
+		UserID:          userID,
+		RequestedBy:     requestedBy,
+		RequestedByName: requestedByName,
+		Reason:          req.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, privacy.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+		h.logOperation(c, "EraseUser", "User", userID.String(), "擦除用户数据失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "擦除用户数据失败")
+		return
+	}
+
+	h.logOperation(c, "EraseUser", "User", userID.String(), "擦除用户数据，记录ID: "+record.ID.String(), "Success")
+	response.Success(c, record, "用户数据已擦除")
+}
+
+// GetDataErasureRecords 获取数据擦除记录
+// GET /api/v1/admin/data-erasure-records
+func (h *AdminHandler) GetDataErasureRecords(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	var records []models.DataErasureRecord
+	var total int64
+
+	query := h.db.Model(&models.DataErasureRecord{})
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&records).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"items":     records,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
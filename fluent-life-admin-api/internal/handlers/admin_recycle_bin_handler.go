@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/recyclebin"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AdminRecycleBinHandler 回收站：查看、恢复和彻底删除已软删除的记录
+type AdminRecycleBinHandler struct {
+	db *gorm.DB
+}
+
+func NewAdminRecycleBinHandler(db *gorm.DB) *AdminRecycleBinHandler {
+	return &AdminRecycleBinHandler{db: db}
+}
+
+// List 获取某类资源的回收站列表，按删除时间倒序
+// GET /api/v1/admin/recycle-bin/:resource
+func (h *AdminRecycleBinHandler) List(res recyclebin.Resource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 20
+		}
+		offset := (page - 1) * pageSize
+
+		items := reflect.New(reflect.SliceOf(reflect.TypeOf(res.Model).Elem())).Interface()
+		var total int64
+
+		query := h.db.Unscoped().Model(res.Model).Where("deleted_at IS NOT NULL")
+		query.Count(&total)
+
+		for _, preload := range res.Preloads {
+			// 关联的用户可能同样已被删除，预加载时不过滤
+			query = query.Preload(preload, func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+		}
+		if err := query.Offset(offset).Limit(pageSize).Order("deleted_at DESC").Find(items).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "查询失败")
+			return
+		}
+
+		response.Success(c, gin.H{
+			"items":     items,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		}, "获取成功")
+	}
+}
+
+// Restore 恢复回收站中的记录，同时恢复随之级联删除的子记录
+// POST /api/v1/admin/recycle-bin/:resource/restore
+func (h *AdminRecycleBinHandler) Restore(res recyclebin.Resource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			IDs []string `json:"ids" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+			response.Error(c, http.StatusBadRequest, "参数错误，需要提供"+res.Label+"ID列表")
+			return
+		}
+
+		restored, err := res.Restore(h.db, req.IDs)
+		if err != nil {
+			audit.Annotate(c, "Restore", auditResourceName(res), strings.Join(req.IDs, ","), "恢复"+res.Label+"失败: "+err.Error(), "Failure")
+			response.Error(c, http.StatusBadRequest, "恢复失败: "+err.Error())
+			return
+		}
+
+		audit.Annotate(c, "Restore", auditResourceName(res), strings.Join(req.IDs, ","), fmt.Sprintf("恢复%s %d 条", res.Label, restored), "Success")
+		response.Success(c, gin.H{"restored": restored}, "恢复成功")
+	}
+}
+
+// Purge 彻底删除回收站中的记录，不可恢复
+// POST /api/v1/admin/recycle-bin/:resource/purge
+func (h *AdminRecycleBinHandler) Purge(res recyclebin.Resource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			IDs []string `json:"ids" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+			response.Error(c, http.StatusBadRequest, "参数错误，需要提供"+res.Label+"ID列表")
+			return
+		}
+
+		purged, err := res.Purge(h.db, req.IDs)
+		if err != nil {
+			audit.Annotate(c, "Purge", auditResourceName(res), strings.Join(req.IDs, ","), "彻底删除"+res.Label+"失败: "+err.Error(), "Failure")
+			response.Error(c, http.StatusInternalServerError, "彻底删除失败")
+			return
+		}
+
+		audit.Annotate(c, "Purge", auditResourceName(res), strings.Join(req.IDs, ","), fmt.Sprintf("彻底删除%s %d 条", res.Label, purged), "Success")
+		response.Success(c, gin.H{"purged": purged}, "彻底删除成功")
+	}
+}
+
+// auditResourceName 审计日志中的资源名与模型名保持一致，例如 Post
+func auditResourceName(res recyclebin.Resource) string {
+	return reflect.TypeOf(res.Model).Elem().Name()
+}
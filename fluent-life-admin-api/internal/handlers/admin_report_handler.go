@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/sanction"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// reportTarget 举报对象对应的内容表
+type reportTarget struct {
+	Label       string
+	Model       interface{}
+	OwnerColumn string // 内容作者所在列，举报用户时为 id
+	// remove 删除被举报内容，nil 表示该类对象不支持删除
+	remove func(tx *gorm.DB, ids []string) error
+}
+
+var reportTargets = map[string]reportTarget{
+	models.ReportTargetPost:    {Label: "帖子", Model: &models.Post{}, OwnerColumn: "user_id", remove: softDeletePosts},
+	models.ReportTargetComment: {Label: "评论", Model: &models.Comment{}, OwnerColumn: "user_id", remove: softDeleteComments},
+	models.ReportTargetRoom:    {Label: "房间", Model: &models.PracticeRoom{}, OwnerColumn: "user_id", remove: softDeleteRooms},
+	models.ReportTargetUser:    {Label: "用户", Model: &models.User{}, OwnerColumn: "id"},
+}
+
+// reportResolutions 案件处理动作及处理后的举报状态
+var reportResolutions = map[string]string{
+	"dismiss": models.ReportStatusDismissed,
+	"remove":  models.ReportStatusResolved,
+	"warn":    models.ReportStatusResolved,
+	"ban":     models.ReportStatusResolved,
+}
+
+// reportCase 同一对象的举报合并后的案件
+type reportCase struct {
+	TargetType      string      `json:"target_type"`
+	TargetID        uuid.UUID   `json:"target_id"`
+	ReportCount     int64       `json:"report_count"`
+	ReporterCount   int64       `json:"reporter_count"`
+	Reasons         string      `json:"reasons"` // 逗号分隔的原因代码
+	FirstReportedAt time.Time   `json:"first_reported_at"`
+	LastReportedAt  time.Time   `json:"last_reported_at"`
+	Target          interface{} `json:"target,omitempty" gorm:"-"`
+}
+
+// GetReportCases 获取举报案件列表，同一对象的举报合并为一个案件，按举报数从多到少排序
+// GET /api/v1/admin/report-cases
+func (h *AdminHandler) GetReportCases(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	query := h.db.Model(&models.Report{}).Where("status = ?", c.DefaultQuery("status", models.ReportStatusPending))
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	grouped := query.Select(`target_type, target_id, COUNT(*) AS report_count, COUNT(DISTINCT reporter_id) AS reporter_count,
+		STRING_AGG(DISTINCT reason, ',') AS reasons, MIN(created_at) AS first_reported_at, MAX(created_at) AS last_reported_at`).
+		Group("target_type, target_id")
+
+	var total int64
+	if err := h.db.Table("(?) AS cases", grouped).Count(&total).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	var cases []reportCase
+	if err := h.db.Table("(?) AS cases", grouped).Order("report_count DESC, first_reported_at ASC").
+		Offset(offset).Limit(pageSize).Scan(&cases).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	for i := range cases {
+		cases[i].Target = h.loadReportTarget(cases[i].TargetType, cases[i].TargetID)
+	}
+
+	response.Success(c, gin.H{
+		"items":     cases,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// GetReportCase 获取案件详情：被举报对象及其全部举报
+// GET /api/v1/admin/report-cases/:target_type/:target_id
+func (h *AdminHandler) GetReportCase(c *gin.Context) {
+	targetType := c.Param("target_type")
+	if _, ok := reportTargets[targetType]; !ok {
+		response.Error(c, http.StatusBadRequest, "无效的举报对象类型")
+		return
+	}
+	targetID, err := uuid.Parse(c.Param("target_id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的举报对象ID")
+		return
+	}
+
+	var reports []models.Report
+	if err := h.db.Preload("Reporter", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("created_at DESC").Find(&reports).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	if len(reports) == 0 {
+		response.Error(c, http.StatusNotFound, "该对象没有举报记录")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"target_type": targetType,
+		"target_id":   targetID,
+		"target":      h.loadReportTarget(targetType, targetID),
+		"reports":     reports,
+	}, "获取成功")
+}
+
+// GetReports 获取举报列表（不合并）
+// GET /api/v1/admin/reports
+func (h *AdminHandler) GetReports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	var reports []models.Report
+	var total int64
+
+	query := h.db.Model(&models.Report{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if reporterID := c.Query("reporter_id"); reporterID != "" {
+		query = query.Where("reporter_id = ?", reporterID)
+	}
+
+	query.Count(&total)
+
+	if err := query.Preload("Reporter", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&reports).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"items":     reports,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// ResolveReportCase 处理案件：驳回、删除内容、警告或封禁作者，该对象所有待处理的举报一并结案
+// POST /api/v1/admin/report-cases/:target_type/:target_id/resolve
+func (h *AdminHandler) ResolveReportCase(c *gin.Context) {
+	targetType := c.Param("target_type")
+	target, ok := reportTargets[targetType]
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "无效的举报对象类型")
+		return
+	}
+	targetID, err := uuid.Parse(c.Param("target_id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的举报对象ID")
+		return
+	}
+
+	var req struct {
+		Action        string `json:"action" binding:"required"` // dismiss, remove, warn, ban
+		Note          string `json:"note"`
+		DurationHours *int   `json:"duration_hours"` // ban 的时长，为空表示永久
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供处理动作 action")
+		return
+	}
+	status, ok := reportResolutions[req.Action]
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "无效的处理动作，可选 dismiss、remove、warn、ban")
+		return
+	}
+	if req.Action == "remove" && target.remove == nil {
+		response.Error(c, http.StatusBadRequest, "该类对象不支持删除，请使用 warn 或 ban")
+		return
+	}
+
+	ownerID, err := h.reportTargetOwner(target, targetID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, target.Label+"不存在")
+		return
+	}
+	if req.Action == "ban" || req.Action == "warn" {
+		var owner models.User
+		if err := h.db.Unscoped().Where("id = ?", ownerID).First(&owner).Error; err != nil {
+			response.Error(c, http.StatusNotFound, "内容作者不存在")
+			return
+		}
+		if owner.Role != "user" {
+			response.Error(c, http.StatusBadRequest, "不能通过举报处理后台账号")
+			return
+		}
+	}
+
+	resolverID, _ := c.Get("userID")
+	resolver, _ := resolverID.(uuid.UUID)
+	now := time.Now()
+
+	audit.SetBefore(c, audit.Snapshot(h.db.Unscoped(), target.Model, "id", targetID))
+
+	var ban *models.UserSanction
+	if req.Action == "ban" {
+		reason := "举报处理：" + target.Label + " " + targetID.String()
+		if req.Note != "" {
+			reason += "，" + req.Note
+		}
+		var msg string
+		if ban, msg = newSanction(c, ownerID, models.SanctionBan, reason, nil, nil, req.DurationHours); msg != "" {
+			response.Error(c, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	var resolved int64
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var firstReport models.Report
+		if err := tx.Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.ReportStatusPending).
+			Order("created_at ASC").First(&firstReport).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errNoPendingReports
+			}
+			return err
+		}
+
+		result := tx.Model(&models.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.ReportStatusPending).
+			Updates(map[string]interface{}{
+				"status":          status,
+				"resolution":      req.Action,
+				"resolution_note": req.Note,
+				"resolved_by":     resolver,
+				"resolved_at":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		resolved = result.RowsAffected
+
+		switch req.Action {
+		case "remove":
+			return target.remove(tx, []string{targetID.String()})
+		case "ban":
+			ban.ReportID = &firstReport.ID
+			if err := sanction.Issue(tx, ban); err != nil {
+				return err
+			}
+			return revokeUserSessions(tx, ownerID, revokeReasonDisabled)
+		}
+		// warn 只记录在举报结案信息和审计日志中
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errNoPendingReports) {
+			response.Error(c, http.StatusBadRequest, "该对象没有待处理的举报")
+			return
+		}
+		audit.Annotate(c, "ResolveReport", "Report", targetID.String(), "处理举报失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "处理举报失败")
+		return
+	}
+
+	audit.SetAfter(c, audit.Snapshot(h.db.Unscoped(), target.Model, "id", targetID))
+	details := fmt.Sprintf("处理%s举报 %d 条：%s", target.Label, resolved, req.Action)
+	if req.Action == "warn" || req.Action == "ban" {
+		details += "，作者 " + ownerID.String()
+	}
+	audit.Annotate(c, "ResolveReport", "Report", targetID.String(), details, "Success")
+	response.Success(c, gin.H{"resolved": resolved, "status": status}, "处理成功")
+}
+
+// GetReportStats 获取举报统计
+// GET /api/v1/admin/report-stats
+func (h *AdminHandler) GetReportStats(c *gin.Context) {
+	var stats struct {
+		TotalCount        int64            `json:"total_count"`
+		PendingCount      int64            `json:"pending_count"`
+		ResolvedCount     int64            `json:"resolved_count"`
+		DismissedCount    int64            `json:"dismissed_count"`
+		PendingCaseCount  int64            `json:"pending_case_count"`
+		TodayCount        int64            `json:"today_count"`
+		PendingByTarget   map[string]int64 `json:"pending_by_target"`
+		ByReason          map[string]int64 `json:"by_reason"`
+		ResolutionSummary map[string]int64 `json:"resolution_summary"`
+	}
+
+	h.db.Model(&models.Report{}).Count(&stats.TotalCount)
+	h.db.Model(&models.Report{}).Where("status = ?", models.ReportStatusPending).Count(&stats.PendingCount)
+	h.db.Model(&models.Report{}).Where("status = ?", models.ReportStatusResolved).Count(&stats.ResolvedCount)
+	h.db.Model(&models.Report{}).Where("status = ?", models.ReportStatusDismissed).Count(&stats.DismissedCount)
+	pendingCases := h.db.Model(&models.Report{}).Select("target_type, target_id").
+		Where("status = ?", models.ReportStatusPending).Group("target_type, target_id")
+	h.db.Table("(?) AS cases", pendingCases).Count(&stats.PendingCaseCount)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	h.db.Model(&models.Report{}).Where("created_at >= ?", today).Count(&stats.TodayCount)
+
+	stats.PendingByTarget = h.countReportsBy("target_type", "status = ?", models.ReportStatusPending)
+	stats.ByReason = h.countReportsBy("reason", "1 = 1")
+	stats.ResolutionSummary = h.countReportsBy("resolution", "resolution IS NOT NULL AND resolution <> ''")
+
+	response.Success(c, stats, "获取成功")
+}
+
+// errNoPendingReports 对象没有待处理的举报
+var errNoPendingReports = errors.New("no pending reports")
+
+// countReportsBy 按列分组统计举报数
+func (h *AdminHandler) countReportsBy(column, where string, args ...interface{}) map[string]int64 {
+	var rows []struct {
+		Key   string
+		Count int64
+	}
+	h.db.Model(&models.Report{}).Select(column+" AS key, COUNT(*) AS count").Where(where, args...).Group(column).Scan(&rows)
+	result := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		result[row.Key] = row.Count
+	}
+	return result
+}
+
+// reportTargetOwner 被举报内容的作者；举报用户时为用户本身。已删除的内容同样可以查到
+func (h *AdminHandler) reportTargetOwner(target reportTarget, targetID uuid.UUID) (uuid.UUID, error) {
+	var owner struct {
+		OwnerID uuid.UUID
+	}
+	err := h.db.Unscoped().Model(target.Model).Select(target.OwnerColumn+" AS owner_id").
+		Where("id = ?", targetID).Take(&owner).Error
+	return owner.OwnerID, err
+}
+
+// loadReportTarget 加载被举报对象用于展示，已删除的对象同样返回
+func (h *AdminHandler) loadReportTarget(targetType string, targetID uuid.UUID) interface{} {
+	unscopedUser := func(db *gorm.DB) *gorm.DB { return db.Unscoped() }
+	var err error
+	var target interface{}
+	switch targetType {
+	case models.ReportTargetPost:
+		var post models.Post
+		err = h.db.Unscoped().Preload("User", unscopedUser).Where("id = ?", targetID).First(&post).Error
+		target = post
+	case models.ReportTargetComment:
+		var comment models.Comment
+		err = h.db.Unscoped().Preload("User", unscopedUser).Where("id = ?", targetID).First(&comment).Error
+		target = comment
+	case models.ReportTargetRoom:
+		var room models.PracticeRoom
+		err = h.db.Unscoped().Preload("User", unscopedUser).Where("id = ?", targetID).First(&room).Error
+		target = room
+	case models.ReportTargetUser:
+		var user models.User
+		err = h.db.Unscoped().Where("id = ?", targetID).First(&user).Error
+		target = user
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	return target
+}
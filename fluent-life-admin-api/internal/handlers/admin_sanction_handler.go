@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/sanction"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateUserSanction 处罚用户：封禁、禁言、禁止创建房间或禁止随机匹配
+// POST /api/v1/admin/users/:id/sanctions
+func (h *AdminHandler) CreateUserSanction(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	var req struct {
+		Type          string     `json:"type" binding:"required"`
+		Reason        string     `json:"reason" binding:"required"`
+		StartsAt      *time.Time `json:"starts_at"`      // 为空表示立即生效
+		EndsAt        *time.Time `json:"ends_at"`        // 与 duration_hours 二选一，都为空表示永久
+		DurationHours *int       `json:"duration_hours"` // 从开始时间起算的时长
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 type 和 reason")
+		return
+	}
+	if _, ok := models.SanctionTypes[req.Type]; !ok {
+		response.Error(c, http.StatusBadRequest, "无效的处罚类型，可选 ban、mute、room_block、match_block")
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+	if user.Role != "user" {
+		response.Error(c, http.StatusBadRequest, "只能处罚普通用户")
+		return
+	}
+
+	s, msg := newSanction(c, userID, req.Type, req.Reason, req.StartsAt, req.EndsAt, req.DurationHours)
+	if msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := sanction.Issue(tx, s); err != nil {
+			return err
+		}
+		if s.Type == models.SanctionBan && s.StateAt(time.Now()) == models.SanctionStateActive {
+			return revokeUserSessions(tx, userID, revokeReasonDisabled)
+		}
+		return nil
+	})
+	if err != nil {
+		audit.Annotate(c, "CreateUserSanction", "User", userID.String(), "处罚用户失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "处罚用户失败")
+		return
+	}
+
+	s.State = s.StateAt(time.Now())
+	// 审计快照记录的是用户本身（封禁会改变 status），处罚记录ID写在描述中
+	audit.Annotate(c, "CreateUserSanction", "User", userID.String(),
+		fmt.Sprintf("%s用户 %s：%s（处罚ID %s）", models.SanctionTypes[s.Type], user.Username, s.Reason, s.ID), "Success")
+	response.Success(c, s, "处罚成功")
+}
+
+// GetUserSanctions 获取用户的处罚历史
+// GET /api/v1/admin/users/:id/sanctions
+func (h *AdminHandler) GetUserSanctions(c *gin.Context) {
+	userID := c.Param("id")
+
+	var sanctions []models.UserSanction
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&sanctions).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	now := time.Now()
+	active := map[string]bool{}
+	for i := range sanctions {
+		sanctions[i].State = sanctions[i].StateAt(now)
+		if sanctions[i].State == models.SanctionStateActive {
+			active[sanctions[i].Type] = true
+		}
+	}
+
+	response.Success(c, gin.H{
+		"items":        sanctions,
+		"total":        len(sanctions),
+		"active_types": active,
+	}, "获取成功")
+}
+
+// GetSanctions 获取处罚列表
+// GET /api/v1/admin/sanctions
+func (h *AdminHandler) GetSanctions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	var sanctions []models.UserSanction
+	var total int64
+	now := time.Now()
+
+	query := h.db.Model(&models.UserSanction{})
+	if c.Query("active") == "true" {
+		query = sanction.Active(query, now)
+	}
+	if sanctionType := c.Query("type"); sanctionType != "" {
+		query = query.Where("type = ?", sanctionType)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&sanctions).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	for i := range sanctions {
+		sanctions[i].State = sanctions[i].StateAt(now)
+	}
+
+	response.Success(c, gin.H{
+		"items":     sanctions,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// RevokeSanction 提前解除处罚
+// POST /api/v1/admin/sanctions/:id/revoke
+func (h *AdminHandler) RevokeSanction(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的处罚ID")
+		return
+	}
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供解除原因 reason")
+		return
+	}
+
+	actorID, _ := c.Get("userID")
+	actor, _ := actorID.(uuid.UUID)
+
+	var revoked *models.UserSanction
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revoked, err = sanction.Revoke(tx, id, actor, req.Reason)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.Error(c, http.StatusNotFound, "处罚记录不存在")
+		case errors.Is(err, sanction.ErrNotActive):
+			response.Error(c, http.StatusBadRequest, "处罚已解除或已到期")
+		default:
+			audit.Annotate(c, "RevokeSanction", "UserSanction", id.String(), "解除处罚失败: "+err.Error(), "Failure")
+			response.Error(c, http.StatusInternalServerError, "解除处罚失败")
+		}
+		return
+	}
+
+	audit.Annotate(c, "RevokeSanction", "UserSanction", id.String(),
+		fmt.Sprintf("解除用户 %s 的%s：%s", revoked.UserID, models.SanctionTypes[revoked.Type], req.Reason), "Success")
+	response.Success(c, nil, "已解除处罚")
+}
+
+// newSanction 按请求参数构造处罚记录，参数无效时返回错误提示
+func newSanction(c *gin.Context, userID uuid.UUID, sanctionType, reason string, startsAt, endsAt *time.Time, durationHours *int) (*models.UserSanction, string) {
+	now := time.Now()
+	s := &models.UserSanction{
+		UserID:   userID,
+		Type:     sanctionType,
+		Reason:   reason,
+		StartsAt: now,
+	}
+	if startsAt != nil && startsAt.After(now) {
+		s.StartsAt = *startsAt
+	}
+	switch {
+	case endsAt != nil && durationHours != nil:
+		return nil, "ends_at 和 duration_hours 只能提供一个"
+	case durationHours != nil:
+		if *durationHours <= 0 {
+			return nil, "处罚时长必须大于 0"
+		}
+		end := s.StartsAt.Add(time.Duration(*durationHours) * time.Hour)
+		s.EndsAt = &end
+	case endsAt != nil:
+		if !endsAt.After(s.StartsAt) {
+			return nil, "结束时间必须晚于开始时间"
+		}
+		s.EndsAt = endsAt
+	}
+
+	if v, ok := c.Get("userID"); ok {
+		s.IssuedBy, _ = v.(uuid.UUID)
+	}
+	if v, ok := c.Get("username"); ok {
+		s.IssuedByName, _ = v.(string)
+	}
+	return s, ""
+}
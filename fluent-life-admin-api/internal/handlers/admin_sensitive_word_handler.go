@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/sensitive"
+	"fluent-life-admin-api/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// categoryCodePattern 分类代码只允许小写字母、数字和下划线
+var categoryCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// AdminSensitiveWordHandler 敏感词词库管理
+type AdminSensitiveWordHandler struct {
+	db     *gorm.DB
+	filter *sensitive.Filter
+}
+
+func NewAdminSensitiveWordHandler(db *gorm.DB, filter *sensitive.Filter) *AdminSensitiveWordHandler {
+	return &AdminSensitiveWordHandler{db: db, filter: filter}
+}
+
+// GetCategories 获取敏感词分类列表（含词条数量）
+// GET /api/v1/admin/sensitive-word-categories
+func (h *AdminSensitiveWordHandler) GetCategories(c *gin.Context) {
+	var categories []models.SensitiveWordCategory
+	if err := h.db.Order("created_at ASC").Find(&categories).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	var counts []struct {
+		CategoryID uuid.UUID
+		Count      int64
+	}
+	h.db.Model(&models.SensitiveWord{}).Select("category_id, COUNT(*) AS count").Group("category_id").Scan(&counts)
+	countByID := make(map[uuid.UUID]int64, len(counts))
+	for _, row := range counts {
+		countByID[row.CategoryID] = row.Count
+	}
+
+	items := make([]gin.H, 0, len(categories))
+	for _, category := range categories {
+		items = append(items, gin.H{
+			"id":          category.ID,
+			"code":        category.Code,
+			"name":        category.Name,
+			"description": category.Description,
+			"action":      category.Action,
+			"risk_score":  category.RiskScore,
+			"enabled":     category.Enabled,
+			"word_count":  countByID[category.ID],
+			"created_at":  category.CreatedAt,
+			"updated_at":  category.UpdatedAt,
+		})
+	}
+
+	response.Success(c, items, "获取成功")
+}
+
+// CreateCategory 创建敏感词分类
+// POST /api/v1/admin/sensitive-word-categories
+func (h *AdminSensitiveWordHandler) CreateCategory(c *gin.Context) {
+	var req struct {
+		Code        string `json:"code" binding:"required"`
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		Action      string `json:"action"`
+		RiskScore   *int   `json:"risk_score"`
+		Enabled     *bool  `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	category := models.SensitiveWordCategory{
+		Code:        strings.TrimSpace(req.Code),
+		Name:        req.Name,
+		Description: req.Description,
+		Action:      req.Action,
+		RiskScore:   50,
+		Enabled:     true,
+	}
+	if category.Action == "" {
+		category.Action = models.SensitiveActionReview
+	}
+	if req.RiskScore != nil {
+		category.RiskScore = *req.RiskScore
+	}
+	if req.Enabled != nil {
+		category.Enabled = *req.Enabled
+	}
+	if msg := validateSensitiveCategory(&category); msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.SensitiveWordCategory{}).Where("code = ?", category.Code).Count(&count)
+	if count > 0 {
+		response.Error(c, http.StatusBadRequest, "分类代码已存在")
+		return
+	}
+
+	if err := h.db.Create(&category).Error; err != nil {
+		audit.Annotate(c, "CreateSensitiveWordCategory", "SensitiveWordCategory", "", "创建敏感词分类失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "创建失败")
+		return
+	}
+	h.filter.Invalidate()
+
+	audit.Annotate(c, "CreateSensitiveWordCategory", "SensitiveWordCategory", category.ID.String(), "创建敏感词分类: "+category.Code, "Success")
+	response.Success(c, category, "创建成功")
+}
+
+// UpdateCategory 更新敏感词分类
+// PUT /api/v1/admin/sensitive-word-categories/:id
+func (h *AdminSensitiveWordHandler) UpdateCategory(c *gin.Context) {
+	id := c.Param("id")
+	var req struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Action      *string `json:"action"`
+		RiskScore   *int    `json:"risk_score"`
+		Enabled     *bool   `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	var category models.SensitiveWordCategory
+	if err := h.db.Where("id = ?", id).First(&category).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "分类不存在")
+		return
+	}
+
+	if req.Name != nil {
+		category.Name = *req.Name
+	}
+	if req.Description != nil {
+		category.Description = *req.Description
+	}
+	if req.Action != nil {
+		category.Action = *req.Action
+	}
+	if req.RiskScore != nil {
+		category.RiskScore = *req.RiskScore
+	}
+	if req.Enabled != nil {
+		category.Enabled = *req.Enabled
+	}
+	if msg := validateSensitiveCategory(&category); msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.db.Save(&category).Error; err != nil {
+		audit.Annotate(c, "UpdateSensitiveWordCategory", "SensitiveWordCategory", id, "更新敏感词分类失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "更新失败")
+		return
+	}
+	h.filter.Invalidate()
+
+	audit.Annotate(c, "UpdateSensitiveWordCategory", "SensitiveWordCategory", id, "更新敏感词分类: "+category.Code, "Success")
+	response.Success(c, category, "更新成功")
+}
+
+// DeleteCategory 删除敏感词分类及其全部词条
+// DELETE /api/v1/admin/sensitive-word-categories/:id
+func (h *AdminSensitiveWordHandler) DeleteCategory(c *gin.Context) {
+	id := c.Param("id")
+
+	var category models.SensitiveWordCategory
+	if err := h.db.Where("id = ?", id).First(&category).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "分类不存在")
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ?", category.ID).Delete(&models.SensitiveWord{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		audit.Annotate(c, "DeleteSensitiveWordCategory", "SensitiveWordCategory", id, "删除敏感词分类失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	h.filter.Invalidate()
+
+	audit.Annotate(c, "DeleteSensitiveWordCategory", "SensitiveWordCategory", id, "删除敏感词分类: "+category.Code, "Success")
+	response.Success(c, nil, "删除成功")
+}
+
+// GetWords 获取敏感词列表
+// GET /api/v1/admin/sensitive-words
+func (h *AdminSensitiveWordHandler) GetWords(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	var words []models.SensitiveWord
+	var total int64
+
+	query := h.db.Model(&models.SensitiveWord{}).Preload("Category")
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		query = query.Where("category_id = ?", categoryID)
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		query = query.Where("word LIKE ?", "%"+keyword+"%")
+	}
+	if enabled := c.Query("enabled"); enabled != "" {
+		query = query.Where("enabled = ?", enabled == "true")
+	}
+
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&words).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"items":     words,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// CreateWords 批量添加敏感词，已存在的词跳过
+// POST /api/v1/admin/sensitive-words
+func (h *AdminSensitiveWordHandler) CreateWords(c *gin.Context) {
+	var req struct {
+		CategoryID string   `json:"category_id" binding:"required"`
+		Words      []string `json:"words" binding:"required"`
+		IsRegex    bool     `json:"is_regex"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Words) == 0 {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 category_id 和 words")
+		return
+	}
+
+	var category models.SensitiveWordCategory
+	if err := h.db.Where("id = ?", req.CategoryID).First(&category).Error; err != nil {
+		response.Error(c, http.StatusBadRequest, "分类不存在")
+		return
+	}
+
+	seen := map[string]bool{}
+	var words []models.SensitiveWord
+	for _, w := range req.Words {
+		w = strings.TrimSpace(w)
+		if w == "" || seen[w] {
+			continue
+		}
+		seen[w] = true
+		if msg := validateSensitiveWord(w, req.IsRegex); msg != "" {
+			response.Error(c, http.StatusBadRequest, msg)
+			return
+		}
+		words = append(words, models.SensitiveWord{CategoryID: category.ID, Word: w, IsRegex: req.IsRegex, Enabled: true})
+	}
+
+	var existing []string
+	h.db.Model(&models.SensitiveWord{}).Where("category_id = ? AND word IN ?", category.ID, req.Words).Pluck("word", &existing)
+	exists := make(map[string]bool, len(existing))
+	for _, w := range existing {
+		exists[w] = true
+	}
+	toCreate := make([]models.SensitiveWord, 0, len(words))
+	for _, w := range words {
+		if !exists[w.Word] {
+			toCreate = append(toCreate, w)
+		}
+	}
+
+	if len(toCreate) > 0 {
+		if err := h.db.CreateInBatches(&toCreate, 500).Error; err != nil {
+			audit.Annotate(c, "CreateSensitiveWords", "SensitiveWord", "", "添加敏感词失败: "+err.Error(), "Failure")
+			response.Error(c, http.StatusInternalServerError, "添加失败")
+			return
+		}
+		h.filter.Invalidate()
+	}
+
+	audit.Annotate(c, "CreateSensitiveWords", "SensitiveWord", category.ID.String(),
+		fmt.Sprintf("向分类 %s 添加敏感词 %d 个，跳过已存在 %d 个", category.Code, len(toCreate), len(words)-len(toCreate)), "Success")
+	response.Success(c, gin.H{"created": len(toCreate), "skipped": len(words) - len(toCreate)}, "添加成功")
+}
+
+// UpdateWord 更新敏感词
+// PUT /api/v1/admin/sensitive-words/:id
+func (h *AdminSensitiveWordHandler) UpdateWord(c *gin.Context) {
+	id := c.Param("id")
+	var req struct {
+		CategoryID *string `json:"category_id"`
+		Word       *string `json:"word"`
+		IsRegex    *bool   `json:"is_regex"`
+		Enabled    *bool   `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	var word models.SensitiveWord
+	if err := h.db.Where("id = ?", id).First(&word).Error; err != nil {
+		response.Error(c, http.StatusNotFound, "敏感词不存在")
+		return
+	}
+
+	if req.CategoryID != nil {
+		var category models.SensitiveWordCategory
+		if err := h.db.Where("id = ?", *req.CategoryID).First(&category).Error; err != nil {
+			response.Error(c, http.StatusBadRequest, "分类不存在")
+			return
+		}
+		word.CategoryID = category.ID
+	}
+	if req.Word != nil {
+		word.Word = strings.TrimSpace(*req.Word)
+	}
+	if req.IsRegex != nil {
+		word.IsRegex = *req.IsRegex
+	}
+	if req.Enabled != nil {
+		word.Enabled = *req.Enabled
+	}
+	if msg := validateSensitiveWord(word.Word, word.IsRegex); msg != "" {
+		response.Error(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.SensitiveWord{}).Where("category_id = ? AND word = ? AND id <> ?", word.CategoryID, word.Word, word.ID).Count(&count)
+	if count > 0 {
+		response.Error(c, http.StatusBadRequest, "该分类下已存在相同的敏感词")
+		return
+	}
+
+	if err := h.db.Omit("Category").Save(&word).Error; err != nil {
+		audit.Annotate(c, "UpdateSensitiveWord", "SensitiveWord", id, "更新敏感词失败: "+err.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "更新失败")
+		return
+	}
+	h.filter.Invalidate()
+
+	audit.Annotate(c, "UpdateSensitiveWord", "SensitiveWord", id, "更新敏感词", "Success")
+	response.Success(c, word, "更新成功")
+}
+
+// DeleteWords 删除敏感词（支持批量删除）
+// POST /api/v1/admin/sensitive-words/delete-batch
+func (h *AdminSensitiveWordHandler) DeleteWords(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) == 0 {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供敏感词ID列表")
+		return
+	}
+
+	result := h.db.Where("id IN ?", req.IDs).Delete(&models.SensitiveWord{})
+	if result.Error != nil {
+		audit.Annotate(c, "DeleteSensitiveWords", "SensitiveWord", strings.Join(req.IDs, ","), "删除敏感词失败: "+result.Error.Error(), "Failure")
+		response.Error(c, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	h.filter.Invalidate()
+
+	audit.Annotate(c, "DeleteSensitiveWords", "SensitiveWord", strings.Join(req.IDs, ","), fmt.Sprintf("删除敏感词 %d 个", result.RowsAffected), "Success")
+	response.Success(c, gin.H{"deleted": result.RowsAffected}, "删除成功")
+}
+
+// TestText 用当前词库检查一段文本，不修改任何数据
+// POST /api/v1/admin/sensitive-words/test
+func (h *AdminSensitiveWordHandler) TestText(c *gin.Context) {
+	var req struct {
+		Text string `json:"text" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 text")
+		return
+	}
+
+	// 词库可能刚被其他实例修改，测试时总是读取最新词库
+	dict, err := sensitive.Load(h.db)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "加载词库失败")
+		return
+	}
+
+	response.Success(c, dict.Scan(req.Text), "检测完成")
+}
+
+// Rescan 把待审核的帖子和评论标记为未扫描，由后台扫描按最新词库重新检查
+// POST /api/v1/admin/sensitive-words/rescan
+func (h *AdminSensitiveWordHandler) Rescan(c *gin.Context) {
+	result := gin.H{}
+	for _, target := range ModerationTargets {
+		res := h.db.Model(target.Model).Where("moderation_status = ? AND scanned_at IS NOT NULL", models.ModerationPending).
+			UpdateColumn("scanned_at", nil)
+		if res.Error != nil {
+			audit.Annotate(c, "RescanSensitiveWords", "SensitiveWord", "", "重新扫描失败: "+res.Error.Error(), "Failure")
+			response.Error(c, http.StatusInternalServerError, "操作失败")
+			return
+		}
+		result[target.Name] = res.RowsAffected
+	}
+
+	audit.Annotate(c, "RescanSensitiveWords", "SensitiveWord", "", fmt.Sprintf("待审核内容重新扫描: %v", result), "Success")
+	response.Success(c, result, "已加入重新扫描")
+}
+
+// GetHits 获取敏感词命中记录
+// GET /api/v1/admin/sensitive-hits
+func (h *AdminSensitiveWordHandler) GetHits(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	var hits []models.SensitiveHit
+	var total int64
+
+	query := h.db.Model(&models.SensitiveHit{})
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	query.Count(&total)
+
+	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&hits).Error; err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"items":     hits,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+// validateSensitiveCategory 校验分类字段，返回错误提示
+func validateSensitiveCategory(category *models.SensitiveWordCategory) string {
+	if !categoryCodePattern.MatchString(category.Code) {
+		return "分类代码只能包含小写字母、数字和下划线"
+	}
+	if category.Action != models.SensitiveActionReview && category.Action != models.SensitiveActionHide {
+		return "处置策略只能是 review 或 hide"
+	}
+	if category.RiskScore < 0 || category.RiskScore > 100 {
+		return "风险分需在 0-100 之间"
+	}
+	return ""
+}
+
+// validateSensitiveWord 校验词条，返回错误提示
+func validateSensitiveWord(word string, isRegex bool) string {
+	if word == "" {
+		return "敏感词不能为空"
+	}
+	if utf8.RuneCountInString(word) > 255 {
+		return "敏感词过长: " + word
+	}
+	if isRegex {
+		re, err := sensitive.CompileRegex(word)
+		if err != nil {
+			return "无效的正则表达式: " + word
+		}
+		if re.MatchString("") {
+			return "正则表达式不能匹配空文本: " + word
+		}
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fluent-life-admin-api/internal/audit"
 	"fluent-life-admin-api/internal/models"
 	"fluent-life-admin-api/pkg/response"
 	"fmt"
@@ -157,6 +158,7 @@ func (h *AdminVideoHandler) GetVideoList(c *gin.Context) {
 		postQuery := h.db.Table("posts").
 			Select("posts.id, posts.user_id, posts.image, posts.content, posts.created_at, users.username").
 			Joins("LEFT JOIN users ON posts.user_id = users.id").
+			Where("posts.deleted_at IS NULL").
 			Where("posts.image IS NOT NULL AND posts.image != ''").
 			Where("(posts.image LIKE ? OR posts.image LIKE ?)", "%.webm%", "%.mp4%")
 
@@ -203,6 +205,7 @@ func (h *AdminVideoHandler) GetVideoList(c *gin.Context) {
 
 	var postCount int64
 	h.db.Table("posts").
+		Where("deleted_at IS NULL").
 		Where("image IS NOT NULL AND image != ''").
 		Where("(image LIKE ? OR image LIKE ?)", "%.webm%", "%.mp4%").
 		Count(&postCount)
@@ -281,6 +284,7 @@ func (h *AdminVideoHandler) GetVideoDetail(c *gin.Context) {
 			Select("posts.id, posts.user_id, posts.image, posts.content, posts.created_at, users.username").
 			Joins("LEFT JOIN users ON posts.user_id = users.id").
 			Where("posts.id = ?", videoID).
+			Where("posts.deleted_at IS NULL").
 			Where("posts.image IS NOT NULL AND posts.image != ''").
 			Scan(&postData).Error; err == nil {
 
@@ -324,9 +328,12 @@ func (h *AdminVideoHandler) DeleteVideo(c *gin.Context) {
 		if err := h.db.Where("id = ?", videoID).First(&record).Error; err == nil {
 			if record.Data != nil {
 				if _, ok := record.Data["video_url"].(string); ok {
+					audit.Annotate(c, "", "TrainingRecord", record.ID.String(), "删除训练记录中的视频", "")
+					audit.SetBefore(c, record)
 					// 清除video_url字段
 					delete(record.Data, "video_url")
 					if err := h.db.Model(&record).Update("data", record.Data).Error; err == nil {
+						audit.SetAfter(c, record)
 						response.Success(c, nil, "视频删除成功")
 						return
 					}
@@ -339,8 +346,12 @@ func (h *AdminVideoHandler) DeleteVideo(c *gin.Context) {
 		// 从社区帖子删除（删除image字段）
 		var post models.Post
 		if err := h.db.Where("id = ?", videoID).First(&post).Error; err == nil {
+			audit.Annotate(c, "", "Post", post.ID.String(), "删除帖子中的视频", "")
+			// image 字段不在 Post 模型中，直接按行读取快照
+			audit.SetBefore(c, audit.Snapshot(h.db, &models.Post{}, "id", videoID))
 			// 使用原始SQL更新image字段为空
 			if err := h.db.Exec("UPDATE posts SET image = '' WHERE id = ?", videoID).Error; err == nil {
+				audit.SetAfter(c, audit.Snapshot(h.db, &models.Post{}, "id", videoID))
 				response.Success(c, nil, "视频删除成功")
 				return
 			}
@@ -391,6 +402,7 @@ func (h *AdminVideoHandler) BatchDeleteVideos(c *gin.Context) {
 		failCount++
 	}
 
+	audit.Annotate(c, "", "", "", fmt.Sprintf("批量删除视频：成功 %d 个，失败 %d 个", successCount, failCount), "")
 	response.Success(c, gin.H{
 		"success_count": successCount,
 		"fail_count":    failCount,
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/internal/voicetype"
 	"fluent-life-admin-api/pkg/response"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetVoiceTypes 获取所有音色类型（管理员）
@@ -127,62 +131,188 @@ func (h *AdminHandler) UpdateVoiceType(c *gin.Context) {
 		}
 	}
 
+	oldType, wasEnabled := voiceType.Type, voiceType.Enabled
 	voiceType.Name = req.Name
 	voiceType.Type = req.Type
 	voiceType.Description = req.Description
 	voiceType.Enabled = req.Enabled
 
-	if err := h.db.Save(&voiceType).Error; err != nil {
+	// 修改 type 时同步更新AI角色和用户设置中的引用；仍被引用的音色不能停用
+	var roles, userSettings int64
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if wasEnabled && !voiceType.Enabled {
+			usage, err := voicetype.FindUsage(tx, oldType)
+			if err != nil {
+				return err
+			}
+			if usage.InUse() {
+				return errVoiceTypeInUse
+			}
+		}
+		if err := tx.Save(&voiceType).Error; err != nil {
+			return err
+		}
+		if oldType != voiceType.Type {
+			var err error
+			roles, userSettings, err = voicetype.Rename(tx, oldType, voiceType.Type)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errVoiceTypeInUse) {
+			response.Error(c, http.StatusConflict, "音色仍被AI角色或用户设置使用，请先将引用替换为其他音色再停用")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "更新音色类型失败: "+err.Error())
 		return
 	}
+	if roles > 0 {
+		h.syncAIRoleSetting(c)
+	}
 
-	h.logOperation(c, "update", "voice_type", voiceType.ID.String(), "更新音色类型: "+voiceType.Name, "success")
+	details := "更新音色类型: " + voiceType.Name
+	if oldType != voiceType.Type {
+		details += fmt.Sprintf("，type 由 %s 改为 %s，同步更新 %d 个AI角色和 %d 个用户设置", oldType, voiceType.Type, roles, userSettings)
+	}
+	h.logOperation(c, "update", "voice_type", voiceType.ID.String(), details, "success")
 	response.Success(c, voiceType, "更新成功")
 }
 
-// DeleteVoiceType 删除音色类型（管理员）
-// DELETE /api/v1/admin/voice-types/:id
+// DeleteVoiceType 删除音色类型（管理员）。仍被AI角色或用户设置使用时返回 409，
+// 传入 replacement_type 时在同一事务中把引用替换为该音色后删除
+// DELETE /api/v1/admin/voice-types/:id?replacement_type=...
 func (h *AdminHandler) DeleteVoiceType(c *gin.Context) {
-	id := c.Param("id")
-	voiceTypeID, err := uuid.Parse(id)
+	replacement := c.Query("replacement_type")
+
+	var voiceType *models.VoiceType
+	var usage *voicetype.Usage
+	var roles, userSettings int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if voiceType, err = lockVoiceType(tx, c.Param("id")); err != nil {
+			return err
+		}
+		if usage, err = voicetype.FindUsage(tx, voiceType.Type); err != nil {
+			return err
+		}
+		if usage.InUse() {
+			if replacement == "" {
+				return errVoiceTypeInUse
+			}
+			if roles, userSettings, err = voicetype.Reassign(tx, voiceType.Type, replacement); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(voiceType).Error
+	})
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, "无效的音色类型ID")
+		if errors.Is(err, errVoiceTypeInUse) {
+			response.Error(c, http.StatusConflict, fmt.Sprintf("音色正被 %d 个AI角色和 %d 个用户设置使用，请指定 replacement_type 替换后删除",
+				len(usage.Roles), usage.UserSettings))
+			return
+		}
+		voiceTypeError(c, err, "删除音色类型失败")
 		return
 	}
+	if roles > 0 {
+		h.syncAIRoleSetting(c)
+	}
 
+	details := "删除音色类型: " + voiceType.Name
+	if usage.InUse() {
+		details += fmt.Sprintf("，%d 个AI角色和 %d 个用户设置改用 %s", roles, userSettings, replacement)
+	}
+	h.logOperation(c, "delete", "voice_type", voiceType.ID.String(), details, "success")
+	response.Success(c, gin.H{"reassigned_roles": roles, "reassigned_user_settings": userSettings}, "删除成功")
+}
+
+// GetVoiceTypeUsage 查询音色被哪些AI角色和多少用户设置使用
+// GET /api/v1/admin/voice-types/:id/usage
+func (h *AdminHandler) GetVoiceTypeUsage(c *gin.Context) {
+	voiceTypeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的音色类型ID")
+		return
+	}
 	var voiceType models.VoiceType
 	if err := h.db.First(&voiceType, voiceTypeID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			response.Error(c, http.StatusNotFound, "音色类型不存在")
-		} else {
-			response.Error(c, http.StatusInternalServerError, "获取音色类型失败: "+err.Error())
-		}
+		voiceTypeError(c, err, "获取音色类型失败")
 		return
 	}
 
-	// 检查是否有AI角色正在使用此音色类型
-	var count int64
-	h.db.Model(&models.AppSetting{}).
-		Where("key = ?", "ai_simulation_roles").
-		Count(&count)
-	
-	if count > 0 {
-		// 检查是否有角色使用此音色
-		var setting models.AppSetting
-		if err := h.db.Where("key = ?", "ai_simulation_roles").First(&setting).Error; err == nil {
-			// 这里可以进一步检查JSON中是否使用了此音色类型
-			// 为了简化，我们允许删除，但会在日志中记录
-		}
+	usage, err := voicetype.FindUsage(h.db, voiceType.Type)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "查询音色引用失败")
+		return
+	}
+	response.Success(c, usage, "获取成功")
+}
+
+// ReassignVoiceType 把引用该音色的AI角色和用户设置改为另一个已启用的音色，不删除音色本身
+// POST /api/v1/admin/voice-types/:id/reassign
+func (h *AdminHandler) ReassignVoiceType(c *gin.Context) {
+	var req struct {
+		ReplacementType string `json:"replacement_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误，需要提供 replacement_type")
+		return
 	}
 
-	if err := h.db.Delete(&voiceType).Error; err != nil {
-		response.Error(c, http.StatusInternalServerError, "删除音色类型失败: "+err.Error())
+	var voiceType *models.VoiceType
+	var roles, userSettings int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if voiceType, err = lockVoiceType(tx, c.Param("id")); err != nil {
+			return err
+		}
+		roles, userSettings, err = voicetype.Reassign(tx, voiceType.Type, req.ReplacementType)
+		return err
+	})
+	if err != nil {
+		voiceTypeError(c, err, "替换音色失败")
 		return
 	}
+	if roles > 0 {
+		h.syncAIRoleSetting(c)
+	}
+
+	h.logOperation(c, "reassign", "voice_type", voiceType.ID.String(),
+		fmt.Sprintf("音色 %s 的 %d 个AI角色和 %d 个用户设置改用 %s", voiceType.Type, roles, userSettings, req.ReplacementType), "success")
+	response.Success(c, gin.H{"reassigned_roles": roles, "reassigned_user_settings": userSettings}, "替换成功")
+}
+
+var (
+	errVoiceTypeInUse     = errors.New("voice type in use")
+	errInvalidVoiceTypeID = errors.New("invalid voice type id")
+)
+
+// lockVoiceType 在事务中按ID锁定音色
+func lockVoiceType(tx *gorm.DB, id string) (*models.VoiceType, error) {
+	voiceTypeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errInvalidVoiceTypeID
+	}
+	var voiceType models.VoiceType
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&voiceType, voiceTypeID).Error; err != nil {
+		return nil, err
+	}
+	return &voiceType, nil
+}
 
-	h.logOperation(c, "delete", "voice_type", voiceType.ID.String(), "删除音色类型: "+voiceType.Name, "success")
-	response.Success(c, nil, "删除成功")
+// voiceTypeError 把音色操作的错误转换为响应
+func voiceTypeError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, errInvalidVoiceTypeID):
+		response.Error(c, http.StatusBadRequest, "无效的音色类型ID")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(c, http.StatusNotFound, "音色类型不存在")
+	case errors.Is(err, voicetype.ErrReplacementUnavailable):
+		response.Error(c, http.StatusBadRequest, "替换音色不存在、未启用或与原音色相同")
+	default:
+		response.Error(c, http.StatusInternalServerError, msg+": "+err.Error())
+	}
 }
 
 // GetEnabledVoiceTypes 获取所有启用的音色类型（用于下拉选择）
@@ -0,0 +1,221 @@
+// Package jobs 基于 Postgres 的后台任务队列，工作协程运行在后台 API 进程内。
+//
+// 排队中的任务通过 SELECT ... FOR UPDATE SKIP LOCKED 认领，多个进程可以同时消费同一张表；
+// 执行中的任务定期写入心跳并检查取消请求，失败的任务按指数退避重新排队。
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 默认值
+const (
+	DefaultMaxAttempts = 3
+	DefaultTimeout     = 30 * time.Minute
+)
+
+var (
+	// ErrUnknownType 任务类型未注册
+	ErrUnknownType = errors.New("unknown job type")
+	// ErrFinished 任务已结束，不能取消
+	ErrFinished = errors.New("job already finished")
+)
+
+// Definition 一种任务
+type Definition struct {
+	Type string
+	// Name 中文名称
+	Name string
+	// Permission 提交任务需要的权限代码
+	Permission string
+	// MaxAttempts 最多执行次数，默认 DefaultMaxAttempts
+	MaxAttempts int
+	// Timeout 单次执行的超时时间，默认 DefaultTimeout
+	Timeout time.Duration
+	// Validate 提交时校验参数，返回的错误信息直接展示给管理员
+	Validate func(payload models.JSONB) error
+	// Run 执行任务。ctx 在取消或超时后结束，任务应及时返回；返回 Permanent 包装的错误时不再重试
+	Run func(ctx context.Context, run *Run) (models.JSONB, error)
+	// Cleanup 清理过期任务记录时调用，用于删除任务生成的文件
+	Cleanup func(job *models.Job)
+}
+
+func (d Definition) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+func (d Definition) timeout() time.Duration {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+	return DefaultTimeout
+}
+
+// permanentError 不需要重试的错误
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记不需要重试的错误，例如参数无效
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent 判断错误是否不需要重试
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Queue 任务类型注册表和队列操作
+type Queue struct {
+	db       *gorm.DB
+	defs     map[string]Definition
+	workerID string
+	// wake 本进程提交任务后唤醒空闲的工作协程，不必等到下一次轮询
+	wake chan struct{}
+}
+
+// NewQueue 创建队列
+func NewQueue(db *gorm.DB) *Queue {
+	host, _ := os.Hostname()
+	return &Queue{
+		db:       db,
+		defs:     map[string]Definition{},
+		workerID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register 注册任务类型，类型重复时 panic
+func (q *Queue) Register(def Definition) {
+	if def.Type == "" || def.Run == nil {
+		panic("jobs: definition needs a type and a Run function")
+	}
+	if _, ok := q.defs[def.Type]; ok {
+		panic(fmt.Sprintf("jobs: duplicate job type %q", def.Type))
+	}
+	q.defs[def.Type] = def
+}
+
+// Definition 按类型查找任务定义
+func (q *Queue) Definition(jobType string) (Definition, bool) {
+	def, ok := q.defs[jobType]
+	return def, ok
+}
+
+// Definitions 按类型排序的全部任务定义
+func (q *Queue) Definitions() []Definition {
+	defs := make([]Definition, 0, len(q.defs))
+	for _, def := range q.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Type < defs[j].Type })
+	return defs
+}
+
+// Submit 校验参数后把任务加入队列。job 需填写 Type、Payload 和提交人
+func (q *Queue) Submit(job *models.Job) error {
+	def, ok := q.defs[job.Type]
+	if !ok {
+		return ErrUnknownType
+	}
+	if def.Validate != nil {
+		if err := def.Validate(job.Payload); err != nil {
+			return Permanent(err)
+		}
+	}
+	job.Status = models.JobQueued
+	job.MaxAttempts = def.maxAttempts()
+	job.RunAt = time.Now()
+	if err := q.db.Create(job).Error; err != nil {
+		return err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Cancel 取消任务：排队中的任务直接取消，执行中的任务标记取消请求，由工作协程停止后置为已取消
+func (q *Queue) Cancel(id uuid.UUID) (*models.Job, error) {
+	now := time.Now()
+	var job models.Job
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&job).Error; err != nil {
+			return err
+		}
+		switch job.Status {
+		case models.JobQueued:
+			job.Status = models.JobCancelled
+			job.FinishedAt = &now
+			return tx.Model(&job).Updates(map[string]interface{}{"status": job.Status, "finished_at": now}).Error
+		case models.JobRunning:
+			job.CancelRequested = true
+			return tx.Model(&job).Update("cancel_requested", true).Error
+		}
+		return ErrFinished
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Run 一次任务执行
+type Run struct {
+	DB  *gorm.DB
+	Job *models.Job
+
+	queue  *Queue
+	cancel context.CancelFunc
+	// cancelled 收到取消请求（或任务已被回收），区别于超时
+	cancelled atomic.Bool
+}
+
+// Progress 更新进度（done/total，total 为 0 时只更新说明），同时写入心跳。
+// 任务被取消或超时后返回 ctx 的错误，调用方应停止执行
+func (r *Run) Progress(ctx context.Context, done, total int64, message string) error {
+	updates := map[string]interface{}{"progress_message": truncate(message, 255)}
+	if total > 0 {
+		percent := int(done * 100 / total)
+		if percent > 99 {
+			percent = 99 // 100 留给完成时写入
+		}
+		updates["progress"] = percent
+		r.Job.Progress = percent
+	}
+	r.Job.ProgressMessage = message
+	if err := r.queue.touch(r, updates); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
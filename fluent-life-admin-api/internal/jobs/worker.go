@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+)
+
+const (
+	// heartbeatInterval 执行中任务写入心跳和检查取消请求的间隔
+	heartbeatInterval = 10 * time.Second
+	// staleAfter 超过这个时间没有心跳的执行中任务视为进程已中断
+	staleAfter = 2 * time.Minute
+	// maintenanceInterval 回收中断任务和清理过期记录的间隔
+	maintenanceInterval = time.Minute
+	// maxBackoff 重试等待时间的上限
+	maxBackoff = 30 * time.Minute
+)
+
+// Start 启动 workers 个工作协程，每 poll 检查一次队列；retention 之前结束的任务记录被清理
+func (q *Queue) Start(workers int, poll, retention time.Duration) {
+	for i := 0; i < workers; i++ {
+		go q.work(poll)
+	}
+	go func() {
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			if n, err := q.RecoverStale(time.Now()); err != nil {
+				log.Printf("回收中断的后台任务失败: %v", err)
+			} else if n > 0 {
+				log.Printf("回收中断的后台任务 %d 个", n)
+			}
+			if retention > 0 {
+				if n, err := q.Prune(time.Now().Add(-retention)); err != nil {
+					log.Printf("清理过期后台任务失败: %v", err)
+				} else if n > 0 {
+					log.Printf("清理过期后台任务 %d 个", n)
+				}
+			}
+		}
+	}()
+}
+
+func (q *Queue) work(poll time.Duration) {
+	for {
+		job, err := q.claim(time.Now())
+		if err != nil {
+			log.Printf("认领后台任务失败: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-q.wake:
+			case <-time.After(poll):
+			}
+			continue
+		}
+		q.execute(job)
+	}
+}
+
+// claim 认领一个到期的排队任务，没有任务时返回 nil
+func (q *Queue) claim(now time.Time) (*models.Job, error) {
+	var jobs []models.Job
+	err := q.db.Raw(`UPDATE jobs SET status = @running, attempts = attempts + 1, worker_id = @worker,
+			started_at = @now, heartbeat_at = @now, updated_at = @now
+		WHERE id = (
+			SELECT id FROM jobs WHERE status = @queued AND run_at <= @now
+			ORDER BY run_at, created_at LIMIT 1 FOR UPDATE SKIP LOCKED
+		) RETURNING *`,
+		map[string]interface{}{"running": models.JobRunning, "queued": models.JobQueued, "worker": q.workerID, "now": now}).
+		Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// execute 执行任务并记录结果
+func (q *Queue) execute(job *models.Job) {
+	def, ok := q.defs[job.Type]
+	if !ok {
+		q.finish(job, models.JobFailed, nil, "未注册的任务类型: "+job.Type)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), def.timeout())
+	defer cancel()
+	run := &Run{DB: q.db, Job: job, queue: q, cancel: cancel}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := q.touch(run, nil); err != nil {
+					log.Printf("后台任务 %s 写入心跳失败: %v", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	result, err := runSafely(ctx, def, run)
+	close(done)
+
+	switch {
+	case err == nil:
+		q.finish(job, models.JobSucceeded, result, "")
+	case run.cancelled.Load():
+		q.finish(job, models.JobCancelled, result, "已取消")
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		q.finish(job, models.JobFailed, result, err.Error())
+	default:
+		q.retry(job, err)
+	}
+}
+
+// runSafely 执行任务，panic 视为不可重试的失败
+func runSafely(ctx context.Context, def Definition, run *Run) (result models.JSONB, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("后台任务 %s panic: %v\n%s", run.Job.ID, r, debug.Stack())
+			err = Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	return def.Run(ctx, run)
+}
+
+// touch 写入心跳和进度，读取取消请求；收到取消请求时结束任务的 ctx
+func (q *Queue) touch(run *Run, updates map[string]interface{}) error {
+	sets := "heartbeat_at = @now, updated_at = @now"
+	args := map[string]interface{}{"now": time.Now(), "id": run.Job.ID, "worker": q.workerID}
+	for _, column := range []string{"progress", "progress_message"} {
+		if v, ok := updates[column]; ok {
+			sets += fmt.Sprintf(", %s = @%s", column, column)
+			args[column] = v
+		}
+	}
+	var cancelRequested []bool
+	if err := q.db.Raw("UPDATE jobs SET "+sets+" WHERE id = @id AND worker_id = @worker AND status = 'running' RETURNING cancel_requested", args).
+		Scan(&cancelRequested).Error; err != nil {
+		return err
+	}
+	// 任务已被回收或取消，本次执行的结果不再写入
+	if len(cancelRequested) == 0 || cancelRequested[0] {
+		run.cancelled.Store(true)
+		run.cancel()
+	}
+	return nil
+}
+
+// finish 写入最终状态。只更新仍由本进程执行的任务，避免覆盖被回收后重新执行的结果
+func (q *Queue) finish(job *models.Job, status string, result models.JSONB, message string) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       status,
+		"result":       result,
+		"error":        message,
+		"finished_at":  now,
+		"heartbeat_at": nil,
+	}
+	if status == models.JobSucceeded {
+		updates["progress"] = 100
+	}
+	err := q.db.Model(&models.Job{}).Where("id = ? AND worker_id = ? AND status = ?", job.ID, q.workerID, models.JobRunning).
+		Updates(updates).Error
+	if err != nil {
+		log.Printf("保存后台任务 %s 结果失败: %v", job.ID, err)
+	}
+}
+
+// retry 按指数退避重新排队：30 秒、1 分钟、2 分钟……最长 maxBackoff
+func (q *Queue) retry(job *models.Job, cause error) {
+	backoff := 30 * time.Second << (job.Attempts - 1)
+	if backoff <= 0 || backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	err := q.db.Model(&models.Job{}).Where("id = ? AND worker_id = ? AND status = ?", job.ID, q.workerID, models.JobRunning).
+		Updates(map[string]interface{}{
+			"status":       models.JobQueued,
+			"error":        cause.Error(),
+			"run_at":       time.Now().Add(backoff),
+			"heartbeat_at": nil,
+		}).Error
+	if err != nil {
+		log.Printf("后台任务 %s 重新排队失败: %v", job.ID, err)
+	}
+}
+
+// RecoverStale 处理心跳超时的执行中任务：有取消请求的置为已取消，次数用完的置为失败，其余重新排队
+func (q *Queue) RecoverStale(now time.Time) (int64, error) {
+	result := q.db.Exec(`UPDATE jobs SET
+			status = CASE WHEN cancel_requested THEN @cancelled WHEN attempts >= max_attempts THEN @failed ELSE @queued END,
+			finished_at = CASE WHEN cancel_requested OR attempts >= max_attempts THEN CAST(@now AS timestamptz) END,
+			error = '执行中断：工作进程停止或失去心跳',
+			run_at = @now, heartbeat_at = NULL, updated_at = @now
+		WHERE status = @running AND (heartbeat_at IS NULL OR heartbeat_at < @stale)`,
+		map[string]interface{}{
+			"cancelled": models.JobCancelled,
+			"failed":    models.JobFailed,
+			"queued":    models.JobQueued,
+			"running":   models.JobRunning,
+			"now":       now,
+			"stale":     now.Add(-staleAfter),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Prune 删除 before 之前结束的任务记录，并调用任务定义的 Cleanup
+func (q *Queue) Prune(before time.Time) (int, error) {
+	pruned := 0
+	for {
+		var jobs []models.Job
+		if err := q.db.Where("finished_at < ?", before).Order("finished_at").Limit(100).Find(&jobs).Error; err != nil {
+			return pruned, err
+		}
+		if len(jobs) == 0 {
+			return pruned, nil
+		}
+		ids := make([]interface{}, len(jobs))
+		for i := range jobs {
+			if def, ok := q.defs[jobs[i].Type]; ok && def.Cleanup != nil {
+				def.Cleanup(&jobs[i])
+			}
+			ids[i] = jobs[i].ID
+		}
+		if err := q.db.Where("id IN ?", ids).Delete(&models.Job{}).Error; err != nil {
+			return pruned, err
+		}
+		pruned += len(jobs)
+	}
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"fluent-life-admin-api/internal/audit"
+	"fluent-life-admin-api/internal/models"
+)
+
+// maxCapturedBody 为判断处理结果而缓存的响应体上限
+const maxCapturedBody = 64 << 10
+
+// AuditResource 描述一类路由对应的实体，用于自动加载变更前后的快照
+type AuditResource struct {
+	// Prefix 路由模板前缀（c.FullPath()），按最长前缀匹配
+	Prefix string
+	// Resource 写入 OperationLog.Resource 的资源名
+	Resource string
+	// Model 实体模型，为 nil 时不自动加载快照
+	Model interface{}
+	// Param 主键所在的路由参数，为空时使用处理函数注解或响应中的 data.id
+	Param string
+	// Column 主键列名，默认为 id
+	Column string
+	// Key 固定主键值，用于以固定键保存的配置（如 app_settings）
+	Key string
+}
+
+// Audit 为所有写操作（非 GET/HEAD/OPTIONS）记录审计日志：操作者、IP、User-Agent、
+// 方法和路由，以及实体变更前后的快照和差异。处理函数通过 audit 包补充操作名称和描述。
+// 必须在 UserAuthMiddleware 之后使用。
+func Audit(db *gorm.DB, resources []AuditResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		entry := audit.Begin(c)
+		res := matchAuditResource(resources, c.FullPath())
+		if res.Column == "" {
+			res.Column = "id"
+		}
+
+		paramValue := ""
+		if res.Param != "" {
+			paramValue = c.Param(res.Param)
+		}
+		key := res.Key
+		if key == "" {
+			key = paramValue
+		}
+		if res.Model != nil && key != "" {
+			entry.Before = audit.Snapshot(db, res.Model, res.Column, key)
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		if entry.Resource == "" {
+			entry.Resource = res.Resource
+		}
+		if entry.Resource == "" {
+			entry.Resource = resourceFromPath(c.FullPath())
+		}
+		if entry.Action == "" {
+			entry.Action = actionFromHandler(c.HandlerName())
+		}
+
+		code, data := writer.result()
+		if entry.ResourceID == "" {
+			entry.ResourceID = paramValue
+		}
+		if entry.ResourceID == "" {
+			entry.ResourceID = idFromData(data)
+		}
+		if entry.Status == "" {
+			// code 为 -1 表示响应不是统一结构（如文件下载），此时只看 HTTP 状态码
+			if (code == 0 || code == -1) && c.Writer.Status() < http.StatusBadRequest {
+				entry.Status = "Success"
+			} else {
+				entry.Status = "Failure"
+			}
+		}
+
+		if res.Model != nil && !entry.AfterSet() {
+			snapshotKey := key
+			if snapshotKey == "" {
+				snapshotKey = entry.ResourceID
+			}
+			if snapshotKey != "" {
+				entry.After = audit.Snapshot(db, res.Model, res.Column, snapshotKey)
+			}
+		}
+
+		logEntry := models.OperationLog{
+			Action:     truncateString(entry.Action, 100),
+			Resource:   truncateString(entry.Resource, 100),
+			ResourceID: truncateString(entry.ResourceID, 255),
+			Details:    entry.Details,
+			Status:     entry.Status,
+			IP:         truncateString(c.ClientIP(), 64),
+			UserAgent:  truncateString(c.Request.UserAgent(), 255),
+			Method:     c.Request.Method,
+			Path:       truncateString(c.FullPath(), 255),
+			Before:     audit.Redact(entry.Before),
+			After:      audit.Redact(entry.After),
+			Diff:       audit.Diff(entry.Before, entry.After),
+		}
+		if v, ok := c.Get("userID"); ok {
+			logEntry.UserID, _ = v.(uuid.UUID)
+		}
+		if v, ok := c.Get("username"); ok {
+			logEntry.Username, _ = v.(string)
+		}
+		if v, ok := c.Get("userRole"); ok {
+			logEntry.UserRole, _ = v.(string)
+		}
+
+		// 审计写入失败不影响已完成的请求
+		if err := db.Create(&logEntry).Error; err != nil {
+			log.Printf("写入审计日志失败: %v", err)
+		}
+	}
+}
+
+// matchAuditResource 按最长前缀匹配路由对应的资源配置
+func matchAuditResource(resources []AuditResource, fullPath string) AuditResource {
+	var best AuditResource
+	for _, r := range resources {
+		if !strings.HasPrefix(fullPath, r.Prefix) || len(r.Prefix) <= len(best.Prefix) {
+			continue
+		}
+		// 只在路径分段边界匹配，避免 /users 匹配到 /user-settings
+		if rest := fullPath[len(r.Prefix):]; rest != "" && !strings.HasPrefix(rest, "/") {
+			continue
+		}
+		best = r
+	}
+	return best
+}
+
+// idFromData 新建类接口通常在响应中返回实体（data.id 或 data.<name>.id），取其 id
+func idFromData(data interface{}) string {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if id, ok := m["id"]; ok {
+		return fmt.Sprint(id)
+	}
+	found := ""
+	for _, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			if id, ok := nested["id"]; ok {
+				if found != "" {
+					return ""
+				}
+				found = fmt.Sprint(id)
+			}
+		}
+	}
+	return found
+}
+
+// resourceFromPath 未配置资源的路由以 /admin/ 之后的第一段路径作为资源名
+func resourceFromPath(fullPath string) string {
+	path := fullPath
+	if i := strings.Index(path, "/admin/"); i >= 0 {
+		path = path[i+len("/admin/"):]
+	}
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" {
+		return "Unknown"
+	}
+	return path
+}
+
+// actionFromHandler 从处理函数名（如 handlers.(*AdminHandler).UpdateUser-fm）提取方法名
+func actionFromHandler(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
+
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
+
+// auditResponseWriter 在写出响应的同时缓存响应体，用于读取统一响应结构中的 code 和 data
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) capture(b []byte) {
+	if w.truncated {
+		return
+	}
+	if w.body.Len()+len(b) > maxCapturedBody {
+		w.truncated = true
+		return
+	}
+	w.body.Write(b)
+}
+
+// result 解析 response.Response；无法解析时 code 为 -1
+func (w *auditResponseWriter) result() (int, interface{}) {
+	if w.truncated || w.body.Len() == 0 {
+		return -1, nil
+	}
+	var resp struct {
+		Code *int        `json:"code"`
+		Data interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &resp); err != nil || resp.Code == nil {
+		return -1, nil
+	}
+	return *resp.Code, resp.Data
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -31,21 +33,39 @@ func UserAuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// 令牌必须对应一个未吊销且未过期的会话
+		var session models.AuthSession
+		if claims.ID == "" || db.Where("jti = ?", claims.ID).First(&session).Error != nil ||
+			session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+			response.Error(c, http.StatusUnauthorized, "Token has been revoked")
+			c.Abort()
+			return
+		}
+
 		var user models.User
 		if err := db.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
 			response.Error(c, http.StatusUnauthorized, "User not found")
 			c.Abort()
 			return
 		}
+		if user.Status == 0 {
+			response.Error(c, http.StatusUnauthorized, "User is disabled")
+			c.Abort()
+			return
+		}
 
 		c.Set("userID", user.ID)
 		c.Set("username", user.Username)
 		c.Set("userRole", user.Role) // Store user role in context
+		c.Set("tokenJTI", claims.ID)
+		c.Set("mustChangePassword", user.MustChangePassword)
 		c.Next()
 	}
 }
 
-// AdminAuthMiddleware checks if the authenticated user has an admin role.
+// AdminAuthMiddleware checks if the authenticated user has a back-office role.
+// Any role other than the plain "user" role may enter the admin API; what it can
+// actually do is decided per route by RequirePermission.
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("userRole")
@@ -56,8 +76,45 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		}
 
 		role, ok := userRole.(string)
-		if !ok || (role != "admin" && role != "super_admin") {
-			response.Error(c, http.StatusForbidden, "Access denied: Admin or Super Admin role required")
+		if !ok || role == "" || role == "user" {
+			response.Error(c, http.StatusForbidden, "Access denied: back-office role required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// PasswordChangeGuard blocks every admin route except the allowed ones while the
+// authenticated account is flagged with MustChangePassword (e.g. the seeded default admin).
+func PasswordChangeGuard(allowedPaths ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedPaths))
+	for _, p := range allowedPaths {
+		allowed[p] = true
+	}
+	return func(c *gin.Context) {
+		if mustChange, _ := c.Get("mustChangePassword"); mustChange == true && !allowed[c.FullPath()] {
+			response.Error(c, http.StatusForbidden, "Password change required before accessing this resource")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ServiceAuthMiddleware authenticates service-to-service calls (e.g. the app backend
+// asking for a user's feature flags) by the shared token in the X-Service-Token header.
+// App users have no admin session, so these endpoints cannot use UserAuthMiddleware.
+func ServiceAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			response.Error(c, http.StatusServiceUnavailable, "Service token not configured")
+			c.Abort()
+			return
+		}
+		provided := c.GetHeader("X-Service-Token")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			response.Error(c, http.StatusUnauthorized, "Invalid service token")
 			c.Abort()
 			return
 		}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"fluent-life-admin-api/internal/permission"
+	"fluent-life-admin-api/pkg/response"
+)
+
+// RequirePermission 根据当前用户的有效权限校验是否拥有指定权限。有效权限由主角色、
+// 附加角色及其继承的上级角色共同决定（见 permission.ForUser）。
+// 必须在 UserAuthMiddleware 之后使用。
+func RequirePermission(db *gorm.DB, code string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRole, exists := c.Get("userRole")
+		if !exists {
+			response.Error(c, http.StatusUnauthorized, "User role not found in context")
+			c.Abort()
+			return
+		}
+
+		roleCode, _ := userRole.(string)
+		if roleCode == permission.SuperAdminRole {
+			c.Next()
+			return
+		}
+
+		userID, _ := c.Get("userID")
+		id, _ := userID.(uuid.UUID)
+		set, err := permission.ForUser(db, id, roleCode)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "Failed to load role permissions")
+			c.Abort()
+			return
+		}
+
+		if !set.Has(code) {
+			response.Error(c, http.StatusForbidden, "Access denied: missing permission "+code)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
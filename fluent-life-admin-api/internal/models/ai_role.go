@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AIRole AI实战模拟角色
+type AIRole struct {
+	// ID 客户端使用的角色标识，例如 "interviewer"，创建后不可修改
+	ID          string `gorm:"type:varchar(50);primaryKey" json:"id"`
+	Name        string `gorm:"type:varchar(50);not null" json:"name"`
+	Description string `gorm:"type:varchar(255)" json:"description"`
+	Category    string `gorm:"type:varchar(50);index" json:"category"` // 分类，例如 "职场"、"语言学习"
+	Avatar      string `gorm:"type:varchar(500)" json:"avatar"`        // 头像URL
+	OpeningLine string `gorm:"type:text" json:"opening_line"`          // 对话开场白，由角色先说
+	VoiceType   string `gorm:"type:varchar(100)" json:"voice_type"`    // 对应 VoiceType.Type
+	Enabled     bool   `gorm:"not null;default:false" json:"enabled"`
+	Order       int    `gorm:"not null;default:0;index" json:"order"`
+	// SystemPrompt 当前发布版本的提示词，与 AIRolePromptVersion 中 PublishedVersion 对应的记录一致
+	SystemPrompt     string    `gorm:"type:text;not null" json:"system_prompt"`
+	PublishedVersion int       `gorm:"not null;default:0" json:"published_version"`
+	LatestVersion    int       `gorm:"not null;default:0" json:"latest_version"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// AIRolePromptVersion AI角色提示词的历史版本，Version 按角色递增，只增不改
+type AIRolePromptVersion struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	RoleID       string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_ai_role_prompt_versions_role_version,priority:1" json:"role_id"`
+	Version      int       `gorm:"not null;uniqueIndex:idx_ai_role_prompt_versions_role_version,priority:2" json:"version"`
+	SystemPrompt string    `gorm:"type:text;not null" json:"system_prompt"`
+	Comment      string    `gorm:"type:varchar(255)" json:"comment,omitempty"`
+	// RestoredFrom 由历史版本恢复而来时指向该版本
+	RestoredFrom  *int      `json:"restored_from,omitempty"`
+	CreatedBy     uuid.UUID `gorm:"type:uuid" json:"created_by"`
+	CreatedByName string    `gorm:"type:varchar(50)" json:"created_by_name"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Published 是否为角色当前发布的版本，查询时填充
+	Published bool `gorm:"-" json:"published"`
+}
+
+func (v *AIRolePromptVersion) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
@@ -14,6 +14,7 @@ type AppSetting struct {
 	Key         string    `gorm:"type:varchar(100);not null;unique" json:"key"` // 設定項的鍵，例如 "app_version", "customer_service_email"
 	Value       string    `gorm:"type:text;not null" json:"value"`                 // 設定項的值
 	Description string    `gorm:"type:varchar(255)" json:"description"`          // 該設定項的描述
+	Version     int       `gorm:"not null;default:0" json:"version"`              // 當前值對應的變更版本，見 AppSettingRevision
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 设置变更类型
+const (
+	SettingActionInitial  = "initial"  // 变更历史上线前已有的值，第一次修改时补记
+	SettingActionSet      = "set"      // 修改
+	SettingActionReset    = "reset"    // 恢复默认值（删除 app_settings 记录）
+	SettingActionRollback = "rollback" // 回滚到历史版本
+)
+
+// AppSettingRevision 应用设置的变更历史，每次变更一条，Version 按 Key 递增
+type AppSettingRevision struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Key     string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_app_setting_revisions_key_version,priority:1" json:"key"`
+	Version int       `gorm:"not null;uniqueIndex:idx_app_setting_revisions_key_version,priority:2" json:"version"`
+	// Value 变更后的值，为空表示恢复为默认值
+	Value  *string `gorm:"type:text" json:"value"`
+	Action string  `gorm:"type:varchar(20);not null" json:"action"`
+	// RollbackOf 回滚时指向的历史版本
+	RollbackOf    *int      `json:"rollback_of,omitempty"`
+	Comment       string    `gorm:"type:varchar(255)" json:"comment,omitempty"`
+	ChangedBy     uuid.UUID `gorm:"type:uuid" json:"changed_by"`
+	ChangedByName string    `gorm:"type:varchar(50)" json:"changed_by_name"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (r *AppSettingRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
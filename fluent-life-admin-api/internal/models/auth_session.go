@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuthSession 后台登录会话，每签发一个访问令牌对应一条记录，以 JWT 的 JTI 为键。
+// 刷新令牌只保存哈希值；吊销后访问令牌和刷新令牌同时失效。
+type AuthSession struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID           uuid.UUID  `gorm:"type:uuid;not null;index:idx_auth_sessions_user_id" json:"user_id"`
+	JTI              string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_auth_sessions_jti" json:"jti"`
+	RefreshTokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_auth_sessions_refresh_hash" json:"-"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`         // 访问令牌过期时间
+	RefreshExpiresAt time.Time  `gorm:"not null" json:"refresh_expires_at"` // 刷新令牌过期时间
+	RevokedAt        *time.Time `gorm:"index:idx_auth_sessions_revoked_at" json:"revoked_at,omitempty"`
+	RevokeReason     string     `gorm:"type:varchar(50)" json:"revoke_reason,omitempty"` // logout/rotated/password_changed/role_changed/disabled/deleted/2fa_reset
+	IP               string     `gorm:"type:varchar(64)" json:"ip"`
+	UserAgent        string     `gorm:"type:varchar(255)" json:"user_agent"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (s *AuthSession) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// DailyStat 按北京时间自然日汇总的新增数据，由 analytics 包的后台汇总任务和 cmd/rollup-stats 维护。
+// 各项按记录的 created_at 归入当天，统计时已软删除的记录不计入
+type DailyStat struct {
+	Day             time.Time `gorm:"type:date;primaryKey" json:"day"`
+	NewUsers        int64     `gorm:"not null;default:0" json:"new_users"`
+	TrainingRecords int64     `gorm:"not null;default:0" json:"training_records"`
+	MeditationCount int64     `gorm:"not null;default:0" json:"meditation_count"`
+	AirflowCount    int64     `gorm:"not null;default:0" json:"airflow_count"`
+	ExposureCount   int64     `gorm:"not null;default:0" json:"exposure_count"`
+	PracticeCount   int64     `gorm:"not null;default:0" json:"practice_count"`
+	TrainingSeconds int64     `gorm:"not null;default:0" json:"training_seconds"`
+	Posts           int64     `gorm:"not null;default:0" json:"posts"`
+	Comments        int64     `gorm:"not null;default:0" json:"comments"`
+	Likes           int64     `gorm:"not null;default:0" json:"likes"`
+	Collections     int64     `gorm:"not null;default:0" json:"collections"`
+	Follows         int64     `gorm:"not null;default:0" json:"follows"`
+	Rooms           int64     `gorm:"not null;default:0" json:"rooms"`
+	AIConversations int64     `gorm:"not null;default:0" json:"ai_conversations"`
+	ComputedAt      time.Time `gorm:"not null" json:"computed_at"`
+}
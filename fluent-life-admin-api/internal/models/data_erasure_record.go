@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DataErasureRecord 用户数据擦除记录，保存删除/匿名化了哪些数据，不保存被擦除的个人信息本身
+type DataErasureRecord struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID          uuid.UUID `gorm:"type:uuid;not null;index:idx_data_erasure_records_user_id" json:"user_id"`
+	UsernameHash    string    `gorm:"type:varchar(64);not null" json:"username_hash"` // 原用户名的 HMAC-SHA256（密钥见 privacy.HashUsername），用于核对申请人而不保留明文
+	RequestedBy     uuid.UUID `gorm:"type:uuid;not null" json:"requested_by"`
+	RequestedByName string    `gorm:"type:varchar(50);not null" json:"requested_by_name"`
+	Reason          string    `gorm:"type:text" json:"reason,omitempty"`
+	// ConsentRetained 用户同意数据收集时，训练统计类数据匿名化保留，否则一并删除
+	ConsentRetained bool      `gorm:"not null;default:false" json:"consent_retained"`
+	Removed         JSONB     `gorm:"type:jsonb" json:"removed"`    // {表名: 删除行数}
+	Anonymised      JSONB     `gorm:"type:jsonb" json:"anonymised"` // {表名: 匿名化行数}
+	MediaURLs       JSONB     `gorm:"type:jsonb" json:"media_urls"` // {"urls": [...]}，需要从对象存储中清理的上传文件
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (r *DataErasureRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
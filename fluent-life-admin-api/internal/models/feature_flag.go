@@ -0,0 +1,62 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StringList 以 JSON 数组保存的字符串列表
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), l)
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+// FeatureFlag 客户端功能开关，按规则对每个用户求值，见 internal/featureflag
+type FeatureFlag struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Key         string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"key"` // 客户端使用的键，例如 "random_match"
+	Name        string    `gorm:"type:varchar(100);not null" json:"name"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	// Enabled 总开关，关闭时对所有人关闭（包括白名单）
+	Enabled bool `gorm:"not null;default:false" json:"enabled"`
+	// RolloutPercent 灰度比例 0-100，按用户ID的稳定哈希分桶
+	RolloutPercent int `gorm:"not null;default:0" json:"rollout_percent"`
+	// AllowUsers 白名单用户ID，不受灰度比例和定向条件限制；DenyUsers 黑名单用户ID，优先于白名单
+	AllowUsers StringList `gorm:"type:jsonb;not null;default:'[]'" json:"allow_users"`
+	DenyUsers  StringList `gorm:"type:jsonb;not null;default:'[]'" json:"deny_users"`
+	// Languages 限定用户设置中的语言（UserSettings.Language），为空表示不限
+	Languages StringList `gorm:"type:jsonb;not null;default:'[]'" json:"languages"`
+	// MinAppVersion、MaxAppVersion 限定客户端版本范围（含两端），为空表示不限
+	MinAppVersion string    `gorm:"type:varchar(20)" json:"min_app_version"`
+	MaxAppVersion string    `gorm:"type:varchar(20)" json:"max_app_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (f *FeatureFlag) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ type Feedback struct {
 	Type      string    `gorm:"type:varchar(50);default:'feedback'" json:"type"` // feedback/bug/suggestion
 	Status    string    `gorm:"type:varchar(20);default:'pending'" json:"status"` // pending/processing/resolved
 	Response  *string   `gorm:"type:text" json:"response,omitempty"`              // 管理员回复
+	ScannedAt *time.Time `gorm:"index" json:"scanned_at,omitempty"`              // 敏感词扫描时间，为空表示尚未扫描
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -10,11 +10,15 @@ import (
 // HelpArticle 幫助中心的具體問答文章
 type HelpArticle struct {
 	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	CategoryID uuid.UUID `gorm:"type:uuid;not null" json:"category_id"` // 所屬分類 ID
+	CategoryID uuid.UUID `gorm:"type:uuid;not null;index:idx_help_articles_category_order,priority:1" json:"category_id"` // 所屬分類 ID
 	Question   string    `gorm:"type:varchar(255);not null" json:"question"` // 問題標題
 	Answer     string    `gorm:"type:text;not null" json:"answer"`         // 答案內容 (支援 Markdown 或 HTML)
-	Order      int       `gorm:"default:0" json:"order"`                 // 在分類內的排序值
+	Order      int       `gorm:"default:0;index:idx_help_articles_category_order,priority:2" json:"order"` // 在分類內的排序值
 	IsActive   bool      `gorm:"not null;default:true" json:"is_active"` // 是否啟用
+	// 客戶端的瀏覽和「是否有幫助」計數，只遞增
+	ViewCount       int64 `gorm:"not null;default:0" json:"view_count"`
+	HelpfulCount    int64 `gorm:"not null;default:0" json:"helpful_count"`
+	NotHelpfulCount int64 `gorm:"not null;default:0" json:"not_helpful_count"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
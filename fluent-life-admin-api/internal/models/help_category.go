@@ -12,6 +12,7 @@ type HelpCategory struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	Name      string    `gorm:"type:varchar(100);not null" json:"name"` // 分類名稱，例如 "常見問題"
 	Order     int       `gorm:"default:0" json:"order"`                 // 排序值，數字越小越靠前
+	IsActive  bool      `gorm:"not null;default:true" json:"is_active"` // 是否在客戶端顯示
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 后台任务状态
+const (
+	JobQueued    = "queued"    // 排队中，包括等待重试
+	JobRunning   = "running"   // 执行中
+	JobSucceeded = "succeeded" // 已完成
+	JobFailed    = "failed"    // 失败且不再重试
+	JobCancelled = "cancelled" // 已取消
+)
+
+// Job 后台任务。排队中的任务由 internal/jobs 的工作协程认领执行，失败后按退避时间重新排队，
+// 直到达到 MaxAttempts；执行中的任务定期写入心跳，进程中断后由其他工作协程重新排队
+type Job struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Type            string     `gorm:"type:varchar(100);not null;index:idx_jobs_type" json:"type"`
+	Status          string     `gorm:"type:varchar(20);not null;default:'queued';index:idx_jobs_status_run_at,priority:1" json:"status"`
+	Payload         JSONB      `gorm:"type:jsonb" json:"payload,omitempty"`
+	Result          JSONB      `gorm:"type:jsonb" json:"result,omitempty"`
+	Error           string     `gorm:"type:text" json:"error,omitempty"`   // 最近一次失败的原因，重试成功后清空
+	Progress        int        `gorm:"not null;default:0" json:"progress"` // 0-100
+	ProgressMessage string     `gorm:"type:varchar(255)" json:"progress_message,omitempty"`
+	Attempts        int        `gorm:"not null;default:0" json:"attempts"` // 已开始执行的次数
+	MaxAttempts     int        `gorm:"not null;default:3" json:"max_attempts"`
+	RunAt           time.Time  `gorm:"not null;index:idx_jobs_status_run_at,priority:2" json:"run_at"` // 最早执行时间，重试时后延
+	CancelRequested bool       `gorm:"not null;default:false" json:"cancel_requested"`                 // 执行中的任务收到取消请求，等待工作协程停止
+	WorkerID        string     `gorm:"type:varchar(100)" json:"worker_id,omitempty"`
+	HeartbeatAt     *time.Time `json:"heartbeat_at,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      *time.Time `gorm:"index:idx_jobs_finished_at" json:"finished_at,omitempty"`
+	CreatedBy       uuid.UUID  `gorm:"type:uuid;not null;index:idx_jobs_created_by" json:"created_by"`
+	CreatedByName   string     `gorm:"type:varchar(50)" json:"created_by_name"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (j *Job) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// Finished 任务是否已结束，不会再执行
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
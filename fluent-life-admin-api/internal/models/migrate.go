@@ -3,7 +3,13 @@ package models
 import "gorm.io/gorm"
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	// 审核字段上线前已发布的帖子和评论视为已通过，新内容默认待审核
+	backfillPosts := db.Migrator().HasTable(&Post{}) && !db.Migrator().HasColumn(&Post{}, "moderation_status")
+	backfillComments := db.Migrator().HasTable(&Comment{}) && !db.Migrator().HasColumn(&Comment{}, "moderation_status")
+	// 记录封禁是否禁用了账号之前，封禁结束时总会恢复账号，已有的封禁保持这一行为
+	backfillSanctions := db.Migrator().HasTable(&UserSanction{}) && !db.Migrator().HasColumn(&UserSanction{}, "disabled_account")
+
+	err := db.AutoMigrate(
 		&User{},
 		&VerificationCode{},
 		&TrainingRecord{},
@@ -30,7 +36,43 @@ func AutoMigrate(db *gorm.DB) error {
 		&Role{},
 		&Menu{},
 		&RandomMatchRecord{},
+		&AuthSession{},
+		&RecoveryCode{},
+		&DataErasureRecord{},
+		&SensitiveWordCategory{},
+		&SensitiveWord{},
+		&SensitiveHit{},
+		&Report{},
+		&UserSanction{},
+		&DailyStat{},
+		&Job{},
+		&HelpCategory{},
+		&HelpArticle{},
+		&AppSettingRevision{},
+		&FeatureFlag{},
+		&AIRole{},
+		&AIRolePromptVersion{},
 	)
+	if err != nil {
+		return err
+	}
+
+	if backfillPosts {
+		if err := db.Model(&Post{}).Unscoped().Where("1 = 1").UpdateColumn("moderation_status", ModerationApproved).Error; err != nil {
+			return err
+		}
+	}
+	if backfillComments {
+		if err := db.Model(&Comment{}).Unscoped().Where("1 = 1").UpdateColumn("moderation_status", ModerationApproved).Error; err != nil {
+			return err
+		}
+	}
+	if backfillSanctions {
+		if err := db.Model(&UserSanction{}).Where("type = ?", SanctionBan).UpdateColumn("disabled_account", true).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 
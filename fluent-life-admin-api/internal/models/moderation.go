@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 审核状态
+const (
+	ModerationPending  = "pending"  // 待审核
+	ModerationApproved = "approved" // 已通过
+	ModerationRejected = "rejected" // 已驳回，未曾公开或审核前即被拦截
+	ModerationHidden   = "hidden"   // 已隐藏，通过后又被下架
+)
+
+// ModerationReasons 驳回/隐藏的原因代码
+var ModerationReasons = map[string]string{
+	"spam":       "垃圾广告",
+	"abuse":      "辱骂攻击",
+	"sexual":     "色情低俗",
+	"illegal":    "违法违规",
+	"privacy":    "泄露隐私",
+	"misleading": "虚假误导",
+	"off_topic":  "与社区无关",
+	"other":      "其他",
+	// sensitive_word 由敏感词过滤自动设置
+	"sensitive_word": "命中敏感词",
+}
+
+// Moderation 帖子和评论共用的审核字段
+type Moderation struct {
+	ModerationStatus string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"moderation_status"`
+	RiskScore        int        `gorm:"not null;default:0;index" json:"risk_score"` // 0-100，越高越优先审核
+	ModerationReason string     `gorm:"type:varchar(50)" json:"moderation_reason,omitempty"`
+	ModerationNote   string     `gorm:"type:text" json:"moderation_note,omitempty"`
+	ModeratedBy      *uuid.UUID `gorm:"type:uuid" json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	ScannedAt        *time.Time `gorm:"index" json:"scanned_at,omitempty"` // 敏感词扫描时间，为空表示尚未扫描
+}
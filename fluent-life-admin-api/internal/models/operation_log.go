@@ -7,15 +7,24 @@ import (
 )
 
 // OperationLog represents an administrative operation log entry.
+// Mutating admin requests are recorded by middleware.Audit together with the request
+// context and JSON snapshots of the affected entity before and after the change.
 type OperationLog struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	Username  string    `gorm:"type:varchar(50);not null" json:"username"`
-	UserRole  string    `gorm:"type:varchar(20);not null" json:"user_role"`
-	Action    string    `gorm:"type:varchar(100);not null" json:"action"`
-	Resource  string    `gorm:"type:varchar(100);not null" json:"resource"` // e.g., "User", "Post", "Room"
-	ResourceID string    `gorm:"type:varchar(255)" json:"resource_id,omitempty"` // ID of the affected resource
-	Details   string    `gorm:"type:text" json:"details,omitempty"`
-	Status    string    `gorm:"type:varchar(20);not null" json:"status"` // "Success", "Failure"
-	CreatedAt time.Time `json:"created_at"`
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index:idx_operation_logs_user_id" json:"user_id"`
+	Username   string    `gorm:"type:varchar(50);not null" json:"username"`
+	UserRole   string    `gorm:"type:varchar(50);not null" json:"user_role"` // 与 roles.code 长度一致
+	Action     string    `gorm:"type:varchar(100);not null" json:"action"`
+	Resource   string    `gorm:"type:varchar(100);not null;index:idx_operation_logs_resource" json:"resource"`        // e.g., "User", "Post", "Room"
+	ResourceID string    `gorm:"type:varchar(255);index:idx_operation_logs_resource_id" json:"resource_id,omitempty"` // ID of the affected resource
+	Details    string    `gorm:"type:text" json:"details,omitempty"`
+	Status     string    `gorm:"type:varchar(20);not null" json:"status"` // "Success", "Failure"
+	IP         string    `gorm:"type:varchar(64)" json:"ip,omitempty"`
+	UserAgent  string    `gorm:"type:varchar(255)" json:"user_agent,omitempty"`
+	Method     string    `gorm:"type:varchar(10)" json:"method,omitempty"`
+	Path       string    `gorm:"type:varchar(255)" json:"path,omitempty"` // 路由模板，例如 /api/v1/admin/users/:id
+	Before     JSONB     `gorm:"type:jsonb" json:"before,omitempty"`      // 变更前快照（敏感字段已脱敏）
+	After      JSONB     `gorm:"type:jsonb" json:"after,omitempty"`       // 变更后快照（敏感字段已脱敏）
+	Diff       JSONB     `gorm:"type:jsonb" json:"diff,omitempty"`        // {字段: {before, after}}
+	CreatedAt  time.Time `gorm:"index:idx_operation_logs_created_at" json:"created_at"`
 }
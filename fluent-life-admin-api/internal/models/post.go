@@ -14,8 +14,10 @@ type Post struct {
 	Tag           string    `gorm:"type:varchar(50);index:idx_posts_tag" json:"tag"`
 	LikesCount    int       `gorm:"not null;default:0" json:"likes_count"`
 	CommentsCount int       `gorm:"not null;default:0" json:"comments_count"`
+	Moderation
 	CreatedAt     time.Time `gorm:"index:idx_posts_created_at" json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	User     User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Likes    []PostLike `gorm:"foreignKey:PostID" json:"likes,omitempty"`
@@ -38,8 +40,10 @@ type Comment struct {
 	UserID     uuid.UUID `gorm:"type:uuid;not null;index:idx_comments_user_id" json:"user_id"`
 	Content    string    `gorm:"type:text;not null" json:"content"`
 	LikesCount int       `gorm:"not null;default:0" json:"likes_count"`
+	Moderation
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	Post  Post          `gorm:"foreignKey:PostID" json:"-"`
 	User  User          `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -18,6 +18,7 @@ type TongueTwister struct {
 	IsActive    bool      `gorm:"not null;default:true;index:idx_tongue_twister_active" json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 // DailyExpression 每日朗诵文案模型
@@ -31,6 +32,7 @@ type DailyExpression struct {
 	IsActive    bool      `gorm:"not null;default:true;index:idx_daily_expression_active" json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 func (t *TongueTwister) BeforeCreate(tx *gorm.DB) error {
@@ -59,6 +61,7 @@ type SpeechTechnique struct {
 	IsActive      bool      `gorm:"not null;default:true;index:idx_speech_technique_active" json:"is_active"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 func (s *SpeechTechnique) BeforeCreate(tx *gorm.DB) error {
@@ -18,8 +18,10 @@ type PracticeRoom struct {
 	MaxMembers  int       `gorm:"not null;default:2" json:"max_members"` // 最大成员数
 	CurrentMembers int    `gorm:"not null;default:1" json:"current_members"` // 当前成员数
 	IsActive    bool      `gorm:"not null;default:true;index:idx_rooms_active" json:"is_active"`
+	ScannedAt   *time.Time `gorm:"index" json:"scanned_at,omitempty"` // 敏感词扫描时间，为空表示尚未扫描
 	CreatedAt   time.Time `gorm:"index:idx_rooms_created_at" json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	User    User              `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Members []PracticeRoomMember `gorm:"foreignKey:RoomID" json:"members,omitempty"`
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 举报对象类型
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetRoom    = "room"
+	ReportTargetUser    = "user"
+)
+
+// 举报状态
+const (
+	ReportStatusPending   = "pending"   // 待处理
+	ReportStatusResolved  = "resolved"  // 已处理（删除内容、警告或封禁）
+	ReportStatusDismissed = "dismissed" // 已驳回
+)
+
+// Report 用户举报，由客户端提交。同一对象的多条举报在后台合并为一个案件处理
+type Report struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ReporterID     uuid.UUID  `gorm:"type:uuid;not null;index:idx_reports_reporter_id" json:"reporter_id"`
+	TargetType     string     `gorm:"type:varchar(20);not null;index:idx_reports_target" json:"target_type"` // post, comment, room, user
+	TargetID       uuid.UUID  `gorm:"type:uuid;not null;index:idx_reports_target" json:"target_id"`
+	Reason         string     `gorm:"type:varchar(50);not null" json:"reason"` // 原因代码，同 ModerationReasons
+	Evidence       string     `gorm:"type:text" json:"evidence,omitempty"`     // 举报人补充的说明
+	Status         string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_reports_status" json:"status"`
+	Resolution     string     `gorm:"type:varchar(20)" json:"resolution,omitempty"` // dismiss, remove, warn, ban
+	ResolutionNote string     `gorm:"type:text" json:"resolution_note,omitempty"`
+	ResolvedBy     *uuid.UUID `gorm:"type:uuid" json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"index:idx_reports_created_at" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Reporter User `gorm:"foreignKey:ReporterID" json:"reporter,omitempty"`
+}
+
+func (r *Report) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -13,8 +13,13 @@ type Role struct {
 	Code        string    `gorm:"type:varchar(50);not null;unique" json:"code"`        // 角色代码
 	Description string    `gorm:"type:text" json:"description"`                        // 描述
 	Permissions JSONB     `gorm:"type:jsonb;default:'[]'::jsonb" json:"permissions"`  // 权限列表，JSON数组
+	// ParentID 上级角色，角色继承上级角色（及其上级）的全部权限和菜单
+	ParentID *uuid.UUID `gorm:"type:uuid;index" json:"parent_id,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Menus 角色可见的侧边栏菜单，未分配的父级菜单在菜单树中自动补齐
+	Menus []Menu `gorm:"many2many:role_menus;" json:"menus,omitempty"`
 }
 
 func (r *Role) BeforeCreate(tx *gorm.DB) error {
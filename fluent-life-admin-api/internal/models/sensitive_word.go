@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 敏感词分类的处置策略
+const (
+	SensitiveActionReview = "review" // 进入审核队列（提高风险分，已通过的内容退回待审核）
+	SensitiveActionHide   = "hide"   // 自动隐藏
+)
+
+// SensitiveWordCategory 敏感词分类，例如 political、abuse、spam、self_harm
+type SensitiveWordCategory struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Code        string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"`
+	Name        string    `gorm:"type:varchar(50);not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	Action      string    `gorm:"type:varchar(20);not null;default:'review'" json:"action"` // review, hide
+	RiskScore   int       `gorm:"not null;default:50" json:"risk_score"`                    // 命中时增加的风险分，0-100
+	Enabled     bool      `gorm:"not null;default:true" json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SensitiveWord 敏感词，普通词按不区分大小写、全角半角统一后的文本匹配，正则按 RE2 语法匹配
+type SensitiveWord struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CategoryID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_sensitive_words_category_word" json:"category_id"`
+	Word       string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_sensitive_words_category_word" json:"word"`
+	IsRegex    bool      `gorm:"not null;default:false" json:"is_regex"`
+	Enabled    bool      `gorm:"not null;default:true" json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Category SensitiveWordCategory `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+}
+
+// SensitiveHit 内容命中敏感词的记录
+type SensitiveHit struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TargetType string    `gorm:"type:varchar(20);not null;index:idx_sensitive_hits_target" json:"target_type"` // posts, comments, rooms, feedback
+	TargetID   uuid.UUID `gorm:"type:uuid;not null;index:idx_sensitive_hits_target" json:"target_id"`
+	Words      JSONB     `gorm:"type:jsonb" json:"words"` // {"words": [...], "categories": [...], "matches": [...]}
+	RiskScore  int       `gorm:"not null;default:0" json:"risk_score"`
+	Action     string    `gorm:"type:varchar(20);not null" json:"action"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+func (c *SensitiveWordCategory) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+func (w *SensitiveWord) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+func (h *SensitiveHit) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
@@ -37,7 +37,7 @@ type TrainingRecord struct {
 	Duration  int       `gorm:"not null" json:"duration"`                                              // 秒
 	Data      JSONB     `gorm:"type:jsonb;index:,type:gin" json:"data,omitempty"`
 	Timestamp time.Time `gorm:"not null;index:idx_training_records_timestamp;index:idx_training_records_user_timestamp" json:"timestamp"`
-	CreatedAt time.Time `json:"created_at"`
+	CreatedAt time.Time `gorm:"index:idx_training_records_created_at" json:"created_at"`
 
 	User User `gorm:"foreignKey:UserID" json:"user"`
 }
@@ -15,11 +15,22 @@ type User struct {
 	PasswordHash string     `gorm:"type:varchar(255);not null" json:"-"`
 	AvatarURL    *string    `gorm:"type:varchar(500)" json:"avatar_url,omitempty"`
 	Status       int        `gorm:"not null;default:1" json:"status"` // 0-禁用, 1-正常
-	Role         string     `gorm:"type:varchar(20);not null;default:'user'" json:"role"` // user, admin, super_admin
+	Role         string     `gorm:"type:varchar(50);not null;default:'user'" json:"role"` // 主角色，对应 roles.code
 	Gender       *string    `gorm:"type:varchar(10)" json:"gender,omitempty"` // 性别
-	CreatedAt    time.Time  `json:"created_at"`
+	CreatedAt    time.Time  `gorm:"index:idx_users_created_at" json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	// MustChangePassword 为 true 时，该账号登录后台后必须先修改密码才能访问其他接口
+	MustChangePassword bool `gorm:"not null;default:false" json:"must_change_password"`
+	// 两步验证：TOTPSecret 在启用前即为待确认的密钥，TOTPLastStep 用于拒绝重放已使用过的验证码
+	TOTPSecret   string `gorm:"type:varchar(64)" json:"-"`
+	TOTPEnabled  bool   `gorm:"not null;default:false" json:"totp_enabled"`
+	TOTPLastStep int64  `gorm:"not null;default:0" json:"-"`
+	// DeletedAt 软删除时间，已删除的用户进入回收站，可恢复
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Roles 附加角色。Role 是主角色，决定能否登录后台；有效权限为主角色与附加角色（含各自继承的上级角色）权限的并集
+	Roles []Role `gorm:"many2many:user_roles;" json:"roles,omitempty"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
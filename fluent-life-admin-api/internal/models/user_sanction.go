@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 处罚类型
+const (
+	SanctionBan        = "ban"         // 封禁账号，生效期间 users.status 为 0
+	SanctionMute       = "mute"        // 禁止发帖和评论
+	SanctionRoomBlock  = "room_block"  // 禁止创建房间
+	SanctionMatchBlock = "match_block" // 禁止随机匹配
+)
+
+// SanctionTypes 处罚类型及中文名称
+var SanctionTypes = map[string]string{
+	SanctionBan:        "封禁",
+	SanctionMute:       "禁言",
+	SanctionRoomBlock:  "禁止创建房间",
+	SanctionMatchBlock: "禁止随机匹配",
+}
+
+// 处罚状态，由时间和撤销/到期记录推算
+const (
+	SanctionStateScheduled = "scheduled" // 尚未开始
+	SanctionStateActive    = "active"
+	SanctionStateExpired   = "expired"
+	SanctionStateRevoked   = "revoked"
+)
+
+// UserSanction 用户处罚记录。EndsAt 为空表示永久；到期由后台任务写入 ExpiredAt
+type UserSanction struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index:idx_user_sanctions_user_id" json:"user_id"`
+	Type         string     `gorm:"type:varchar(20);not null;index:idx_user_sanctions_type" json:"type"`
+	Reason       string     `gorm:"type:text;not null" json:"reason"`
+	StartsAt     time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt       *time.Time `gorm:"index:idx_user_sanctions_ends_at" json:"ends_at,omitempty"`
+	IssuedBy     uuid.UUID  `gorm:"type:uuid;not null" json:"issued_by"`
+	IssuedByName string     `gorm:"type:varchar(50)" json:"issued_by_name"`
+	ReportID     *uuid.UUID `gorm:"type:uuid" json:"report_id,omitempty"` // 由举报处理产生时关联的举报
+	ExpiredAt    *time.Time `json:"expired_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy    *uuid.UUID `gorm:"type:uuid" json:"revoked_by,omitempty"`
+	RevokeReason string     `gorm:"type:text" json:"revoke_reason,omitempty"`
+	// DisabledAccount 封禁时账号的禁用状态由本封禁造成（账号原本正常），封禁结束时才恢复账号；
+	// 管理员另行禁用的账号不会被封禁结束恢复
+	DisabledAccount bool      `gorm:"not null;default:false" json:"disabled_account"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// State 查询时按当前时间推算的状态
+	State string `gorm:"-" json:"state"`
+}
+
+// StateAt 处罚在指定时间的状态
+func (s *UserSanction) StateAt(now time.Time) string {
+	switch {
+	case s.RevokedAt != nil:
+		return SanctionStateRevoked
+	case s.ExpiredAt != nil || (s.EndsAt != nil && !s.EndsAt.After(now)):
+		return SanctionStateExpired
+	case s.StartsAt.After(now):
+		return SanctionStateScheduled
+	}
+	return SanctionStateActive
+}
+
+func (s *UserSanction) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
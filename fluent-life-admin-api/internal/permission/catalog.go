@@ -0,0 +1,78 @@
+package permission
+
+// Catalog 后台全部权限代码，按模块分组。
+// 路由只能使用这里声明过的权限代码，角色也只能被授予这些代码（以及表示全部权限的 "*"）
+var Catalog = []Module{
+	{Code: "user", Name: "用户管理", Permissions: []Definition{
+		{Code: "user:read", Description: "查看用户、关注、验证码、用户设置和处罚记录"},
+		{Code: "user:write", Description: "编辑和删除用户，处罚用户，修改用户设置"},
+		{Code: "user:2fa-reset", Description: "重置用户的两步验证"},
+		{Code: "user:export", Description: "导出用户个人数据和用户列表"},
+		{Code: "user:erase", Description: "擦除用户个人数据并查看擦除记录"},
+	}},
+	{Code: "post", Name: "帖子管理", Permissions: []Definition{
+		{Code: "post:read", Description: "查看帖子、点赞、收藏和帖子审核队列"},
+		{Code: "post:write", Description: "编辑、删除和审核帖子"},
+	}},
+	{Code: "comment", Name: "评论管理", Permissions: []Definition{
+		{Code: "comment:read", Description: "查看评论和评论审核队列"},
+		{Code: "comment:write", Description: "编辑、删除和审核评论"},
+	}},
+	{Code: "room", Name: "房间管理", Permissions: []Definition{
+		{Code: "room:read", Description: "查看练习房间"},
+		{Code: "room:write", Description: "创建、编辑、关闭和删除练习房间"},
+	}},
+	{Code: "training", Name: "训练管理", Permissions: []Definition{
+		{Code: "training:read", Description: "查看训练统计、训练记录、随机匹配、成就和冥想进度"},
+		{Code: "training:write", Description: "修改和删除训练记录、成就和冥想进度"},
+	}},
+	{Code: "analytics", Name: "数据分析", Permissions: []Definition{
+		{Code: "analytics:read", Description: "查看用户、训练和社区的趋势分析"},
+	}},
+	{Code: "content", Name: "内容管理", Permissions: []Definition{
+		{Code: "content:read", Description: "查看绕口令、朗诵文案、语音技巧、法律文档、帮助中心和脱敏练习场景"},
+		{Code: "content:write", Description: "维护绕口令、朗诵文案、语音技巧、法律文档、帮助中心和脱敏练习场景"},
+	}},
+	{Code: "feedback", Name: "用户反馈", Permissions: []Definition{
+		{Code: "feedback:read", Description: "查看用户反馈及统计"},
+		{Code: "feedback:write", Description: "处理和删除用户反馈"},
+	}},
+	{Code: "report", Name: "举报处理", Permissions: []Definition{
+		{Code: "report:read", Description: "查看举报、举报案件和统计"},
+		{Code: "report:write", Description: "处理举报案件"},
+	}},
+	{Code: "sensitive", Name: "敏感词", Permissions: []Definition{
+		{Code: "sensitive:read", Description: "查看敏感词词库和命中记录，检测文本"},
+		{Code: "sensitive:write", Description: "维护敏感词词库，重新扫描待审核内容"},
+	}},
+	{Code: "ai", Name: "AI管理", Permissions: []Definition{
+		{Code: "ai:read", Description: "查看AI对话、AI模拟角色和音色"},
+		{Code: "ai:write", Description: "维护AI模拟角色和音色，删除AI对话"},
+	}},
+	{Code: "setting", Name: "应用设置", Permissions: []Definition{
+		{Code: "setting:read", Description: "查看应用设置及其变更历史"},
+		{Code: "setting:write", Description: "修改应用设置、恢复默认值和回滚到历史版本"},
+	}},
+	{Code: "flag", Name: "功能开关", Permissions: []Definition{
+		{Code: "flag:read", Description: "查看功能开关，查看指定用户的开关求值结果"},
+		{Code: "flag:write", Description: "创建、修改和删除功能开关"},
+	}},
+	{Code: "video", Name: "视频管理", Permissions: []Definition{
+		{Code: "video:read", Description: "查看视频"},
+		{Code: "video:write", Description: "删除视频"},
+	}},
+	{Code: "log", Name: "操作日志", Permissions: []Definition{
+		{Code: "log:read", Description: "查看操作日志"},
+	}},
+	{Code: "job", Name: "后台任务", Permissions: []Definition{
+		{Code: "job:manage", Description: "查看和取消其他管理员提交的后台任务"},
+	}},
+	{Code: "permission", Name: "权限管理", Permissions: []Definition{
+		{Code: "permission:read", Description: "查看角色、菜单、权限目录和用户的有效权限"},
+		{Code: "permission:write", Description: "维护角色、菜单及角色的权限和菜单分配，分配用户的附加角色"},
+	}},
+	{Code: "self", Name: "普通用户", Permissions: []Definition{
+		// 客户端使用，后台路由不需要
+		{Code: "self:read", Description: "查看自己的数据"},
+	}},
+}
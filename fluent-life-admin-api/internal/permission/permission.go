@@ -0,0 +1,146 @@
+// Package permission 维护权限目录，并记录每个后台路由所需的权限。
+//
+// 路由通过 Router 注册时声明权限代码，Registry 据此得到权限与路由的对应关系，
+// 用于列出权限目录、校验角色权限以及统计角色可访问的路由。
+package permission
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// All 表示拥有全部权限
+const All = "*"
+
+// Module 权限模块
+type Module struct {
+	Code        string       `json:"code"`
+	Name        string       `json:"name"`
+	Permissions []Definition `json:"permissions"`
+}
+
+// Definition 权限定义
+type Definition struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// Route 需要权限的后台路由
+type Route struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Permission string `json:"permission"`
+	Handler    string `json:"handler"`
+}
+
+// Registry 权限目录及使用各权限的路由。
+// 路由只在启动时注册，之后只读，因此不需要加锁
+type Registry struct {
+	modules []Module
+	known   map[string]bool
+	routes  []Route
+}
+
+// NewRegistry 创建权限注册表，权限代码重复时 panic
+func NewRegistry(modules []Module) *Registry {
+	r := &Registry{modules: modules, known: map[string]bool{}}
+	for _, m := range modules {
+		for _, d := range m.Permissions {
+			if r.known[d.Code] {
+				panic("duplicate permission code " + d.Code)
+			}
+			r.known[d.Code] = true
+		}
+	}
+	return r
+}
+
+// Known 判断权限代码是否在目录中，"*" 视为已知
+func (r *Registry) Known(code string) bool {
+	return code == All || r.known[code]
+}
+
+// Unknown 返回不在目录中的权限代码
+func (r *Registry) Unknown(codes []string) []string {
+	var unknown []string
+	for _, code := range codes {
+		if !r.Known(code) {
+			unknown = append(unknown, code)
+		}
+	}
+	return unknown
+}
+
+// Modules 权限目录
+func (r *Registry) Modules() []Module {
+	return r.modules
+}
+
+// Routes 已注册的路由，按注册顺序
+func (r *Registry) Routes() []Route {
+	return r.routes
+}
+
+// Router 在注册路由的同时挂载权限校验并登记到 Registry
+type Router struct {
+	group    *gin.RouterGroup
+	registry *Registry
+	require  func(code string) gin.HandlerFunc
+}
+
+// NewRouter require 根据权限代码创建校验中间件
+func NewRouter(group *gin.RouterGroup, registry *Registry, require func(code string) gin.HandlerFunc) *Router {
+	return &Router{group: group, registry: registry, require: require}
+}
+
+// Group 创建子路由组
+func (rt *Router) Group(relativePath string) *Router {
+	return &Router{group: rt.group.Group(relativePath), registry: rt.registry, require: rt.require}
+}
+
+func (rt *Router) GET(relativePath, code string, handlers ...gin.HandlerFunc) {
+	rt.handle("GET", relativePath, code, handlers)
+}
+
+func (rt *Router) POST(relativePath, code string, handlers ...gin.HandlerFunc) {
+	rt.handle("POST", relativePath, code, handlers)
+}
+
+func (rt *Router) PUT(relativePath, code string, handlers ...gin.HandlerFunc) {
+	rt.handle("PUT", relativePath, code, handlers)
+}
+
+func (rt *Router) PATCH(relativePath, code string, handlers ...gin.HandlerFunc) {
+	rt.handle("PATCH", relativePath, code, handlers)
+}
+
+func (rt *Router) DELETE(relativePath, code string, handlers ...gin.HandlerFunc) {
+	rt.handle("DELETE", relativePath, code, handlers)
+}
+
+// handle 权限代码不在目录中时 panic，保证目录覆盖所有路由
+func (rt *Router) handle(method, relativePath, code string, handlers []gin.HandlerFunc) {
+	fullPath := path.Join(rt.group.BasePath(), relativePath)
+	if !rt.registry.known[code] {
+		panic(fmt.Sprintf("route %s %s uses unknown permission %q", method, fullPath, code))
+	}
+	rt.registry.routes = append(rt.registry.routes, Route{
+		Method:     method,
+		Path:       fullPath,
+		Permission: code,
+		Handler:    handlerName(handlers[len(handlers)-1]),
+	})
+	rt.group.Handle(method, relativePath, append([]gin.HandlerFunc{rt.require(code)}, handlers...)...)
+}
+
+// handlerName 处理函数名，例如 handlers.(*AdminHandler).GetUsers
+func handlerName(h gin.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
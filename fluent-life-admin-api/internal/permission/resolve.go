@@ -0,0 +1,118 @@
+package permission
+
+import (
+	"fluent-life-admin-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SuperAdminRole 超级管理员角色代码。即使 roles 表尚未初始化，也始终拥有全部权限，避免默认管理员被锁在系统之外
+const SuperAdminRole = "super_admin"
+
+// Set 一个用户的有效权限：主角色和附加角色的权限，以及它们沿 parent_id 继承的上级角色的权限
+type Set struct {
+	// Roles 生效的角色代码，包括继承的上级角色，按解析顺序
+	Roles []string
+	// Grants 权限代码及授予该权限的角色代码
+	Grants map[string][]string
+	all    bool
+}
+
+// Has 判断是否拥有指定权限
+func (s *Set) Has(code string) bool {
+	return s.all || len(s.Grants[code]) > 0
+}
+
+// All 是否拥有全部权限
+func (s *Set) All() bool {
+	return s.all
+}
+
+// Covers 判断是否拥有 other 中的全部权限
+func (s *Set) Covers(other *Set) bool {
+	if s.all {
+		return true
+	}
+	if other.all {
+		return false
+	}
+	for code, grantedBy := range other.Grants {
+		if len(grantedBy) > 0 && !s.Has(code) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve 按角色代码计算有效权限，roles 为 roles 表中的全部角色；不存在的角色代码忽略
+func Resolve(roles []models.Role, codes []string) *Set {
+	byCode := make(map[string]*models.Role, len(roles))
+	byID := make(map[uuid.UUID]*models.Role, len(roles))
+	for i := range roles {
+		byCode[roles[i].Code] = &roles[i]
+		byID[roles[i].ID] = &roles[i]
+	}
+
+	set := &Set{Grants: map[string][]string{}}
+	seen := map[uuid.UUID]bool{}
+	for _, code := range codes {
+		if code == SuperAdminRole {
+			set.all = true
+		}
+		// 沿上级角色向上继承；seen 同时用于去重和防止数据中的环
+		for role := byCode[code]; role != nil && !seen[role.ID]; role = parentOf(role, byID) {
+			seen[role.ID] = true
+			set.Roles = append(set.Roles, role.Code)
+			// 继承超级管理员的角色同样拥有全部权限
+			if role.Code == SuperAdminRole {
+				set.all = true
+			}
+			for perm, granted := range role.Permissions {
+				if ok, _ := granted.(bool); ok {
+					set.Grants[perm] = append(set.Grants[perm], role.Code)
+				}
+			}
+		}
+	}
+	if len(set.Grants[All]) > 0 {
+		set.all = true
+	}
+	return set
+}
+
+// ForUser 计算用户的有效权限，primaryRole 为 users.role
+func ForUser(db *gorm.DB, userID uuid.UUID, primaryRole string) (*Set, error) {
+	assigned, err := AssignedRoles(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ForRoles(db, append([]string{primaryRole}, assigned...))
+}
+
+// ForRoles 计算一组角色代码（含继承的上级角色）的有效权限
+func ForRoles(db *gorm.DB, codes []string) (*Set, error) {
+	var roles []models.Role
+	if err := db.Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return Resolve(roles, codes), nil
+}
+
+// AssignedRoles 用户的附加角色代码
+func AssignedRoles(db *gorm.DB, userID uuid.UUID) ([]string, error) {
+	var codes []string
+	err := db.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.code ASC").
+		Pluck("roles.code", &codes).Error
+	return codes, err
+}
+
+func parentOf(role *models.Role, byID map[uuid.UUID]*models.Role) *models.Role {
+	if role.ParentID == nil {
+		return nil
+	}
+	return byID[*role.ParentID]
+}
@@ -0,0 +1,453 @@
+// Package privacy 实现用户个人数据的导出（数据可携带权）和擦除（删除权）。
+package privacy
+
+import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"fluent-life-admin-api/internal/analytics"
+	"fluent-life-admin-api/internal/models"
+)
+
+// ErrUserNotFound 用户不存在（包括已彻底删除）
+var ErrUserNotFound = errors.New("user not found")
+
+// hashKey 擦除记录中用户名哈希的 HMAC 密钥。用户名很短，不加密钥的哈希可以用字典还原，
+// 生产环境必须通过 PRIVACY_HASH_SECRET 设置
+var hashKey = []byte(getHashSecret())
+
+func getHashSecret() string {
+	secret := os.Getenv("PRIVACY_HASH_SECRET")
+	if secret == "" {
+		// Fallback for development if PRIVACY_HASH_SECRET is not set
+		secret = "privacyhashsecretthatshouldbechangedinproduction"
+	}
+	return secret
+}
+
+// sensitiveColumns 不导出的凭据类字段
+var sensitiveColumns = []string{"password_hash", "totp_secret", "totp_last_step", "refresh_token_hash", "code_hash", "code"}
+
+// section 导出归档中的一个文件
+type section struct {
+	file  string
+	model interface{}
+	where string
+	args  func(u *models.User) []interface{}
+}
+
+func byUserID(u *models.User) []interface{} { return []interface{}{u.ID} }
+
+// sections 导出的全部数据，软删除的记录同样导出
+var sections = []section{
+	{file: "user_settings.json", model: &models.UserSettings{}, where: "user_id = ?", args: byUserID},
+	{file: "training_records.json", model: &models.TrainingRecord{}, where: "user_id = ?", args: byUserID},
+	{file: "ai_conversations.json", model: &models.AIConversation{}, where: "user_id = ?", args: byUserID},
+	{file: "posts.json", model: &models.Post{}, where: "user_id = ?", args: byUserID},
+	{file: "comments.json", model: &models.Comment{}, where: "user_id = ?", args: byUserID},
+	{file: "post_likes.json", model: &models.PostLike{}, where: "user_id = ?", args: byUserID},
+	{file: "comment_likes.json", model: &models.CommentLike{}, where: "user_id = ?", args: byUserID},
+	{file: "post_collections.json", model: &models.PostCollection{}, where: "user_id = ?", args: byUserID},
+	{file: "follows.json", model: &models.Follow{}, where: "follower_id = ? OR followee_id = ?", args: func(u *models.User) []interface{} {
+		return []interface{}{u.ID, u.ID}
+	}},
+	{file: "feedback.json", model: &models.Feedback{}, where: "user_id = ?", args: byUserID},
+	{file: "reports.json", model: &models.Report{}, where: "reporter_id = ?", args: byUserID},
+	{file: "sanctions.json", model: &models.UserSanction{}, where: "user_id = ?", args: byUserID},
+	{file: "random_match_records.json", model: &models.RandomMatchRecord{}, where: "user_id = ? OR matched_user_id = ?", args: func(u *models.User) []interface{} {
+		return []interface{}{u.ID, u.ID}
+	}},
+	{file: "meditation_progress.json", model: &models.MeditationProgress{}, where: "user_id = ?", args: byUserID},
+	{file: "achievements.json", model: &models.Achievement{}, where: "user_id = ?", args: byUserID},
+	{file: "practice_rooms.json", model: &models.PracticeRoom{}, where: "user_id = ?", args: byUserID},
+	{file: "practice_room_members.json", model: &models.PracticeRoomMember{}, where: "user_id = ?", args: byUserID},
+	{file: "login_sessions.json", model: &models.AuthSession{}, where: "user_id = ?", args: byUserID},
+	{file: "verification_codes.json", model: &models.VerificationCode{}, where: "identifier IN ?", args: func(u *models.User) []interface{} {
+		return []interface{}{identifiers(u)}
+	}},
+}
+
+// loadUser 加载用户（包括回收站中的用户）
+func loadUser(db *gorm.DB, userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := db.Unscoped().Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// identifiers 用户的邮箱和手机号，验证码按此关联
+func identifiers(u *models.User) []string {
+	ids := []string{}
+	if u.Email != nil && *u.Email != "" {
+		ids = append(ids, *u.Email)
+	}
+	if u.Phone != nil && *u.Phone != "" {
+		ids = append(ids, *u.Phone)
+	}
+	return ids
+}
+
+// Export 把用户的全部数据按实体写入 zip 归档（每个实体一个 JSON 文件，另附 manifest.json）
+func Export(db *gorm.DB, userID uuid.UUID, w io.Writer) error {
+	user, err := loadUser(db, userID)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	counts := map[string]int{}
+
+	userRow, err := loadRows(db, &models.User{}, "id = ?", user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "user.json", firstOrNil(userRow)); err != nil {
+		return err
+	}
+	counts["user.json"] = len(userRow)
+
+	for _, s := range sections {
+		rows, err := loadRows(db, s.model, s.where, s.args(user)...)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", s.file, err)
+		}
+		if err := writeJSON(zw, s.file, rows); err != nil {
+			return err
+		}
+		counts[s.file] = len(rows)
+	}
+
+	urls, err := mediaURLs(db, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(zw, "media_urls.json", urls); err != nil {
+		return err
+	}
+	counts["media_urls.json"] = len(urls)
+
+	manifest := map[string]interface{}{
+		"user_id":      user.ID,
+		"generated_at": time.Now(),
+		"files":        counts,
+	}
+	if err := writeJSON(zw, "manifest.json", manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// loadRows 以 map 形式读取所有列（包括管理后台模型中未声明的列），并去除凭据类字段
+func loadRows(db *gorm.DB, model interface{}, where string, args ...interface{}) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if err := db.Unscoped().Model(model).Where(where, args...).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for _, col := range sensitiveColumns {
+			delete(row, col)
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				var decoded interface{}
+				if json.Unmarshal(b, &decoded) == nil {
+					row[k] = decoded
+				} else {
+					row[k] = string(b)
+				}
+			} else if s, ok := v.(string); ok && (strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")) {
+				var decoded interface{}
+				if json.Unmarshal([]byte(s), &decoded) == nil {
+					row[k] = decoded
+				}
+			}
+		}
+	}
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+	return rows, nil
+}
+
+func firstOrNil(rows []map[string]interface{}) interface{} {
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// mediaURLs 用户上传的音视频地址（训练记录中的 video_url、帖子中的 image）
+func mediaURLs(db *gorm.DB, userID uuid.UUID) ([]string, error) {
+	var urls []string
+	var trainingURLs []string
+	if err := db.Model(&models.TrainingRecord{}).
+		Where("user_id = ? AND data->>'video_url' IS NOT NULL AND data->>'video_url' != ''", userID).
+		Pluck("data->>'video_url'", &trainingURLs).Error; err != nil {
+		return nil, err
+	}
+	urls = append(urls, trainingURLs...)
+
+	var postURLs []string
+	if err := db.Unscoped().Model(&models.Post{}).
+		Where("user_id = ? AND image IS NOT NULL AND image != ''", userID).
+		Pluck("image", &postURLs).Error; err != nil {
+		return nil, err
+	}
+	urls = append(urls, postURLs...)
+	if urls == nil {
+		urls = []string{}
+	}
+	return urls, nil
+}
+
+// ErasureRequest 擦除请求
+type ErasureRequest struct {
+	UserID          uuid.UUID
+	RequestedBy     uuid.UUID
+	RequestedByName string
+	Reason          string
+}
+
+// Erase 在一个事务中删除或匿名化用户的全部数据，并写入 DataErasureRecord。
+//
+// 社交内容、AI 对话、反馈、设置、登录凭据、附加角色、内容的敏感词命中记录等始终删除；用户在 UserSettings 中同意数据收集
+// （DataCollectionConsent，未设置时视为不同意）时，训练记录、冥想进度、成就和匹配记录
+// 去除个人内容后保留用于统计，否则一并删除。用户行本身匿名化为墓碑记录，保证保留数据不再关联到个人。
+func Erase(db *gorm.DB, req ErasureRequest) (*models.DataErasureRecord, error) {
+	user, err := loadUser(db, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings models.UserSettings
+	consent := false
+	if err := db.Where("user_id = ?", user.ID).First(&settings).Error; err == nil {
+		consent = settings.DataCollectionConsent
+	}
+
+	urls, err := mediaURLs(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	mediaList := make([]interface{}, len(urls))
+	for i, u := range urls {
+		mediaList[i] = u
+	}
+
+	record := &models.DataErasureRecord{
+		UserID:          user.ID,
+		UsernameHash:    HashUsername(user.Username),
+		RequestedBy:     req.RequestedBy,
+		RequestedByName: req.RequestedByName,
+		Reason:          req.Reason,
+		ConsentRetained: consent,
+		Removed:         models.JSONB{},
+		Anonymised:      models.JSONB{},
+		MediaURLs:       models.JSONB{"urls": mediaList},
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		e := &eraser{tx: tx.Unscoped(), record: record}
+
+		// 子查询每次使用时重新构建
+		postIDs := func() *gorm.DB {
+			return tx.Unscoped().Model(&models.Post{}).Select("id").Where("user_id = ?", user.ID)
+		}
+		commentIDs := func() *gorm.DB {
+			return tx.Unscoped().Model(&models.Comment{}).Select("id").Where("user_id = ? OR post_id IN (?)", user.ID, postIDs())
+		}
+		roomIDs := func() *gorm.DB {
+			return tx.Unscoped().Model(&models.PracticeRoom{}).Select("id").Where("user_id = ?", user.ID)
+		}
+		feedbackIDs := func() *gorm.DB {
+			return tx.Unscoped().Model(&models.Feedback{}).Select("id").Where("user_id = ?", user.ID)
+		}
+
+		// 以下两项依赖内容的ID，需在删除内容之前执行。
+		// 敏感词命中记录保存了命中的原文片段，随内容一起删除
+		e.remove("sensitive_hits", &models.SensitiveHit{},
+			"(target_type = 'posts' AND target_id IN (?)) OR (target_type = 'comments' AND target_id IN (?)) OR "+
+				"(target_type = 'rooms' AND target_id IN (?)) OR (target_type = 'feedback' AND target_id IN (?))",
+			postIDs(), commentIDs(), roomIDs(), feedbackIDs())
+		// 其他用户针对该用户及其内容的举报保留处理结果，清除可能包含个人信息的举报说明和处理备注
+		e.anonymise("reports", &models.Report{},
+			"(target_type = ? AND target_id = ?) OR (target_type = ? AND target_id IN (?)) OR "+
+				"(target_type = ? AND target_id IN (?)) OR (target_type = ? AND target_id IN (?))",
+			[]interface{}{
+				models.ReportTargetUser, user.ID,
+				models.ReportTargetPost, postIDs(),
+				models.ReportTargetComment, commentIDs(),
+				models.ReportTargetRoom, roomIDs(),
+			},
+			map[string]interface{}{"evidence": "", "resolution_note": ""})
+
+		// 用户帖子下的全部评论及其点赞、帖子的点赞和收藏
+		e.remove("comment_likes", &models.CommentLike{}, "user_id = ? OR comment_id IN (?)", user.ID, commentIDs())
+		e.remove("comments", &models.Comment{}, "user_id = ? OR post_id IN (?)", user.ID, postIDs())
+		e.remove("post_likes", &models.PostLike{}, "user_id = ? OR post_id IN (?)", user.ID, postIDs())
+		e.remove("post_collections", &models.PostCollection{}, "user_id = ? OR post_id IN (?)", user.ID, postIDs())
+		e.remove("posts", &models.Post{}, "user_id = ?", user.ID)
+
+		e.remove("practice_room_members", &models.PracticeRoomMember{}, "user_id = ? OR room_id IN (?)", user.ID, roomIDs())
+		e.remove("practice_rooms", &models.PracticeRoom{}, "user_id = ?", user.ID)
+
+		e.remove("follows", &models.Follow{}, "follower_id = ? OR followee_id = ?", user.ID, user.ID)
+		e.remove("ai_conversations", &models.AIConversation{}, "user_id = ?", user.ID)
+		e.remove("feedbacks", &models.Feedback{}, "user_id = ?", user.ID)
+		e.remove("reports", &models.Report{}, "reporter_id = ?", user.ID)
+		e.remove("user_sanctions", &models.UserSanction{}, "user_id = ?", user.ID)
+		e.remove("user_settings", &models.UserSettings{}, "user_id = ?", user.ID)
+		e.remove("auth_sessions", &models.AuthSession{}, "user_id = ?", user.ID)
+		e.remove("recovery_codes", &models.RecoveryCode{}, "user_id = ?", user.ID)
+		e.removeRows("user_roles", "user_id = ?", user.ID)
+		e.remove("verification_codes", &models.VerificationCode{}, "identifier IN ?", identifiers(user))
+
+		// 其他用户的匹配记录中去掉与该用户的关联
+		e.anonymise("random_match_records.matched_user_id", &models.RandomMatchRecord{}, "matched_user_id = ?",
+			[]interface{}{user.ID}, map[string]interface{}{"matched_user_id": nil})
+
+		if consent {
+			// 训练数据中可能包含录音、视频地址和转写文本，只保留类型、时长和时间
+			e.anonymise("training_records", &models.TrainingRecord{}, "user_id = ?",
+				[]interface{}{user.ID}, map[string]interface{}{"data": nil})
+			e.keep("meditation_progresses", &models.MeditationProgress{}, user.ID)
+			e.keep("achievements", &models.Achievement{}, user.ID)
+			e.keep("random_match_records", &models.RandomMatchRecord{}, user.ID)
+		} else {
+			e.remove("training_records", &models.TrainingRecord{}, "user_id = ?", user.ID)
+			e.remove("meditation_progresses", &models.MeditationProgress{}, "user_id = ?", user.ID)
+			e.remove("achievements", &models.Achievement{}, "user_id = ?", user.ID)
+			e.remove("random_match_records", &models.RandomMatchRecord{}, "user_id = ?", user.ID)
+		}
+
+		// 审计日志中的实体快照可能包含该用户的个人信息，清除快照但保留操作记录本身；
+		// 日志的操作人（包括以该用户名尝试登录的记录）和描述中的用户名替换为墓碑用户名
+		tombstone := "erased_" + strings.ReplaceAll(user.ID.String(), "-", "")[:12]
+		e.anonymise("operation_logs", &models.OperationLog{},
+			"resource_id = ? OR before->>'user_id' = ? OR after->>'user_id' = ?",
+			[]interface{}{user.ID.String(), user.ID.String(), user.ID.String()},
+			map[string]interface{}{"before": nil, "after": nil, "diff": nil})
+		e.anonymise("operation_logs.username", &models.OperationLog{}, "user_id = ? OR username = ?",
+			[]interface{}{user.ID, user.Username}, map[string]interface{}{"username": tombstone})
+		e.anonymise("operation_logs.details", &models.OperationLog{}, "STRPOS(details, ?) > 0",
+			[]interface{}{user.Username}, map[string]interface{}{"details": gorm.Expr("REPLACE(details, ?, ?)", user.Username, tombstone)})
+
+		// 用户行匿名化为墓碑：清除所有可识别信息，禁用并标记为已删除
+		e.anonymise("users", &models.User{}, "id = ?", []interface{}{user.ID}, map[string]interface{}{
+			"username":             tombstone,
+			"email":                nil,
+			"phone":                nil,
+			"avatar_url":           nil,
+			"gender":               nil,
+			"password_hash":        "!",
+			"status":               0,
+			"must_change_password": false,
+			"totp_secret":          "",
+			"totp_enabled":         false,
+			"deleted_at":           time.Now(),
+		})
+
+		if e.err != nil {
+			return e.err
+		}
+		// 用户的全部数据都晚于注册时间，重新计算注册以来的每日统计
+		if err := analytics.RollupSince(tx, user.CreatedAt); err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// eraser 按顺序执行删除/匿名化，记录每张表受影响的行数，遇到第一个错误后停止
+type eraser struct {
+	tx     *gorm.DB
+	record *models.DataErasureRecord
+	err    error
+}
+
+func (e *eraser) remove(table string, model interface{}, where string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	res := e.tx.Where(where, args...).Delete(model)
+	if res.Error != nil {
+		e.err = fmt.Errorf("erase %s: %w", table, res.Error)
+		return
+	}
+	e.record.Removed[table] = res.RowsAffected
+}
+
+// removeRows 删除没有模型的关联表中的行
+func (e *eraser) removeRows(table string, where string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	res := e.tx.Exec("DELETE FROM "+table+" WHERE "+where, args...)
+	if res.Error != nil {
+		e.err = fmt.Errorf("erase %s: %w", table, res.Error)
+		return
+	}
+	e.record.Removed[table] = res.RowsAffected
+}
+
+func (e *eraser) anonymise(table string, model interface{}, where string, args []interface{}, values map[string]interface{}) {
+	if e.err != nil {
+		return
+	}
+	res := e.tx.Model(model).Where(where, args...).Updates(values)
+	if res.Error != nil {
+		e.err = fmt.Errorf("anonymise %s: %w", table, res.Error)
+		return
+	}
+	e.record.Anonymised[table] = res.RowsAffected
+}
+
+// keep 记录按用户同意保留（仅关联到匿名化后的用户）的行数
+func (e *eraser) keep(table string, model interface{}, userID uuid.UUID) {
+	if e.err != nil {
+		return
+	}
+	var n int64
+	if err := e.tx.Model(model).Where("user_id = ?", userID).Count(&n).Error; err != nil {
+		e.err = fmt.Errorf("count %s: %w", table, err)
+		return
+	}
+	e.record.Anonymised[table] = n
+}
+
+// HashUsername 用户名的 HMAC-SHA256，与擦除记录中的 username_hash 比对可核对申请人
+func HashUsername(username string) string {
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(username))
+	return hex.EncodeToString(mac.Sum(nil))
+}
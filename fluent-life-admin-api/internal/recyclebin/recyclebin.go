@@ -0,0 +1,371 @@
+// Package recyclebin 实现软删除资源的级联删除、恢复和彻底清除。
+//
+// 级联删除的子记录与父记录写入完全相同的 deleted_at，恢复父记录时据此只恢复
+// 随父记录一起删除的子记录，而不会恢复此前被单独删除的子记录。
+package recyclebin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fluent-life-admin-api/internal/analytics"
+	"fluent-life-admin-api/internal/models"
+)
+
+// Resource 回收站中的一类资源
+type Resource struct {
+	// Name 路由中的资源名，例如 posts
+	Name string
+	// Label 中文名称，用于提示信息
+	Label string
+	// Permission 权限模块，列表需要 <Permission>:read，恢复和清除需要 <Permission>:write
+	Permission string
+	// Model 软删除模型
+	Model interface{}
+	// Preloads 列表中需要预加载的关联
+	Preloads []string
+	// stats 资源计入每日统计，恢复和清除后需要重新计算
+	stats bool
+	// restore 恢复指定记录及其级联子记录，返回恢复的父记录数
+	restore func(tx *gorm.DB, ids []string) (int64, error)
+	// purge 彻底删除指定的已软删除记录及其子记录，返回删除的父记录数
+	purge func(tx *gorm.DB, ids []string) (int64, error)
+}
+
+// Resources 支持回收站的资源
+var Resources = []Resource{
+	{Name: "users", Label: "用户", Permission: "user", Model: &models.User{}, restore: restoreUsers, purge: purgeUsers, stats: true},
+	{Name: "posts", Label: "帖子", Permission: "post", Model: &models.Post{}, Preloads: []string{"User"}, restore: restorePosts, purge: purgePosts, stats: true},
+	{Name: "comments", Label: "评论", Permission: "comment", Model: &models.Comment{}, Preloads: []string{"User"}, restore: restoreComments, purge: purgeComments, stats: true},
+	{Name: "rooms", Label: "房间", Permission: "room", Model: &models.PracticeRoom{}, Preloads: []string{"User"}, restore: restoreSimple(&models.PracticeRoom{}), purge: purgeRooms, stats: true},
+	{Name: "tongue-twisters", Label: "绕口令", Permission: "content", Model: &models.TongueTwister{}, restore: restoreSimple(&models.TongueTwister{}), purge: purgeSimple(&models.TongueTwister{})},
+	{Name: "daily-expressions", Label: "每日朗诵文案", Permission: "content", Model: &models.DailyExpression{}, restore: restoreSimple(&models.DailyExpression{}), purge: purgeSimple(&models.DailyExpression{})},
+	{Name: "speech-techniques", Label: "语音技巧", Permission: "content", Model: &models.SpeechTechnique{}, restore: restoreSimple(&models.SpeechTechnique{}), purge: purgeSimple(&models.SpeechTechnique{})},
+}
+
+// Find 按名称查找资源
+func Find(name string) (Resource, bool) {
+	for _, r := range Resources {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Resource{}, false
+}
+
+// Restore 在事务中恢复记录
+func (r Resource) Restore(db *gorm.DB, ids []string) (int64, error) {
+	var restored int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return r.withStats(tx, ids, func() error {
+			var err error
+			restored, err = r.restore(tx, ids)
+			return err
+		})
+	})
+	return restored, err
+}
+
+// Purge 在事务中彻底删除已软删除的记录
+func (r Resource) Purge(db *gorm.DB, ids []string) (int64, error) {
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return r.withStats(tx, ids, func() error {
+			var err error
+			purged, err = r.purge(tx, ids)
+			return err
+		})
+	})
+	return purged, err
+}
+
+// withStats 执行 change，资源计入每日统计时随后重新计算受影响日期的数据
+func (r Resource) withStats(tx *gorm.DB, ids []string, change func() error) error {
+	if !r.stats {
+		return change()
+	}
+	return analytics.Reroll(tx, r.Model, ids, change)
+}
+
+// PurgeExpired 彻底删除删除时间早于 before 的记录，返回各资源删除的数量。
+// 某类资源清除失败时记录错误并继续清除其他资源，失败的资源不出现在结果中，返回的错误汇总所有失败
+func PurgeExpired(db *gorm.DB, before time.Time, dryRun bool) (map[string]int64, error) {
+	result := make(map[string]int64, len(Resources))
+	var errs []error
+	for _, r := range Resources {
+		var ids []string
+		if err := db.Unscoped().Model(r.Model).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Pluck("id", &ids).Error; err != nil {
+			errs = append(errs, fmt.Errorf("查询过期%s失败: %w", r.Label, err))
+			continue
+		}
+		if len(ids) == 0 || dryRun {
+			result[r.Name] = int64(len(ids))
+			continue
+		}
+		purged, err := r.Purge(db, ids)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("清除%s失败: %w", r.Label, err))
+			continue
+		}
+		result[r.Name] = purged
+	}
+	return result, errors.Join(errs...)
+}
+
+// Now 返回截断到数据库精度（微秒）的当前时间，保证父子记录的 deleted_at 可精确比较
+func Now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// SoftDeleteUsers 软删除用户，并级联软删除其帖子和评论（以及这些帖子下的评论）
+func SoftDeleteUsers(tx *gorm.DB, ids []string) (int64, error) {
+	now := Now()
+	var postIDs []string
+	if err := tx.Model(&models.Post{}).Where("user_id IN ?", ids).Pluck("id", &postIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(postIDs) > 0 {
+		if err := stampDeleted(tx, &models.Comment{}, "post_id IN ?", postIDs, now); err != nil {
+			return 0, err
+		}
+		if err := stampDeleted(tx, &models.Post{}, "id IN ?", postIDs, now); err != nil {
+			return 0, err
+		}
+	}
+	if err := stampDeleted(tx, &models.Comment{}, "user_id IN ?", ids, now); err != nil {
+		return 0, err
+	}
+	res := tx.Model(&models.User{}).Where("id IN ?", ids).Update("deleted_at", now)
+	return res.RowsAffected, res.Error
+}
+
+// SoftDeletePosts 软删除帖子，并级联软删除帖子下的评论
+func SoftDeletePosts(tx *gorm.DB, ids []string) (int64, error) {
+	now := Now()
+	if err := stampDeleted(tx, &models.Comment{}, "post_id IN ?", ids, now); err != nil {
+		return 0, err
+	}
+	res := tx.Model(&models.Post{}).Where("id IN ?", ids).Update("deleted_at", now)
+	return res.RowsAffected, res.Error
+}
+
+// stampDeleted 为仍未删除的记录写入指定的 deleted_at（模型的软删除作用域会自动排除已删除的记录）
+func stampDeleted(tx *gorm.DB, model interface{}, query string, arg interface{}, now time.Time) error {
+	return tx.Model(model).Where(query, arg).Update("deleted_at", now).Error
+}
+
+func restoreSimple(model interface{}) func(tx *gorm.DB, ids []string) (int64, error) {
+	return func(tx *gorm.DB, ids []string) (int64, error) {
+		res := tx.Unscoped().Model(model).
+			Where("id IN ? AND deleted_at IS NOT NULL", ids).
+			Update("deleted_at", nil)
+		return res.RowsAffected, res.Error
+	}
+}
+
+func purgeSimple(model interface{}) func(tx *gorm.DB, ids []string) (int64, error) {
+	return func(tx *gorm.DB, ids []string) (int64, error) {
+		res := tx.Unscoped().Where("id IN ? AND deleted_at IS NOT NULL", ids).Delete(model)
+		return res.RowsAffected, res.Error
+	}
+}
+
+// restoreUsers 恢复用户，以及与用户同一时刻被删除的帖子和评论
+func restoreUsers(tx *gorm.DB, ids []string) (int64, error) {
+	if err := tx.Exec(`
+		UPDATE comments c SET deleted_at = NULL
+		FROM users u
+		WHERE u.id IN ? AND u.deleted_at IS NOT NULL AND c.deleted_at = u.deleted_at
+		  AND (c.user_id = u.id OR c.post_id IN (SELECT p.id FROM posts p WHERE p.user_id = u.id AND p.deleted_at = u.deleted_at))
+	`, ids).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Exec(`
+		UPDATE posts p SET deleted_at = NULL
+		FROM users u
+		WHERE u.id IN ? AND u.deleted_at IS NOT NULL AND p.user_id = u.id AND p.deleted_at = u.deleted_at
+	`, ids).Error; err != nil {
+		return 0, err
+	}
+	return restoreSimple(&models.User{})(tx, ids)
+}
+
+// restorePosts 恢复帖子及与其同一时刻被删除的评论；作者已被删除的帖子需先恢复作者
+func restorePosts(tx *gorm.DB, ids []string) (int64, error) {
+	var orphaned int64
+	if err := tx.Unscoped().Model(&models.Post{}).
+		Where("id IN ? AND user_id IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)", ids).
+		Count(&orphaned).Error; err != nil {
+		return 0, err
+	}
+	if orphaned > 0 {
+		return 0, fmt.Errorf("有 %d 条帖子的作者已被删除，请先恢复作者", orphaned)
+	}
+
+	if err := tx.Exec(`
+		UPDATE comments c SET deleted_at = NULL
+		FROM posts p
+		WHERE p.id IN ? AND p.deleted_at IS NOT NULL AND c.post_id = p.id AND c.deleted_at = p.deleted_at
+	`, ids).Error; err != nil {
+		return 0, err
+	}
+	return restoreSimple(&models.Post{})(tx, ids)
+}
+
+// restoreComments 恢复评论；所属帖子或作者已被删除的评论需先恢复帖子或作者
+func restoreComments(tx *gorm.DB, ids []string) (int64, error) {
+	var orphaned int64
+	if err := tx.Unscoped().Model(&models.Comment{}).
+		Where("id IN ?", ids).
+		Where("(post_id IN (SELECT id FROM posts WHERE deleted_at IS NOT NULL) OR user_id IN (SELECT id FROM users WHERE deleted_at IS NOT NULL))").
+		Count(&orphaned).Error; err != nil {
+		return 0, err
+	}
+	if orphaned > 0 {
+		return 0, fmt.Errorf("有 %d 条评论所属的帖子或作者已被删除，请先恢复帖子或作者", orphaned)
+	}
+	return restoreSimple(&models.Comment{})(tx, ids)
+}
+
+// 用户内容的子查询，@ids 为要彻底删除的用户
+const (
+	userPostIDs     = "SELECT id FROM posts WHERE user_id IN @ids"
+	userCommentIDs  = "SELECT id FROM comments WHERE user_id IN @ids OR post_id IN (" + userPostIDs + ")"
+	userRoomIDs     = "SELECT id FROM practice_rooms WHERE user_id IN @ids"
+	userFeedbackIDs = "SELECT id FROM feedbacks WHERE user_id IN @ids"
+)
+
+// userDependents 彻底删除用户前需要删除的关联数据，范围与 privacy.Erase 删除的数据一致：
+// 用户本人的全部记录，以及其帖子、评论、房间下其他用户的评论、点赞、收藏和成员记录。
+// 按外键依赖排列，引用帖子、评论和房间的记录先于帖子、评论和房间删除
+var userDependents = []struct {
+	table string
+	where string
+}{
+	{"sensitive_hits", "(target_type = 'posts' AND target_id IN (" + userPostIDs + ")) OR " +
+		"(target_type = 'comments' AND target_id IN (" + userCommentIDs + ")) OR " +
+		"(target_type = 'rooms' AND target_id IN (" + userRoomIDs + ")) OR " +
+		"(target_type = 'feedback' AND target_id IN (" + userFeedbackIDs + "))"},
+	{"comment_likes", "user_id IN @ids OR comment_id IN (" + userCommentIDs + ")"},
+	{"comments", "user_id IN @ids OR post_id IN (" + userPostIDs + ")"},
+	{"post_likes", "user_id IN @ids OR post_id IN (" + userPostIDs + ")"},
+	{"post_collections", "user_id IN @ids OR post_id IN (" + userPostIDs + ")"},
+	{"posts", "user_id IN @ids"},
+	{"practice_room_members", "user_id IN @ids OR room_id IN (" + userRoomIDs + ")"},
+	{"practice_rooms", "user_id IN @ids"},
+	{"follows", "follower_id IN @ids OR followee_id IN @ids"},
+	{"ai_conversations", "user_id IN @ids"},
+	{"feedbacks", "user_id IN @ids"},
+	{"reports", "reporter_id IN @ids"},
+	{"user_sanctions", "user_id IN @ids"},
+	{"user_settings", "user_id IN @ids"},
+	{"auth_sessions", "user_id IN @ids"},
+	{"recovery_codes", "user_id IN @ids"},
+	{"user_roles", "user_id IN @ids"},
+	{"verification_codes", "identifier IN (SELECT email FROM users WHERE id IN @ids AND email IS NOT NULL " +
+		"UNION SELECT phone FROM users WHERE id IN @ids AND phone IS NOT NULL)"},
+	{"training_records", "user_id IN @ids"},
+	{"meditation_progresses", "user_id IN @ids"},
+	{"achievements", "user_id IN @ids"},
+	{"random_match_records", "user_id IN @ids"},
+}
+
+// purgeUsers 彻底删除用户及其全部关联数据；其他用户的匹配记录保留，只去掉与该用户的关联
+func purgeUsers(tx *gorm.DB, ids []string) (int64, error) {
+	var deletedIDs []string
+	if err := tx.Unscoped().Model(&models.User{}).
+		Where("id IN ? AND deleted_at IS NOT NULL", ids).
+		Pluck("id", &deletedIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(deletedIDs) == 0 {
+		return 0, nil
+	}
+
+	args := map[string]interface{}{"ids": deletedIDs}
+	for _, d := range userDependents {
+		if err := tx.Exec("DELETE FROM "+d.table+" WHERE "+d.where, args).Error; err != nil {
+			return 0, fmt.Errorf("删除 %s 失败: %w", d.table, err)
+		}
+	}
+	if err := tx.Exec("UPDATE random_match_records SET matched_user_id = NULL WHERE matched_user_id IN @ids", args).Error; err != nil {
+		return 0, fmt.Errorf("更新 random_match_records 失败: %w", err)
+	}
+	res := tx.Unscoped().Where("id IN ?", deletedIDs).Delete(&models.User{})
+	return res.RowsAffected, res.Error
+}
+
+// purgePosts 彻底删除帖子及其点赞、收藏和全部评论
+func purgePosts(tx *gorm.DB, ids []string) (int64, error) {
+	var deletedIDs []string
+	if err := tx.Unscoped().Model(&models.Post{}).
+		Where("id IN ? AND deleted_at IS NOT NULL", ids).
+		Pluck("id", &deletedIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(deletedIDs) == 0 {
+		return 0, nil
+	}
+
+	var commentIDs []string
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("post_id IN ?", deletedIDs).Pluck("id", &commentIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(commentIDs) > 0 {
+		if err := tx.Where("comment_id IN ?", commentIDs).Delete(&models.CommentLike{}).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.Unscoped().Where("id IN ?", commentIDs).Delete(&models.Comment{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Where("post_id IN ?", deletedIDs).Delete(&models.PostLike{}).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("post_id IN ?", deletedIDs).Delete(&models.PostCollection{}).Error; err != nil {
+		return 0, err
+	}
+	res := tx.Unscoped().Where("id IN ?", deletedIDs).Delete(&models.Post{})
+	return res.RowsAffected, res.Error
+}
+
+// purgeComments 彻底删除评论及其点赞
+func purgeComments(tx *gorm.DB, ids []string) (int64, error) {
+	var deletedIDs []string
+	if err := tx.Unscoped().Model(&models.Comment{}).
+		Where("id IN ? AND deleted_at IS NOT NULL", ids).
+		Pluck("id", &deletedIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(deletedIDs) == 0 {
+		return 0, nil
+	}
+	if err := tx.Where("comment_id IN ?", deletedIDs).Delete(&models.CommentLike{}).Error; err != nil {
+		return 0, err
+	}
+	res := tx.Unscoped().Where("id IN ?", deletedIDs).Delete(&models.Comment{})
+	return res.RowsAffected, res.Error
+}
+
+// purgeRooms 彻底删除房间及其成员记录
+func purgeRooms(tx *gorm.DB, ids []string) (int64, error) {
+	var deletedIDs []string
+	if err := tx.Unscoped().Model(&models.PracticeRoom{}).
+		Where("id IN ? AND deleted_at IS NOT NULL", ids).
+		Pluck("id", &deletedIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(deletedIDs) == 0 {
+		return 0, nil
+	}
+	if err := tx.Where("room_id IN ?", deletedIDs).Delete(&models.PracticeRoomMember{}).Error; err != nil {
+		return 0, err
+	}
+	res := tx.Unscoped().Where("id IN ?", deletedIDs).Delete(&models.PracticeRoom{})
+	return res.RowsAffected, res.Error
+}
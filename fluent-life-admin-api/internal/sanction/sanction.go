@@ -0,0 +1,175 @@
+// Package sanction 管理用户处罚的生效、撤销和到期。
+//
+// 禁言、禁止创建房间、禁止随机匹配由客户端在对应操作前查询生效中的处罚；
+// 封禁同时把 users.status 置为 0，处罚结束且没有其他生效中的封禁时恢复为 1。
+// 只有禁用状态由封禁造成时才恢复（见 models.UserSanction.DisabledAccount），
+// 封禁前或封禁期间被管理员另行禁用的账号保持禁用。
+package sanction
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrNotActive 处罚已撤销或已到期
+var ErrNotActive = errors.New("sanction is no longer in effect")
+
+// Active 限定为在 now 时刻生效中的处罚
+func Active(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("revoked_at IS NULL AND expired_at IS NULL AND starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", now, now)
+}
+
+// ActiveUserIDs 生效中处罚的用户ID子查询，可选按类型筛选
+func ActiveUserIDs(db *gorm.DB, now time.Time, sanctionType string) *gorm.DB {
+	query := Active(db.Model(&models.UserSanction{}), now).Select("user_id")
+	if sanctionType != "" {
+		query = query.Where("type = ?", sanctionType)
+	}
+	return query
+}
+
+// Issue 创建处罚；已开始的封禁立即禁用账号
+func Issue(tx *gorm.DB, s *models.UserSanction) error {
+	now := time.Now()
+	if s.Type == models.SanctionBan && s.StateAt(now) == models.SanctionStateActive {
+		result := tx.Model(&models.User{}).Where("id = ? AND status <> 0", s.UserID).Update("status", 0)
+		if result.Error != nil {
+			return result.Error
+		}
+		s.DisabledAccount = result.RowsAffected > 0
+	}
+	return tx.Create(s).Error
+}
+
+// KeepDisabled 管理员手动禁用账号时调用：用户生效中的封禁结束后不再恢复账号
+func KeepDisabled(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&models.UserSanction{}).
+		Where("user_id = ? AND type = ? AND disabled_account = ?", userID, models.SanctionBan, true).
+		Update("disabled_account", false).Error
+}
+
+// Revoke 撤销处罚；撤销封禁后若没有其他生效中的封禁则恢复账号
+func Revoke(tx *gorm.DB, id uuid.UUID, revokedBy uuid.UUID, reason string) (*models.UserSanction, error) {
+	var s models.UserSanction
+	if err := tx.Where("id = ?", id).First(&s).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if state := s.StateAt(now); state == models.SanctionStateRevoked || state == models.SanctionStateExpired {
+		return nil, ErrNotActive
+	}
+	if err := tx.Model(&s).Updates(map[string]interface{}{
+		"revoked_at":    now,
+		"revoked_by":    revokedBy,
+		"revoke_reason": reason,
+	}).Error; err != nil {
+		return nil, err
+	}
+	if s.Type == models.SanctionBan {
+		if err := liftBanIfClear(tx, &s, now); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+// Sweep 处理到期和到点开始的处罚，返回本次到期的处罚数和新禁用的账号数
+func Sweep(db *gorm.DB, now time.Time) (expired, banned int64, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var due []models.UserSanction
+		if err := tx.Where("revoked_at IS NULL AND expired_at IS NULL AND ends_at IS NOT NULL AND ends_at <= ?", now).
+			Find(&due).Error; err != nil {
+			return err
+		}
+		for i, s := range due {
+			if err := tx.Model(&models.UserSanction{}).Where("id = ?", s.ID).Update("expired_at", now).Error; err != nil {
+				return err
+			}
+			if s.Type == models.SanctionBan {
+				if err := liftBanIfClear(tx, &due[i], now); err != nil {
+					return err
+				}
+			}
+		}
+		expired = int64(len(due))
+
+		// 到点开始的封禁，以及生效期间被手动启用的账号，统一置为禁用，禁用状态此后由封禁负责恢复
+		var userIDs []uuid.UUID
+		if err := tx.Model(&models.User{}).Where("status <> 0 AND id IN (?)", ActiveUserIDs(tx, now, models.SanctionBan)).
+			Pluck("id", &userIDs).Error; err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		result := tx.Model(&models.User{}).Where("id IN ?", userIDs).Update("status", 0)
+		if result.Error != nil {
+			return result.Error
+		}
+		banned = result.RowsAffected
+		return Active(tx.Model(&models.UserSanction{}), now).
+			Where("user_id IN ? AND type = ?", userIDs, models.SanctionBan).
+			Update("disabled_account", true).Error
+	})
+	return expired, banned, err
+}
+
+// StartSweeper 在后台定期执行 Sweep
+func StartSweeper(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			expired, banned, err := Sweep(db, time.Now())
+			if err != nil {
+				log.Printf("处罚到期处理失败: %v", err)
+				continue
+			}
+			if expired > 0 || banned > 0 {
+				log.Printf("处罚到期处理: 到期 %d 条，禁用账号 %d 个", expired, banned)
+			}
+		}
+	}()
+}
+
+// liftBanIfClear 封禁 s 结束后处理账号状态：禁用状态不是由封禁造成的不做处理；
+// 用户还有其他生效中的封禁时，交由其中一个在结束时恢复；否则恢复账号并写入操作日志
+func liftBanIfClear(tx *gorm.DB, s *models.UserSanction, now time.Time) error {
+	if !s.DisabledAccount {
+		return nil
+	}
+	var next models.UserSanction
+	found := Active(tx.Model(&models.UserSanction{}), now).
+		Where("user_id = ? AND type = ? AND id <> ?", s.UserID, models.SanctionBan, s.ID).
+		Order("ends_at DESC NULLS FIRST").Limit(1).Find(&next)
+	if found.Error != nil {
+		return found.Error
+	}
+	if found.RowsAffected > 0 {
+		return tx.Model(&next).Update("disabled_account", true).Error
+	}
+
+	result := tx.Model(&models.User{}).Where("id = ? AND status = 0", s.UserID).Update("status", 1)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Create(&models.OperationLog{
+		Username:   "system",
+		UserRole:   "system",
+		Action:     "LiftBan",
+		Resource:   "User",
+		ResourceID: s.UserID.String(),
+		Details:    fmt.Sprintf("封禁 %s 结束，自动恢复账号", s.ID),
+		Status:     "Success",
+		Before:     models.JSONB{"status": 0},
+		After:      models.JSONB{"status": 1},
+		Diff:       models.JSONB{"status": map[string]interface{}{"before": 0, "after": 1}},
+	}).Error
+}
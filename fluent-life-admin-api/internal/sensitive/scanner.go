@@ -0,0 +1,191 @@
+package sensitive
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// scanBatchSize 每类内容每轮扫描的最大条数
+const scanBatchSize = 200
+
+// Target 需要扫描的一类内容
+type Target struct {
+	// Name 目标名称，同时作为命中记录的 target_type
+	Name string
+	// Model 内容模型，需有 scanned_at 列
+	Model interface{}
+	// Fields 参与扫描的文本列
+	Fields []string
+	// apply 按命中结果处置内容，未命中时不调用
+	apply func(tx *gorm.DB, id uuid.UUID, result Result, now time.Time) error
+}
+
+// Targets 扫描的内容
+var Targets = []Target{
+	{Name: "posts", Model: &models.Post{}, Fields: []string{"content"}, apply: applyModeration(&models.Post{})},
+	{Name: "comments", Model: &models.Comment{}, Fields: []string{"content"}, apply: applyModeration(&models.Comment{})},
+	{Name: "rooms", Model: &models.PracticeRoom{}, Fields: []string{"title", "description"}, apply: applyRoom},
+	// 反馈只记录命中，由管理员在反馈列表中处理
+	{Name: "feedback", Model: &models.Feedback{}, Fields: []string{"content"}},
+}
+
+// FindTarget 按名称查找扫描目标
+func FindTarget(name string) (Target, bool) {
+	for _, t := range Targets {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+// applyModeration 帖子和评论：写入风险分；hide 策略直接隐藏，review 策略把已通过的内容退回待审核
+func applyModeration(model interface{}) func(tx *gorm.DB, id uuid.UUID, result Result, now time.Time) error {
+	return func(tx *gorm.DB, id uuid.UUID, result Result, now time.Time) error {
+		if err := tx.Model(model).Where("id = ?", id).
+			UpdateColumn("risk_score", gorm.Expr("GREATEST(risk_score, ?)", result.RiskScore)).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"moderation_reason": "sensitive_word",
+			"moderation_note":   "命中敏感词: " + strings.Join(result.Words, ", "),
+			"moderated_by":      nil,
+			"moderated_at":      now,
+		}
+		query := tx.Model(model).Where("id = ?", id)
+		if result.Action == models.SensitiveActionHide {
+			updates["moderation_status"] = models.ModerationHidden
+			query = query.Where("moderation_status IN ?", []string{models.ModerationPending, models.ModerationApproved})
+		} else {
+			updates["moderation_status"] = models.ModerationPending
+			query = query.Where("moderation_status = ?", models.ModerationApproved)
+		}
+		return query.UpdateColumns(updates).Error
+	}
+}
+
+// applyRoom 房间没有审核状态，hide 策略直接关闭房间
+func applyRoom(tx *gorm.DB, id uuid.UUID, result Result, now time.Time) error {
+	if result.Action != models.SensitiveActionHide {
+		return nil
+	}
+	return tx.Model(&models.PracticeRoom{}).Where("id = ?", id).UpdateColumn("is_active", false).Error
+}
+
+// ScanText 拼接各列文本后扫描
+func ScanText(dict *Dictionary, row map[string]interface{}, fields []string) Result {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if v, ok := row[field].(string); ok && v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return dict.Scan(strings.Join(parts, "\n"))
+}
+
+// ScanPending 扫描尚未扫描的内容，返回本轮扫描的条数和命中的条数
+func ScanPending(db *gorm.DB, filter *Filter, target Target, limit int) (scanned, hits int, err error) {
+	dict, err := filter.Dictionary()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var rows []map[string]interface{}
+	columns := append([]string{"id"}, target.Fields...)
+	if err := db.Model(target.Model).Select(columns).Where("scanned_at IS NULL").
+		Order("created_at ASC").Limit(limit).Find(&rows).Error; err != nil {
+		return 0, 0, err
+	}
+
+	for _, row := range rows {
+		id, err := rowID(row["id"])
+		if err != nil {
+			continue
+		}
+		result := ScanText(dict, row, target.Fields)
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if result.Hit() {
+				if err := Record(tx, target.Name, id, result); err != nil {
+					return err
+				}
+				if target.apply != nil {
+					if err := target.apply(tx, id, result, now); err != nil {
+						return err
+					}
+				}
+			}
+			return tx.Model(target.Model).Where("id = ?", id).UpdateColumn("scanned_at", now).Error
+		})
+		if err != nil {
+			return scanned, hits, err
+		}
+		scanned++
+		if result.Hit() {
+			hits++
+		}
+	}
+	return scanned, hits, nil
+}
+
+// Record 保存命中记录
+func Record(tx *gorm.DB, targetType string, targetID uuid.UUID, result Result) error {
+	return tx.Create(&models.SensitiveHit{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Words: models.JSONB{
+			"words":      result.Words,
+			"categories": result.Categories,
+			"matches":    result.Matches,
+		},
+		RiskScore: result.RiskScore,
+		Action:    result.Action,
+	}).Error
+}
+
+// StartScanner 在后台定期扫描新内容
+func StartScanner(db *gorm.DB, filter *Filter, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, target := range Targets {
+				// 每轮把积压的内容分批扫完，避免新内容长时间未被检查
+				for {
+					scanned, hits, err := ScanPending(db, filter, target, scanBatchSize)
+					if err != nil {
+						log.Printf("敏感词扫描失败 %s: %v", target.Name, err)
+						break
+					}
+					if hits > 0 {
+						log.Printf("敏感词扫描 %s: 扫描 %d 条，命中 %d 条", target.Name, scanned, hits)
+					}
+					if scanned < scanBatchSize {
+						break
+					}
+				}
+			}
+		}
+	}()
+}
+
+// rowID map 查询结果中的 uuid 列可能是字符串或字节
+func rowID(v interface{}) (uuid.UUID, error) {
+	switch id := v.(type) {
+	case string:
+		return uuid.Parse(id)
+	case []byte:
+		return uuid.ParseBytes(id)
+	case uuid.UUID:
+		return id, nil
+	}
+	return uuid.Nil, fmt.Errorf("unexpected id type %T", v)
+}
@@ -0,0 +1,251 @@
+// Package sensitive 实现基于管理员维护词库的敏感词过滤。
+//
+// 普通词使用 Aho–Corasick 多模式匹配，正则词逐条匹配。匹配前统一转小写并把全角
+// ASCII 字符转为半角，转换逐字符进行，命中位置与原文的字符位置一致。
+package sensitive
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"fluent-life-admin-api/internal/models"
+	"fluent-life-admin-api/pkg/ahocorasick"
+
+	"gorm.io/gorm"
+)
+
+// reloadInterval 词库缓存的最长有效期，多实例部署时其他实例的修改最迟在此时间后生效
+const reloadInterval = time.Minute
+
+// maxRiskScore 风险分上限
+const maxRiskScore = 100
+
+// Match 一次命中，Start/End 为字符（rune）下标，区间左闭右开
+type Match struct {
+	Word     string `json:"word"`
+	Category string `json:"category"`
+	IsRegex  bool   `json:"is_regex"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Text     string `json:"text"` // 原文中命中的片段
+
+	category *models.SensitiveWordCategory
+}
+
+// Result 文本的过滤结果
+type Result struct {
+	Matches    []Match  `json:"matches"`
+	Words      []string `json:"words"`      // 命中的词（去重）
+	Categories []string `json:"categories"` // 命中的分类代码（去重）
+	RiskScore  int      `json:"risk_score"` // 命中分类风险分之和，最高 100
+	Action     string   `json:"action"`     // 未命中为空；任一分类为 hide 时为 hide，否则为 review
+}
+
+// Hit 是否命中任何敏感词
+func (r Result) Hit() bool {
+	return len(r.Matches) > 0
+}
+
+type entry struct {
+	word     string
+	category *models.SensitiveWordCategory
+	re       *regexp.Regexp
+}
+
+// Dictionary 编译后的词库，只读
+type Dictionary struct {
+	keywords []entry
+	matcher  *ahocorasick.Matcher
+	regexes  []entry
+}
+
+// Load 从数据库读取启用的分类和词条并编译；无法编译的正则被跳过
+func Load(db *gorm.DB) (*Dictionary, error) {
+	var categories []models.SensitiveWordCategory
+	if err := db.Where("enabled = ?", true).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.SensitiveWordCategory, len(categories))
+	for i := range categories {
+		byID[categories[i].ID.String()] = &categories[i]
+	}
+
+	var words []models.SensitiveWord
+	if err := db.Where("enabled = ?", true).Find(&words).Error; err != nil {
+		return nil, err
+	}
+
+	d := &Dictionary{}
+	var patterns []string
+	for _, w := range words {
+		category, ok := byID[w.CategoryID.String()]
+		if !ok {
+			continue
+		}
+		if w.IsRegex {
+			re, err := CompileRegex(w.Word)
+			if err != nil {
+				continue
+			}
+			d.regexes = append(d.regexes, entry{word: w.Word, category: category, re: re})
+			continue
+		}
+		pattern := Normalize(strings.TrimSpace(w.Word))
+		if pattern == "" {
+			continue
+		}
+		d.keywords = append(d.keywords, entry{word: w.Word, category: category})
+		patterns = append(patterns, pattern)
+	}
+	d.matcher = ahocorasick.New(patterns)
+	return d, nil
+}
+
+// CompileRegex 编译正则词条，统一为不区分大小写
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// Normalize 转小写并把全角 ASCII 字符和全角空格转为半角，逐字符转换不改变字符数
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r == '　':
+			r = ' '
+		case r >= '！' && r <= '～':
+			r -= 0xFEE0
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Scan 检查文本
+func (d *Dictionary) Scan(text string) Result {
+	var result Result
+	if d == nil || text == "" {
+		return result
+	}
+	normalized := Normalize(text)
+	original := []rune(text)
+
+	for _, m := range d.matcher.FindAll(normalized) {
+		e := d.keywords[m.Pattern]
+		result.Matches = append(result.Matches, Match{
+			Word: e.word, Category: e.category.Code, Start: m.Start, End: m.End, Text: string(original[m.Start:m.End]),
+			category: e.category,
+		})
+	}
+	for _, e := range d.regexes {
+		for _, loc := range e.re.FindAllStringIndex(normalized, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			start := utf8.RuneCountInString(normalized[:loc[0]])
+			end := start + utf8.RuneCountInString(normalized[loc[0]:loc[1]])
+			result.Matches = append(result.Matches, Match{
+				Word: e.word, Category: e.category.Code, IsRegex: true, Start: start, End: end, Text: string(original[start:end]),
+				category: e.category,
+			})
+		}
+	}
+	if len(result.Matches) == 0 {
+		return result
+	}
+	sort.SliceStable(result.Matches, func(i, j int) bool { return result.Matches[i].Start < result.Matches[j].Start })
+
+	seenWords := map[string]bool{}
+	seenCategories := map[string]bool{}
+	result.Action = models.SensitiveActionReview
+	for _, m := range result.Matches {
+		if !seenWords[m.Word] {
+			seenWords[m.Word] = true
+			result.Words = append(result.Words, m.Word)
+		}
+		if seenCategories[m.Category] {
+			continue
+		}
+		seenCategories[m.Category] = true
+		result.Categories = append(result.Categories, m.Category)
+		result.RiskScore += m.category.RiskScore
+		if m.category.Action == models.SensitiveActionHide {
+			result.Action = models.SensitiveActionHide
+		}
+	}
+	if result.RiskScore > maxRiskScore {
+		result.RiskScore = maxRiskScore
+	}
+	return result
+}
+
+// Filter 缓存编译后的词库，词库变更后调用 Invalidate 使其在下次使用时重新加载
+type Filter struct {
+	db       *gorm.DB
+	mu       sync.Mutex
+	dict     *Dictionary
+	loadedAt time.Time
+}
+
+func NewFilter(db *gorm.DB) *Filter {
+	return &Filter{db: db}
+}
+
+// Dictionary 获取当前词库，缓存过期时重新加载
+func (f *Filter) Dictionary() (*Dictionary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dict != nil && time.Since(f.loadedAt) < reloadInterval {
+		return f.dict, nil
+	}
+	dict, err := Load(f.db)
+	if err != nil {
+		return nil, err
+	}
+	f.dict, f.loadedAt = dict, time.Now()
+	return dict, nil
+}
+
+// Invalidate 丢弃缓存的词库
+func (f *Filter) Invalidate() {
+	f.mu.Lock()
+	f.dict = nil
+	f.mu.Unlock()
+}
+
+// Scan 用当前词库检查文本
+func (f *Filter) Scan(text string) (Result, error) {
+	dict, err := f.Dictionary()
+	if err != nil {
+		return Result{}, err
+	}
+	return dict.Scan(text), nil
+}
+
+// DefaultCategories 首次启动时创建的分类，词条由管理员维护
+var DefaultCategories = []models.SensitiveWordCategory{
+	{Code: "political", Name: "涉政", Action: models.SensitiveActionHide, RiskScore: 80, Enabled: true},
+	{Code: "abuse", Name: "辱骂攻击", Action: models.SensitiveActionReview, RiskScore: 50, Enabled: true},
+	{Code: "spam", Name: "垃圾广告", Action: models.SensitiveActionReview, RiskScore: 40, Enabled: true},
+	{Code: "self_harm", Name: "自伤自残", Action: models.SensitiveActionReview, RiskScore: 90, Enabled: true},
+}
+
+// EnsureDefaultCategories 分类表为空时创建默认分类
+func EnsureDefaultCategories(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.SensitiveWordCategory{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	categories := make([]models.SensitiveWordCategory, len(DefaultCategories))
+	copy(categories, DefaultCategories)
+	return db.Create(&categories).Error
+}
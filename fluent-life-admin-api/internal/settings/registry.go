@@ -0,0 +1,249 @@
+// Package settings 应用设置：已知设置项的注册表、类型校验、变更历史和带缓存的读取。
+//
+// 设置值以文本保存在 app_settings 表中，没有记录的设置项使用注册表中的默认值。
+// 每次修改、恢复默认和回滚都写入 app_setting_revisions，版本号按设置项递增。
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Type 设置值的类型
+type Type string
+
+const (
+	String Type = "string"
+	Int    Type = "int"
+	Bool   Type = "bool"
+	JSON   Type = "json"
+)
+
+// 已注册的设置项
+const (
+	AppVersionKey          = "app_version"
+	MinAppVersionKey       = "min_supported_app_version"
+	ServiceEmailKey        = "customer_service_email"
+	ServicePhoneKey        = "customer_service_phone"
+	MaintenanceModeKey     = "maintenance_mode"
+	MaintenanceMessageKey  = "maintenance_message"
+	AIDailyMessageLimitKey = "ai_daily_message_limit"
+	AISimulationRolesKey   = "ai_simulation_roles"
+)
+
+const (
+	versionPattern = `^\d+\.\d+\.\d+$`
+	// maxValueBytes 单个设置值的最大长度
+	maxValueBytes = 256 * 1024
+)
+
+// Definition 一个已知的设置项
+type Definition struct {
+	Key         string      `json:"key"`
+	Type        Type        `json:"type"`
+	Description string      `json:"description"`
+	Default     interface{} `json:"default"`
+	Schema      *Schema     `json:"schema,omitempty"`
+	// Check Schema 之外的校验，参数为解码后的值
+	Check func(v interface{}) error `json:"-"`
+	// ReadOnly 由其他数据同步生成，不能通过设置接口修改
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// Definitions 全部已知设置项
+var Definitions = []Definition{
+	{
+		Key: AppVersionKey, Type: String, Default: "1.0.0",
+		Description: "客户端最新版本号，格式 x.y.z",
+		Schema:      &Schema{Type: "string", Pattern: versionPattern},
+	},
+	{
+		Key: MinAppVersionKey, Type: String, Default: "1.0.0",
+		Description: "客户端最低支持版本，低于该版本需强制更新",
+		Schema:      &Schema{Type: "string", Pattern: versionPattern},
+	},
+	{
+		Key: ServiceEmailKey, Type: String, Default: "",
+		Description: "客服邮箱，为空时客户端不展示",
+		Schema:      &Schema{Type: "string", Pattern: `^$|^[^@\s]+@[^@\s]+\.[^@\s]+$`, MaxLength: intPtr(100)},
+	},
+	{
+		Key: ServicePhoneKey, Type: String, Default: "",
+		Description: "客服电话，为空时客户端不展示",
+		Schema:      &Schema{Type: "string", Pattern: `^[0-9+\- ]*$`, MaxLength: intPtr(30)},
+	},
+	{
+		Key: MaintenanceModeKey, Type: Bool, Default: false,
+		Description: "维护模式，开启后客户端展示维护提示",
+		Schema:      &Schema{Type: "boolean"},
+	},
+	{
+		Key: MaintenanceMessageKey, Type: String, Default: "",
+		Description: "维护模式下展示的提示文字",
+		Schema:      &Schema{Type: "string", MaxLength: intPtr(200)},
+	},
+	{
+		Key: AIDailyMessageLimitKey, Type: Int, Default: 50,
+		Description: "每个用户每天可发送的 AI 对话消息数，0 表示不限制",
+		Schema:      &Schema{Type: "integer", Minimum: floatPtr(0), Maximum: floatPtr(10000)},
+	},
+	{
+		Key: AISimulationRolesKey, Type: JSON, Default: []interface{}{},
+		Description: "AI实战模拟角色的发布快照，由 ai_roles 表同步生成，请在AI角色管理中修改",
+		Schema: &Schema{Type: "array", MaxItems: intPtr(100), Items: &Schema{
+			Type:     "object",
+			Required: []string{"id", "name", "system_prompt"},
+			Properties: map[string]*Schema{
+				"id":            {Type: "string", MinLength: intPtr(1), MaxLength: intPtr(50)},
+				"name":          {Type: "string", MinLength: intPtr(1), MaxLength: intPtr(50)},
+				"description":   {Type: "string", MaxLength: intPtr(255)},
+				"category":      {Type: "string", MaxLength: intPtr(50)},
+				"avatar":        {Type: "string", MaxLength: intPtr(500)},
+				"opening_line":  {Type: "string", MaxLength: intPtr(2000)},
+				"system_prompt": {Type: "string", MinLength: intPtr(1), MaxLength: intPtr(4000)},
+				"voice_type":    {Type: "string", MaxLength: intPtr(100)},
+				"enabled":       {Type: "boolean"},
+			},
+		}},
+		Check:    uniqueField("id"),
+		ReadOnly: true,
+	},
+}
+
+// Lookup 按键查找设置项
+func Lookup(key string) (Definition, bool) {
+	for _, def := range Definitions {
+		if def.Key == key {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// ErrInvalid 设置值不符合类型或校验规则，错误信息可直接展示给管理员
+type ErrInvalid struct{ msg string }
+
+func (e *ErrInvalid) Error() string { return e.msg }
+
+func invalid(format string, args ...interface{}) error {
+	return &ErrInvalid{msg: fmt.Sprintf(format, args...)}
+}
+
+// IsInvalid 判断是否为校验错误
+func IsInvalid(err error) bool {
+	var e *ErrInvalid
+	return errors.As(err, &e)
+}
+
+// Encode 校验接口传入的 JSON 值，返回保存到数据库的文本
+func (d Definition) Encode(raw json.RawMessage) (string, error) {
+	if len(raw) > maxValueBytes {
+		return "", invalid("设置值过大")
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", invalid("value 不是有效的 JSON")
+	}
+	if err := d.validate(v); err != nil {
+		return "", err
+	}
+
+	switch d.Type {
+	case String:
+		return v.(string), nil
+	case Int:
+		return strconv.FormatInt(int64(v.(float64)), 10), nil
+	case Bool:
+		return strconv.FormatBool(v.(bool)), nil
+	}
+	// 重新编码得到紧凑的 JSON
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// Decode 把数据库中的文本解码为设置值（string、int64、bool 或 JSON 解码结果），并按当前规则校验
+func (d Definition) Decode(stored string) (interface{}, error) {
+	var v interface{}
+	switch d.Type {
+	case String:
+		v = stored
+	case Int:
+		n, err := strconv.ParseInt(stored, 10, 64)
+		if err != nil {
+			return nil, invalid("不是整数: %q", stored)
+		}
+		v = float64(n)
+	case Bool:
+		b, err := strconv.ParseBool(stored)
+		if err != nil {
+			return nil, invalid("不是布尔值: %q", stored)
+		}
+		v = b
+	default:
+		if err := json.Unmarshal([]byte(stored), &v); err != nil {
+			return nil, invalid("不是有效的 JSON")
+		}
+	}
+	if err := d.validate(v); err != nil {
+		return nil, err
+	}
+	if d.Type == Int {
+		return int64(v.(float64)), nil
+	}
+	return v, nil
+}
+
+// validate 先检查类型，再按 Schema 和 Check 校验
+func (d Definition) validate(v interface{}) error {
+	ok := false
+	switch d.Type {
+	case String:
+		_, ok = v.(string)
+	case Int:
+		n, isNum := v.(float64)
+		ok = isNum && n == float64(int64(n))
+	case Bool:
+		_, ok = v.(bool)
+	case JSON:
+		ok = v != nil
+	}
+	if !ok {
+		return invalid("%s 的值应为 %s 类型", d.Key, d.Type)
+	}
+	if d.Schema != nil {
+		if err := d.Schema.Validate(v); err != nil {
+			return invalid("%s", err.Error())
+		}
+	}
+	if d.Check != nil {
+		if err := d.Check(v); err != nil {
+			return invalid("%s", err.Error())
+		}
+	}
+	return nil
+}
+
+// defaultValue 默认值，类型与 Decode 的结果一致
+func (d Definition) defaultValue() interface{} {
+	if n, ok := d.Default.(int); ok {
+		return int64(n)
+	}
+	return d.Default
+}
+
+// uniqueField 数组中对象的 field 字段不能重复
+func uniqueField(field string) func(v interface{}) error {
+	return func(v interface{}) error {
+		seen := map[interface{}]bool{}
+		for i, item := range v.([]interface{}) {
+			id := item.(map[string]interface{})[field]
+			if seen[id] {
+				return fmt.Errorf("[%d].%s: %v 重复", i, field, id)
+			}
+			seen[id] = true
+		}
+		return nil
+	}
+}
@@ -0,0 +1,142 @@
+package settings
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema 设置值的结构约束，字段名与 JSON Schema 一致（只支持这里列出的关键字），
+// 接口原样返回给前端用于渲染表单
+type Schema struct {
+	// Type object、array、string、integer、number 或 boolean
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Pattern    string             `json:"pattern,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+	MaxItems   *int               `json:"maxItems,omitempty"`
+}
+
+// Validate 校验 JSON 解码后的值（map[string]interface{}、[]interface{}、string、float64、bool）
+func (s *Schema) Validate(v interface{}) error {
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	fail := func(format string, args ...interface{}) error {
+		msg := fmt.Sprintf(format, args...)
+		if path == "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("%s: %s", path, msg)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fail("应为对象")
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fail("缺少字段 %s", name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				return fail("不支持的字段 %s", name)
+			}
+			if err := prop.validate(joinPath(path, name), obj[name]); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fail("应为数组")
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			return fail("最多 %d 项", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fail("应为字符串")
+		}
+		n := len([]rune(str))
+		if s.MinLength != nil && n < *s.MinLength {
+			if *s.MinLength == 1 {
+				return fail("不能为空")
+			}
+			return fail("至少 %d 个字符", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fail("最多 %d 个字符", *s.MaxLength)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			return fail("格式不正确")
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return fail("可选值为 %s", strings.Join(s.Enum, "、"))
+		}
+	case "integer", "number":
+		num, ok := v.(float64)
+		if !ok {
+			return fail("应为数字")
+		}
+		if s.Type == "integer" && num != float64(int64(num)) {
+			return fail("应为整数")
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			return fail("不能小于 %v", *s.Minimum)
+		}
+		if s.Maximum != nil && num > *s.Maximum {
+			return fail("不能大于 %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fail("应为 true 或 false")
+		}
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func intPtr(n int) *int { return &n }
+
+func floatPtr(n float64) *float64 { return &n }
@@ -0,0 +1,325 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"fluent-life-admin-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUnknownKey 设置项未注册
+	ErrUnknownKey = errors.New("unknown setting key")
+	// ErrVersionConflict 设置已被其他管理员修改
+	ErrVersionConflict = errors.New("setting version conflict")
+	// ErrRevisionNotFound 历史版本不存在
+	ErrRevisionNotFound = errors.New("setting revision not found")
+)
+
+// Actor 变更设置的管理员
+type Actor struct {
+	ID   uuid.UUID
+	Name string
+}
+
+// Entry 设置项的当前状态
+type Entry struct {
+	Definition
+	// Registered 为 false 表示数据库中存在但未注册的设置项，只读
+	Registered bool        `json:"registered"`
+	Value      interface{} `json:"value"`
+	IsDefault  bool        `json:"is_default"`
+	// Invalid 数据库中的值不符合当前规则，读取方此时使用默认值
+	Invalid   string     `json:"invalid,omitempty"`
+	Version   int        `json:"version"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Store 读写设置。读取走进程内缓存，本进程修改后立即失效，其他进程的修改在 ttl 内生效
+type Store struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	values   map[string]interface{} // 已注册设置项解码后的值，nil 表示需要重新加载
+	loadedAt time.Time
+}
+
+// NewStore 创建设置读写器
+func NewStore(db *gorm.DB, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+// Invalidate 清空缓存，下次读取时重新加载
+func (s *Store) Invalidate() {
+	s.mu.Lock()
+	s.values = nil
+	s.mu.Unlock()
+}
+
+// Value 读取设置值，类型见 Definition.Decode；没有设置或值无效时返回默认值，未注册的键返回 nil
+func (s *Store) Value(key string) interface{} {
+	def, ok := Lookup(key)
+	if !ok {
+		return nil
+	}
+	if v, ok := s.cached()[key]; ok {
+		return v
+	}
+	return def.defaultValue()
+}
+
+// String 读取字符串设置
+func (s *Store) String(key string) string {
+	v, _ := s.Value(key).(string)
+	return v
+}
+
+// Int 读取整数设置
+func (s *Store) Int(key string) int64 {
+	v, _ := s.Value(key).(int64)
+	return v
+}
+
+// Bool 读取布尔设置
+func (s *Store) Bool(key string) bool {
+	v, _ := s.Value(key).(bool)
+	return v
+}
+
+// JSON 把 JSON 设置解码到 out
+func (s *Store) JSON(key string, out interface{}) error {
+	data, err := json.Marshal(s.Value(key))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// cached 返回缓存的设置值，过期时重新加载；加载失败时沿用上次的值
+func (s *Store) cached() map[string]interface{} {
+	s.mu.RLock()
+	values, fresh := s.values, s.values != nil && time.Since(s.loadedAt) < s.ttl
+	s.mu.RUnlock()
+	if fresh {
+		return values
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values != nil && time.Since(s.loadedAt) < s.ttl {
+		return s.values
+	}
+	var rows []models.AppSetting
+	if err := s.db.Select("key", "value").Find(&rows).Error; err != nil {
+		log.Printf("加载应用设置失败: %v", err)
+		if s.values != nil {
+			return s.values
+		}
+		return map[string]interface{}{}
+	}
+	values = make(map[string]interface{}, len(rows))
+	for _, row := range rows {
+		def, ok := Lookup(row.Key)
+		if !ok {
+			continue
+		}
+		v, err := def.Decode(row.Value)
+		if err != nil {
+			log.Printf("应用设置 %s 的值无效，使用默认值: %v", row.Key, err)
+			continue
+		}
+		values[row.Key] = v
+	}
+	s.values, s.loadedAt = values, time.Now()
+	return values
+}
+
+// Entries 全部设置项的当前状态（直接查询数据库），已注册的在前
+func (s *Store) Entries() ([]Entry, error) {
+	var rows []models.AppSetting
+	if err := s.db.Order("key ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*models.AppSetting, len(rows))
+	for i := range rows {
+		byKey[rows[i].Key] = &rows[i]
+	}
+
+	entries := make([]Entry, 0, len(Definitions)+len(rows))
+	for _, def := range Definitions {
+		entries = append(entries, newEntry(def, byKey[def.Key]))
+	}
+	for i := range rows {
+		if _, ok := Lookup(rows[i].Key); !ok {
+			entries = append(entries, unregisteredEntry(&rows[i]))
+		}
+	}
+	return entries, nil
+}
+
+// Entry 单个设置项的当前状态（直接查询数据库）
+func (s *Store) Entry(key string) (*Entry, error) {
+	var row models.AppSetting
+	err := s.db.Where("key = ?", key).First(&row).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	found := err == nil
+
+	def, ok := Lookup(key)
+	if !ok {
+		if !found {
+			return nil, ErrUnknownKey
+		}
+		entry := unregisteredEntry(&row)
+		return &entry, nil
+	}
+	var current *models.AppSetting
+	if found {
+		current = &row
+	}
+	entry := newEntry(def, current)
+	return &entry, nil
+}
+
+func newEntry(def Definition, row *models.AppSetting) Entry {
+	entry := Entry{Definition: def, Registered: true, Value: def.defaultValue(), IsDefault: true}
+	if row == nil {
+		return entry
+	}
+	entry.Version, entry.UpdatedAt = row.Version, &row.UpdatedAt
+	v, err := def.Decode(row.Value)
+	if err != nil {
+		entry.Invalid = err.Error()
+		return entry
+	}
+	entry.Value, entry.IsDefault = v, false
+	return entry
+}
+
+func unregisteredEntry(row *models.AppSetting) Entry {
+	return Entry{
+		Definition: Definition{Key: row.Key, Type: String, Description: row.Description},
+		Value:      row.Value,
+		Version:    row.Version,
+		UpdatedAt:  &row.UpdatedAt,
+	}
+}
+
+// Set 修改设置。value 为 JSON 编码的新值；expectVersion 不为空时，当前版本不一致返回 ErrVersionConflict
+func (s *Store) Set(actor Actor, key string, value json.RawMessage, expectVersion *int, comment string) (*Entry, error) {
+	def, ok := Lookup(key)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	stored, err := def.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return s.change(actor, def, &stored, models.SettingActionSet, nil, expectVersion, comment)
+}
+
+// Reset 恢复默认值
+func (s *Store) Reset(actor Actor, key string, expectVersion *int, comment string) (*Entry, error) {
+	def, ok := Lookup(key)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return s.change(actor, def, nil, models.SettingActionReset, nil, expectVersion, comment)
+}
+
+// Rollback 把设置恢复为历史版本的值，作为一个新版本记录
+func (s *Store) Rollback(actor Actor, key string, version int, comment string) (*Entry, error) {
+	def, ok := Lookup(key)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	var rev models.AppSettingRevision
+	if err := s.db.Where("key = ? AND version = ?", key, version).First(&rev).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	// 校验规则可能在这之后变过
+	if rev.Value != nil {
+		if _, err := def.Decode(*rev.Value); err != nil {
+			return nil, invalid("版本 %d 的值不符合当前规则: %s", version, err.Error())
+		}
+	}
+	return s.change(actor, def, rev.Value, models.SettingActionRollback, &version, nil, comment)
+}
+
+// change 在一个事务中写入新值和变更记录；value 为空表示删除记录、恢复默认值
+func (s *Store) change(actor Actor, def Definition, value *string, action string, rollbackOf, expectVersion *int, comment string) (*Entry, error) {
+	var saved *models.AppSetting
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var current models.AppSetting
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", def.Key).First(&current).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		found := err == nil
+		if expectVersion != nil && *expectVersion != current.Version {
+			return ErrVersionConflict
+		}
+
+		var latest int
+		if err := tx.Model(&models.AppSettingRevision{}).Where("key = ?", def.Key).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		// 变更历史上线前已有的值先补记为第 1 版，之后可以回滚到它
+		if found && latest == 0 {
+			initial := current.Value
+			if err := tx.Create(&models.AppSettingRevision{
+				Key: def.Key, Version: 1, Value: &initial, Action: models.SettingActionInitial,
+				Comment: "变更历史上线前的值", CreatedAt: current.UpdatedAt,
+			}).Error; err != nil {
+				return err
+			}
+			latest = 1
+		}
+		if latest < current.Version {
+			latest = current.Version
+		}
+		version := latest + 1
+
+		if err := tx.Create(&models.AppSettingRevision{
+			Key: def.Key, Version: version, Value: value, Action: action, RollbackOf: rollbackOf,
+			Comment: comment, ChangedBy: actor.ID, ChangedByName: actor.Name,
+		}).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case value == nil:
+			if found {
+				return tx.Delete(&current).Error
+			}
+			return nil
+		case found:
+			current.Value, current.Version = *value, version
+			saved = &current
+			return tx.Model(&current).Updates(map[string]interface{}{"value": *value, "version": version}).Error
+		default:
+			saved = &models.AppSetting{Key: def.Key, Value: *value, Description: def.Description, Version: version}
+			return tx.Create(saved).Error
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Invalidate()
+
+	entry := newEntry(def, saved)
+	return &entry, nil
+}
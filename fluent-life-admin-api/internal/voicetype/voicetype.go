@@ -0,0 +1,148 @@
+// Package voicetype 维护音色与引用方（AI角色的 voice_type、用户设置的 ai_voice_type）之间的一致性。
+//
+// 引用方保存的是音色的 type 字符串，没有外键约束：修改和删除音色时由这里迁移引用，
+// 写入引用时用 Enabled 校验，历史遗留的无效引用由 Check 找出（见 cmd/check-voice-types）。
+package voicetype
+
+import (
+	"errors"
+	"sort"
+
+	"fluent-life-admin-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrReplacementUnavailable 替换音色不存在、未启用或与原音色相同
+var ErrReplacementUnavailable = errors.New("replacement voice type unavailable")
+
+// Enabled 音色存在且已启用
+func Enabled(db *gorm.DB, voiceType string) bool {
+	var count int64
+	db.Model(&models.VoiceType{}).Where("type = ? AND enabled = ?", voiceType, true).Count(&count)
+	return count > 0
+}
+
+// RoleRef 引用音色的AI角色
+type RoleRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Usage 音色的引用情况
+type Usage struct {
+	VoiceType    string    `json:"voice_type"`
+	Roles        []RoleRef `json:"roles"`
+	UserSettings int64     `json:"user_settings"`
+}
+
+// InUse 是否仍被引用
+func (u *Usage) InUse() bool {
+	return len(u.Roles) > 0 || u.UserSettings > 0
+}
+
+// FindUsage 查询引用该音色的AI角色和用户设置数
+func FindUsage(db *gorm.DB, voiceType string) (*Usage, error) {
+	usage := &Usage{VoiceType: voiceType, Roles: make([]RoleRef, 0)}
+	if err := db.Model(&models.AIRole{}).Select("id, name").Where("voice_type = ?", voiceType).
+		Order(`"order" ASC`).Scan(&usage.Roles).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.UserSettings{}).Where("ai_voice_type = ?", voiceType).
+		Count(&usage.UserSettings).Error; err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// Reassign 在事务中把引用 from 的AI角色和用户设置改为 to，to 必须存在且已启用。
+// 返回修改的角色数和用户设置数
+func Reassign(tx *gorm.DB, from, to string) (int64, int64, error) {
+	if to == from || !Enabled(tx, to) {
+		return 0, 0, ErrReplacementUnavailable
+	}
+	return Rename(tx, from, to)
+}
+
+// Rename 把引用 from 的AI角色和用户设置改为 to，不校验 to，用于音色本身修改 type 时同步引用
+func Rename(tx *gorm.DB, from, to string) (int64, int64, error) {
+	roles := tx.Model(&models.AIRole{}).Where("voice_type = ?", from).
+		Updates(map[string]interface{}{"voice_type": to})
+	if roles.Error != nil {
+		return 0, 0, roles.Error
+	}
+	settings := tx.Model(&models.UserSettings{}).Where("ai_voice_type = ?", from).
+		Updates(map[string]interface{}{"ai_voice_type": to})
+	if settings.Error != nil {
+		return 0, 0, settings.Error
+	}
+	return roles.RowsAffected, settings.RowsAffected, nil
+}
+
+// 无效引用的原因
+const (
+	StatusMissing  = "missing"  // 音色不存在
+	StatusDisabled = "disabled" // 音色已停用
+)
+
+// Dangling 引用了不存在或已停用音色的记录
+type Dangling struct {
+	VoiceType    string   `json:"voice_type"`
+	Status       string   `json:"status"`
+	Roles        []string `json:"roles"`
+	UserSettings int64    `json:"user_settings"`
+}
+
+// Check 找出全部无效引用，按音色分组，空值视为未设置
+func Check(db *gorm.DB) ([]Dangling, error) {
+	var roles []struct {
+		ID        string
+		VoiceType string
+		Enabled   *bool
+	}
+	if err := db.Table("ai_roles AS r").Select("r.id, r.voice_type, v.enabled").
+		Joins("LEFT JOIN voice_types AS v ON v.type = r.voice_type").
+		Where("r.voice_type <> '' AND (v.id IS NULL OR v.enabled = ?)", false).
+		Order("r.id ASC").Scan(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	var settings []struct {
+		VoiceType string
+		Enabled   *bool
+		Count     int64
+	}
+	if err := db.Table("user_settings AS s").Select("s.ai_voice_type AS voice_type, v.enabled, COUNT(*) AS count").
+		Joins("LEFT JOIN voice_types AS v ON v.type = s.ai_voice_type").
+		Where("COALESCE(s.ai_voice_type, '') <> '' AND (v.id IS NULL OR v.enabled = ?)", false).
+		Group("s.ai_voice_type, v.enabled").Scan(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	byType := map[string]*Dangling{}
+	entry := func(voiceType string, enabled *bool) *Dangling {
+		d, ok := byType[voiceType]
+		if !ok {
+			d = &Dangling{VoiceType: voiceType, Status: StatusMissing, Roles: make([]string, 0)}
+			if enabled != nil {
+				d.Status = StatusDisabled
+			}
+			byType[voiceType] = d
+		}
+		return d
+	}
+	for _, r := range roles {
+		d := entry(r.VoiceType, r.Enabled)
+		d.Roles = append(d.Roles, r.ID)
+	}
+	for _, s := range settings {
+		entry(s.VoiceType, s.Enabled).UserSettings += s.Count
+	}
+
+	result := make([]Dangling, 0, len(byType))
+	for _, d := range byType {
+		result = append(result, *d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].VoiceType < result[j].VoiceType })
+	return result, nil
+}
@@ -0,0 +1,94 @@
+// Package ahocorasick 实现 Aho–Corasick 多模式串匹配，按 rune 处理，适用于中文文本。
+package ahocorasick
+
+// Match 一次匹配，Start/End 为 rune 下标，区间左闭右开
+type Match struct {
+	Pattern int // 模式串在构建时的下标
+	Start   int
+	End     int
+}
+
+type node struct {
+	next map[rune]int
+	fail int
+	out  []int // 以该节点结尾的模式串（含失败链上的）
+}
+
+// Matcher 构建完成后只读，可被多个 goroutine 并发使用
+type Matcher struct {
+	nodes   []node
+	lengths []int // 各模式串的 rune 长度
+}
+
+// New 用模式串构建匹配器，空模式串被忽略
+func New(patterns []string) *Matcher {
+	m := &Matcher{nodes: []node{{next: map[rune]int{}}}, lengths: make([]int, len(patterns))}
+	for i, p := range patterns {
+		if p == "" {
+			continue
+		}
+		cur := 0
+		for _, r := range p {
+			m.lengths[i]++
+			nxt, ok := m.nodes[cur].next[r]
+			if !ok {
+				m.nodes = append(m.nodes, node{next: map[rune]int{}})
+				nxt = len(m.nodes) - 1
+				m.nodes[cur].next[r] = nxt
+			}
+			cur = nxt
+		}
+		m.nodes[cur].out = append(m.nodes[cur].out, i)
+	}
+	m.buildFailLinks()
+	return m
+}
+
+// buildFailLinks 按广度优先计算失败指针，并把失败链上的输出合并到当前节点
+func (m *Matcher) buildFailLinks() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for f != 0 {
+				if _, ok := m.nodes[f].next[r]; ok {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			if nxt, ok := m.nodes[f].next[r]; ok && nxt != child {
+				m.nodes[child].fail = nxt
+			}
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[m.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// FindAll 返回文本中所有（可重叠的）匹配，按结束位置排序
+func (m *Matcher) FindAll(text string) []Match {
+	var matches []Match
+	cur := 0
+	i := 0
+	for _, r := range text {
+		for cur != 0 {
+			if _, ok := m.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if nxt, ok := m.nodes[cur].next[r]; ok {
+			cur = nxt
+		}
+		i++
+		for _, p := range m.nodes[cur].out {
+			matches = append(matches, Match{Pattern: p, Start: i - m.lengths[p], End: i})
+		}
+	}
+	return matches
+}
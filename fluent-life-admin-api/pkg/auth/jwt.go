@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"time"
@@ -16,6 +19,17 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+const (
+	// AccessTokenTTL 访问令牌有效期
+	AccessTokenTTL = 24 * time.Hour
+	// RefreshTokenTTL 刷新令牌有效期
+	RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// DefaultAdminPassword is the well-known password of the seeded admin account.
+// Accounts still using it are forced to change it before using the admin API.
+const DefaultAdminPassword = "admin123"
+
 var jwtSecret = []byte(getJWTSecret())
 
 func getJWTSecret() string {
@@ -28,12 +42,14 @@ func getJWTSecret() string {
 }
 
 // GenerateToken generates a new JWT token for the given user ID and role.
-func GenerateToken(userID uuid.UUID, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token valid for 24 hours
+// jti identifies the token so that it can be looked up and revoked server-side.
+func GenerateToken(userID uuid.UUID, role, jti string) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenTTL)
 	claims := &JWTClaims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -70,3 +86,62 @@ func ParseToken(tokenString string) (*JWTClaims, error) {
 
 	return claims, nil
 }
+
+// GenerateRefreshToken generates an opaque random refresh token.
+func GenerateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken returns the SHA-256 hex digest of an opaque token for storage.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChallengeTokenTTL is how long a two-factor login challenge stays valid.
+const ChallengeTokenTTL = 5 * time.Minute
+
+// challengeAudience marks tokens that only prove the password step of a
+// two-factor login. They carry no session and are rejected by the auth middleware.
+const challengeAudience = "2fa-challenge"
+
+// GenerateChallengeToken issues a short-lived token proving that userID passed
+// the password check and must now present a second factor.
+func GenerateChallengeToken(userID uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ChallengeTokenTTL)
+	claims := &jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    "fluent-life-admin-api",
+		Subject:   userID.String(),
+		Audience:  jwt.ClaimStrings{challengeAudience},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(jwtSecret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign challenge token: %w", err)
+	}
+	return tokenString, expiresAt, nil
+}
+
+// ParseChallengeToken validates a challenge token and returns the user ID it was issued for.
+func ParseChallengeToken(tokenString string) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtSecret, nil
+	}, jwt.WithAudience(challengeAudience))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to parse challenge token: %w", err)
+	}
+	return uuid.Parse(claims.Subject)
+}
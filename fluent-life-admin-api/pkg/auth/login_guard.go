@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginGuardPolicy describes how failed login attempts are throttled for one kind of key.
+type LoginGuardPolicy struct {
+	// FreeAttempts is the number of failures allowed before any backoff is applied.
+	FreeAttempts int
+	// BaseDelay is the wait imposed after the first failure beyond FreeAttempts;
+	// it doubles for every further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAttempts is the number of failures that triggers a temporary lockout.
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+// DefaultUsernamePolicy throttles failures against a single account.
+var DefaultUsernamePolicy = LoginGuardPolicy{
+	FreeAttempts:    3,
+	BaseDelay:       2 * time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAttempts: 10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          30 * time.Minute,
+}
+
+// DefaultIPPolicy throttles failures from a single client address across accounts.
+var DefaultIPPolicy = LoginGuardPolicy{
+	FreeAttempts:    10,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAttempts: 30,
+	LockoutDuration: 30 * time.Minute,
+	Window:          30 * time.Minute,
+}
+
+type loginAttempt struct {
+	failures    int
+	lastFailure time.Time
+	blockedTill time.Time
+}
+
+// LoginGuard tracks failed login attempts in memory per username and per IP,
+// applying progressive backoff and temporary lockout.
+type LoginGuard struct {
+	mu        sync.Mutex
+	usernames map[string]*loginAttempt
+	ips       map[string]*loginAttempt
+	userPol   LoginGuardPolicy
+	ipPol     LoginGuardPolicy
+	now       func() time.Time
+}
+
+// NewLoginGuard creates a LoginGuard with the given policies.
+func NewLoginGuard(userPolicy, ipPolicy LoginGuardPolicy) *LoginGuard {
+	return &LoginGuard{
+		usernames: make(map[string]*loginAttempt),
+		ips:       make(map[string]*loginAttempt),
+		userPol:   userPolicy,
+		ipPol:     ipPolicy,
+		now:       time.Now,
+	}
+}
+
+// RetryAfter reports how long the caller must wait before another attempt for
+// the username/IP pair is accepted. Zero means the attempt may proceed.
+func (g *LoginGuard) RetryAfter(username, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	wait := g.waitFor(g.usernames, username, g.userPol, now)
+	if w := g.waitFor(g.ips, ip, g.ipPol, now); w > wait {
+		wait = w
+	}
+	return wait
+}
+
+// Fail records a failed attempt and returns whether the username is now locked out.
+func (g *LoginGuard) Fail(username, ip string) (locked bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	locked = g.record(g.usernames, username, g.userPol, now)
+	g.record(g.ips, ip, g.ipPol, now)
+	return locked
+}
+
+// Succeed clears the failure history of the username. The IP history is kept so
+// that a valid login cannot be used to reset an ongoing spray against other accounts.
+func (g *LoginGuard) Succeed(username string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.usernames, username)
+}
+
+func (g *LoginGuard) waitFor(m map[string]*loginAttempt, key string, pol LoginGuardPolicy, now time.Time) time.Duration {
+	a, ok := m[key]
+	if !ok {
+		return 0
+	}
+	if now.Sub(a.lastFailure) > pol.Window && now.After(a.blockedTill) {
+		delete(m, key)
+		return 0
+	}
+	if now.Before(a.blockedTill) {
+		return a.blockedTill.Sub(now)
+	}
+	return 0
+}
+
+// maxTrackedKeys bounds memory use; expired entries are pruned once it is exceeded.
+const maxTrackedKeys = 10000
+
+func (g *LoginGuard) record(m map[string]*loginAttempt, key string, pol LoginGuardPolicy, now time.Time) bool {
+	if len(m) > maxTrackedKeys {
+		for k, a := range m {
+			if now.Sub(a.lastFailure) > pol.Window && now.After(a.blockedTill) {
+				delete(m, k)
+			}
+		}
+	}
+
+	a, ok := m[key]
+	if !ok || (now.Sub(a.lastFailure) > pol.Window && now.After(a.blockedTill)) {
+		a = &loginAttempt{}
+		m[key] = a
+	}
+	a.failures++
+	a.lastFailure = now
+
+	if a.failures >= pol.LockoutAttempts {
+		a.blockedTill = now.Add(pol.LockoutDuration)
+		return true
+	}
+	if a.failures > pol.FreeAttempts {
+		delay := pol.BaseDelay << uint(a.failures-pol.FreeAttempts-1)
+		if delay <= 0 || delay > pol.MaxDelay {
+			delay = pol.MaxDelay
+		}
+		a.blockedTill = now.Add(delay)
+	}
+	return false
+}